@@ -9,10 +9,16 @@ import (
 	"syscall"
 	"time"
 
+	"boiler-go/internal/claimcheck"
 	"boiler-go/internal/config"
 	"boiler-go/internal/db"
 	"boiler-go/internal/handler"
+	"boiler-go/internal/metrics"
+	"boiler-go/internal/outbox"
+	"boiler-go/internal/ratelimit"
 	"boiler-go/internal/scheduler"
+	"boiler-go/internal/tasks"
+	"boiler-go/internal/worker"
 	"boiler-go/pkg/logger"
 
 	"github.com/hibiken/asynq"
@@ -65,27 +71,115 @@ func main() {
 	logg.Info().Msg("database connected")
 	defer db.Close()
 
-	rdb := redis.NewClient(&redis.Options{
+	// Redis holds the asynq queues, idempotent responses and progress events.
+	// The API runs without it when config.UsesRedis says so, and rdb stays nil.
+	var rdb *redis.Client
+	if cfg.UsesRedis() {
+		rdb = redis.NewClient(&redis.Options{
+			Addr:     cfg.RedisAddr,
+			Password: cfg.RedisPassword,
+			DB:       cfg.RedisDB,
+		})
+		if err := rdb.Ping(ctx).Err(); err != nil {
+			logg.Fatal().Err(err).Msg("redis connection failed")
+		}
+		logg.Info().Msg("redis connected")
+	} else {
+		logg.Info().Msg("running without redis")
+	}
+
+	redisOpt := asynq.RedisClientOpt{
 		Addr:     cfg.RedisAddr,
 		Password: cfg.RedisPassword,
 		DB:       cfg.RedisDB,
-	})
+	}
 
-	if err := rdb.Ping(ctx).Err(); err != nil {
-		logg.Fatal().Err(err).Msg("redis connection failed")
+	// Store of payloads too large to be kept in Redis, shared with the workers
+	payloads, err := claimcheck.Open(cfg.PayloadStore, cfg.PayloadStoreDir, db.Get())
+	if err != nil {
+		logg.Fatal().Err(err).Msg("failed to open payload store")
 	}
-	logg.Info().Msg("redis connected")
 
-	// Initialize scheduler client for worker task enqueueing
-	schedulerClient := scheduler.NewClient(asynq.RedisClientOpt{
-		Addr:     cfg.RedisAddr,
-		Password: cfg.RedisPassword,
-		DB:       cfg.RedisDB,
-	})
-	logg.Info().Msg("scheduler client initialized")
+	// Initialize scheduler client for worker task enqueueing. In inline mode it runs
+	// tasks with inlineMux, which gets the worker's handlers below.
+	var backend scheduler.Backend
+	inlineMux := asynq.NewServeMux()
+	if cfg.QueueMode == "inline" {
+		backend = scheduler.NewInlineBackend(inlineMux, scheduler.InlineConfig{
+			Concurrency:    cfg.QueueInlineConcurrency,
+			RetryDelayFunc: tasks.RetryDelay,
+			IsFailure:      tasks.IsFailure,
+			ErrorHandler:   worker.ErrorHandler(logg),
+		})
+		logg.Warn().Msg("QUEUE_MODE is inline, tasks run in the API process")
+	} else {
+		backend, err = scheduler.NewBackend(cfg.QueueBackend, redisOpt, db.Get())
+		if err != nil {
+			logg.Fatal().Err(err).Msg("failed to create queue backend")
+		}
+	}
+	schedulerClient := scheduler.NewClientWithBackend(backend, db.Get())
+	schedulerClient.OffloadPayloads(payloads, cfg.PayloadOffloadThreshold)
+	logg.Info().Str("backend", cfg.QueueBackend).Str("mode", cfg.QueueMode).Msg("scheduler client initialized")
 	defer schedulerClient.Close()
 
-	router := handler.NewRouter(logg, cfg, db.Get(), rdb, schedulerClient)
+	// Initialize inspector for task and queue introspection
+	var inspector *asynq.Inspector
+	if rdb != nil {
+		inspector = asynq.NewInspector(redisOpt)
+		defer inspector.Close()
+	}
+
+	// Expose pool stats alongside the HTTP metrics registered by the router
+	reg := metrics.NewRegistry()
+	reg.MustRegister(metrics.NewPgxPoolCollector(db.Get()))
+	if rdb != nil {
+		reg.MustRegister(metrics.NewRedisPoolCollector(rdb))
+	}
+
+	// Without a worker process, the API runs the task handlers, the outbox relay and the
+	// payload sweeper itself
+	relayDone := make(chan struct{})
+	sweepDone := make(chan struct{})
+	relayCtx, stopRelay := context.WithCancel(logger.WithContext(ctx, logg))
+	defer stopRelay()
+	if cfg.QueueMode == "inline" {
+		rateLimits, err := ratelimit.ParseRules(cfg.TaskRateLimits)
+		if err != nil {
+			logg.Fatal().Err(err).Msg("invalid TASK_RATE_LIMITS")
+		}
+		if rdb == nil && len(rateLimits) > 0 {
+			logg.Warn().Msg("TASK_RATE_LIMITS ignored, rate limits need redis")
+		}
+		worker.Register(inlineMux, worker.Deps{
+			Logger:              logg,
+			Pool:                db.Get(),
+			Client:              schedulerClient,
+			Payloads:            payloads,
+			Redis:               rdb,
+			RateLimits:          rateLimits,
+			ProgressTTL:         cfg.ProgressTTL,
+			Metrics:             metrics.NewTaskMetrics(reg),
+			WebhookSecret:       cfg.WebhookSecret,
+			WebhookTimeout:      cfg.WebhookTimeout,
+			WebhookAllowPrivate: cfg.WebhookAllowPrivate,
+		})
+
+		relay := outbox.NewRelay(db.Get(), schedulerClient, cfg.OutboxPollInterval, cfg.OutboxBatchSize, cfg.OutboxRetention)
+		go func() {
+			defer close(relayDone)
+			relay.Run(relayCtx)
+		}()
+		go func() {
+			defer close(sweepDone)
+			worker.SweepPayloads(relayCtx, schedulerClient, cfg.PayloadSweepInterval)
+		}()
+	} else {
+		close(relayDone)
+		close(sweepDone)
+	}
+
+	router := handler.NewRouter(logg, cfg, db.Get(), rdb, schedulerClient, inspector, reg)
 
 	server := &http.Server{
 		Addr:           ":" + cfg.AppPort,
@@ -130,9 +224,16 @@ func main() {
 		logg.Info().Msg("server shutdown completed gracefully")
 	}
 
+	// Stop the inline outbox relay and payload sweeper before the database pool closes
+	stopRelay()
+	<-relayDone
+	<-sweepDone
+
 	// close resources in reverse order of initialization
-	if err := rdb.Close(); err != nil {
-		logg.Error().Err(err).Msg("redis close failed")
+	if rdb != nil {
+		if err := rdb.Close(); err != nil {
+			logg.Error().Err(err).Msg("redis close failed")
+		}
 	}
 
 	logg.Info().Msg("server stopped cleanly")
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"boiler-go/internal/claimcheck"
+	"boiler-go/internal/config"
+	"boiler-go/internal/db"
+	"boiler-go/internal/scheduler"
+	"boiler-go/internal/tasks"
+	"boiler-go/pkg/logger"
+
+	"github.com/hibiken/asynq"
+	"github.com/rs/zerolog"
+)
+
+// newLogger creates a logger based on the configuration.
+// defaultFile is used when LOG_FILE is not set and LOG_OUTPUT is "file" or "both".
+func newLogger(cfg *config.Config, defaultFile string) zerolog.Logger {
+	outputCfg := logger.OutputConfig{}
+
+	switch cfg.LogOutput {
+	case "stdout":
+		outputCfg.Stdout = true
+		outputCfg.StdoutOnly = true
+	case "file":
+		outputCfg.Stdout = false
+		outputCfg.StdoutOnly = false
+		outputCfg.FilePath = cfg.LogFile
+		if outputCfg.FilePath == "" {
+			outputCfg.FilePath = defaultFile
+		}
+	case "both":
+		outputCfg.Stdout = true
+		outputCfg.StdoutOnly = false
+		outputCfg.FilePath = cfg.LogFile
+		if outputCfg.FilePath == "" {
+			outputCfg.FilePath = defaultFile
+		}
+	}
+
+	return logger.NewWithOutput(outputCfg)
+}
+
+// periodicManager is implemented by asynq.PeriodicTaskManager, and by
+// scheduler.PeriodicScheduler for the Postgres backend.
+type periodicManager interface {
+	Start() error
+	Shutdown()
+}
+
+func main() {
+	// Load config first with basic logger
+	cfg := config.Load(logger.New())
+
+	// Create logger based on configuration
+	logg := newLogger(cfg, "logs/scheduler.log")
+
+	// Initialize database pool with timeout context
+	dbCtx, dbCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer dbCancel()
+	if err := db.Open(dbCtx, cfg); err != nil {
+		logg.Fatal().Err(err).Msg("failed to initialize database")
+	}
+	logg.Info().Msg("database connected")
+	defer db.Close()
+
+	static, err := withTaskDefaults(periodicTasks)
+	if err != nil {
+		logg.Fatal().Err(err).Msg("invalid periodic task configuration")
+	}
+
+	// Serve code-defined entries plus the periodic_tasks table, re-synced on an interval
+	provider := scheduler.NewConfigProvider(logg, db.Get(), static, tasks.DefaultOptions)
+
+	var mgr periodicManager
+	switch cfg.QueueBackend {
+	case scheduler.BackendPostgres:
+		// asynq's manager only enqueues to Redis, so tasks go through scheduler.Client instead
+		payloads, err := claimcheck.Open(cfg.PayloadStore, cfg.PayloadStoreDir, db.Get())
+		if err != nil {
+			logg.Fatal().Err(err).Msg("failed to open payload store")
+		}
+		client := scheduler.NewClientWithBackend(scheduler.NewPostgresBackend(db.Get()), db.Get())
+		client.OffloadPayloads(payloads, cfg.PayloadOffloadThreshold)
+		defer client.Close()
+		mgr = scheduler.NewPeriodicScheduler(logg, client, provider, cfg.PeriodicSyncInterval)
+	default:
+		if cfg.RedisAddr == "" {
+			logg.Fatal().Msg("REDIS_ADDR is required by the scheduler")
+		}
+		mgr, err = asynq.NewPeriodicTaskManager(asynq.PeriodicTaskManagerOpts{
+			RedisConnOpt: asynq.RedisClientOpt{
+				Addr:     cfg.RedisAddr,
+				Password: cfg.RedisPassword,
+				DB:       cfg.RedisDB,
+			},
+			PeriodicTaskConfigProvider: provider,
+			SyncInterval:               cfg.PeriodicSyncInterval,
+			SchedulerOpts: &asynq.SchedulerOpts{
+				PostEnqueueFunc: func(info *asynq.TaskInfo, err error) {
+					if err != nil {
+						logg.Error().Err(err).Msg("periodic task enqueue failed")
+						return
+					}
+					logg.Info().
+						Str("task_id", info.ID).
+						Str("task_type", info.Type).
+						Str("queue", info.Queue).
+						Msg("periodic task enqueued")
+				},
+			},
+		})
+		if err != nil {
+			logg.Fatal().Err(err).Msg("failed to create periodic task manager")
+		}
+	}
+
+	schedulerErrors := make(chan error, 1)
+
+	go func() {
+		logg.Info().
+			Int("static_entries", len(static)).
+			Dur("sync_interval", cfg.PeriodicSyncInterval).
+			Msg("scheduler starting")
+		// Start performs the initial sync, so a broken table fails startup
+		if err := mgr.Start(); err != nil {
+			schedulerErrors <- fmt.Errorf("scheduler failed to start: %w", err)
+		}
+	}()
+
+	// Setup signal handling
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+
+	select {
+	case err := <-schedulerErrors:
+		logg.Fatal().Err(err).Msg("scheduler startup failed")
+	case sig := <-sigChan:
+		logg.Info().Str("signal", sig.String()).Msg("shutdown signal received")
+	}
+
+	logg.Info().Msg("shutting down scheduler...")
+
+	// Shutdown with timeout enforcement for an in-flight enqueue
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.SchedulerShutdownTimeout)
+	defer cancel()
+
+	// Run mgr.Shutdown() in a goroutine since it blocks until running cron jobs complete
+	done := make(chan struct{})
+	go func() {
+		mgr.Shutdown()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		logg.Info().Msg("scheduler shutdown completed gracefully")
+	case <-shutdownCtx.Done():
+		logg.Warn().Msg("scheduler shutdown timed out, forcing exit")
+	}
+
+	logg.Info().Msg("scheduler stopped cleanly")
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+
+	"boiler-go/internal/queue"
+	"boiler-go/internal/scheduler"
+	"boiler-go/internal/tasks"
+)
+
+// periodicTasks declares the code-defined tasks enqueued on a schedule.
+// Each entry picks up the defaults registered for its task type in internal/tasks.
+// Runtime-defined schedules live in the periodic_tasks table instead.
+var periodicTasks = []scheduler.PeriodicTask{
+	{
+		Cronspec: "@every 5m",
+		TaskType: tasks.TypeWorkerPing,
+		Payload:  tasks.PingPayload{Message: "periodic ping from scheduler"},
+		Queue:    queue.QueueLow,
+		Timezone: "UTC",
+	},
+}
+
+// withTaskDefaults prepends the registered defaults of each entry's task type,
+// so periodic tasks get the same retry count and timeout as API-enqueued ones.
+// It also validates every entry so a bad code-defined schedule fails at startup.
+func withTaskDefaults(entries []scheduler.PeriodicTask) ([]scheduler.PeriodicTask, error) {
+	out := make([]scheduler.PeriodicTask, 0, len(entries))
+	for _, entry := range entries {
+		opts, ok := tasks.DefaultOptions(entry.TaskType)
+		if !ok {
+			return nil, fmt.Errorf("periodic task type %q is not registered", entry.TaskType)
+		}
+		entry.Opts = append(opts, entry.Opts...)
+		if err := entry.Validate(); err != nil {
+			return nil, fmt.Errorf("periodic task %s (%s): %w", entry.TaskType, entry.Cronspec, err)
+		}
+		out = append(out, entry)
+	}
+	return out, nil
+}
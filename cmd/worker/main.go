@@ -2,23 +2,38 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"boiler-go/internal/claimcheck"
 	"boiler-go/internal/config"
 	"boiler-go/internal/db"
+	"boiler-go/internal/metrics"
+	"boiler-go/internal/outbox"
 	"boiler-go/internal/queue"
+	"boiler-go/internal/ratelimit"
+	"boiler-go/internal/scheduler"
 	"boiler-go/internal/tasks"
+	"boiler-go/internal/worker"
 	"boiler-go/pkg/logger"
 
 	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog"
 )
 
+// taskServer processes tasks with a handler: an asynq.Server, or a
+// scheduler.PostgresServer with QUEUE_BACKEND=postgres.
+type taskServer interface {
+	Run(handler asynq.Handler) error
+	Stop()
+	Shutdown()
+}
+
 // newLogger creates a logger based on the configuration.
 // defaultFile is used when LOG_FILE is not set and LOG_OUTPUT is "file" or "both".
 func newLogger(cfg *config.Config, defaultFile string) zerolog.Logger {
@@ -47,19 +62,15 @@ func newLogger(cfg *config.Config, defaultFile string) zerolog.Logger {
 	return logger.NewWithOutput(outputCfg)
 }
 
-// PingTaskPayload mirrors the structure from internal/handler/worker.go
-type PingTaskPayload struct {
-	Message   string    `json:"message"`
-	RequestID string    `json:"request_id"`
-	QueuedAt  time.Time `json:"queued_at"`
-}
-
 func main() {
 	// Load config first with basic logger
 	cfg := config.Load(logger.New())
 
 	// Create logger based on configuration
 	logg := newLogger(cfg, "logs/worker.log")
+	if cfg.QueueMode == "inline" {
+		logg.Fatal().Msg("QUEUE_MODE is inline, tasks run in the API process and the worker is not used")
+	}
 
 	// Initialize database pool with timeout context
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -76,62 +87,138 @@ func main() {
 		DB:       cfg.RedisDB,
 	}
 
-	srv := asynq.NewServer(
-		redisOpt,
-		asynq.Config{
-			Concurrency: 10, // worker concurrency
-			// queue priorities (higher weight = higher priority)
-			Queues: queue.Priorities(),
-			// StrictPriority: true, // uncomment to always process higher priority queues first
-
-			// Exponential backoff retry strategy
-			RetryDelayFunc: func(n int, e error, t *asynq.Task) time.Duration {
-				// 1s, 2s, 4s, 8s, 16s...
-				return time.Duration(1<<uint(n)) * time.Second
+	// Redis client for state shared with other processes: rate limit buckets and task progress.
+	// Without Redis, see config.UsesRedis, rdb stays nil and both are turned off.
+	var rdb *redis.Client
+	if cfg.UsesRedis() {
+		rdb = redis.NewClient(&redis.Options{
+			Addr:     cfg.RedisAddr,
+			Password: cfg.RedisPassword,
+			DB:       cfg.RedisDB,
+		})
+		defer rdb.Close()
+	}
+
+	rateLimits, err := ratelimit.ParseRules(cfg.TaskRateLimits)
+	if err != nil {
+		logg.Fatal().Err(err).Msg("invalid TASK_RATE_LIMITS")
+	}
+	for taskType, rule := range rateLimits {
+		if _, ok := tasks.Lookup(taskType); !ok {
+			logg.Fatal().Str("task_type", taskType).Msg("TASK_RATE_LIMITS names an unknown task type")
+		}
+		if rdb == nil {
+			logg.Warn().Str("task_type", taskType).Msg("task rate limit ignored, rate limits need redis")
+			continue
+		}
+		logg.Info().Str("task_type", taskType).Stringer("limit", rule).Msg("task rate limit enabled")
+	}
+
+	reg := metrics.NewRegistry()
+	reg.MustRegister(metrics.NewPgxPoolCollector(db.Get()))
+	taskMetrics := metrics.NewTaskMetrics(reg)
+
+	errorHandler := worker.ErrorHandler(logg)
+
+	// Both backends share the queue weights, retry policy and failure logging
+	var srv taskServer
+	switch cfg.QueueBackend {
+	case scheduler.BackendPostgres:
+		srv = scheduler.NewPostgresServer(db.Get(), scheduler.PostgresServerConfig{
+			Concurrency:       10,
+			Queues:            queue.Priorities(),
+			PollInterval:      cfg.QueuePollInterval,
+			VisibilityTimeout: cfg.QueueVisibilityTimeout,
+			RetryDelayFunc:    tasks.RetryDelay,
+			IsFailure:         tasks.IsFailure,
+			ErrorHandler:      errorHandler,
+			Logger:            logg,
+		})
+	default:
+		srv = asynq.NewServer(
+			redisOpt,
+			asynq.Config{
+				Concurrency: 10, // worker concurrency
+				// queue priorities (higher weight = higher priority)
+				Queues: queue.Priorities(),
+				// StrictPriority: true, // uncomment to always process higher priority queues first
+
+				// Exponential backoff with jitter, using the retry policy registered per task type
+				RetryDelayFunc: tasks.RetryDelay,
+				// Rate limited tasks are retried without using up their retries
+				IsFailure: tasks.IsFailure,
+
+				ErrorHandler: errorHandler,
+
+				// Grouped tasks are aggregated into one task per group, see worker.Aggregator
+				GroupAggregator:  worker.Aggregator(logg, taskMetrics),
+				GroupGracePeriod: cfg.GroupGracePeriod,
+				GroupMaxDelay:    cfg.GroupMaxDelay,
+				GroupMaxSize:     cfg.GroupMaxSize,
 			},
+		)
+	}
+	logg.Info().Str("backend", cfg.QueueBackend).Msg("queue backend selected")
 
-			ErrorHandler: asynq.ErrorHandlerFunc(func(ctx context.Context, task *asynq.Task, err error) {
-				taskID := "unknown"
-				if rw := task.ResultWriter(); rw != nil {
-					taskID = rw.TaskID()
-				}
-				logg.Error().
-					Err(err).
-					Str("task_type", task.Type()).
-					Str("task_id", taskID).
-					Msg("task processing failed")
-			}),
-		},
-	)
+	// Store of payloads too large to be kept in Redis, shared with the API
+	payloads, err := claimcheck.Open(cfg.PayloadStore, cfg.PayloadStoreDir, db.Get())
+	if err != nil {
+		logg.Fatal().Err(err).Msg("failed to open payload store")
+	}
 
-	mux := asynq.NewServeMux()
+	// Scheduler client used to enqueue the next steps of workflows
+	backend, err := scheduler.NewBackend(cfg.QueueBackend, redisOpt, db.Get())
+	if err != nil {
+		logg.Fatal().Err(err).Msg("failed to create queue backend")
+	}
+	schedulerClient := scheduler.NewClientWithBackend(backend, db.Get())
+	schedulerClient.OffloadPayloads(payloads, cfg.PayloadOffloadThreshold)
+	defer schedulerClient.Close()
 
-	// Add logging middleware
-	mux.Use(loggingMiddleware(logg))
-
-	// worker ping handler - used by API to verify worker is alive
-	mux.HandleFunc(tasks.TypeWorkerPing, func(ctx context.Context, t *asynq.Task) error {
-		// Parse payload for correlation ID
-		var payload PingTaskPayload
-		logEvent := logg.Info()
-
-		if err := json.Unmarshal(t.Payload(), &payload); err != nil {
-			// Fallback to raw payload if parsing fails
-			logEvent.Str("payload_raw", string(t.Payload()))
-		} else {
-			logEvent.Str("payload", payload.Message)
-			if payload.RequestID != "" {
-				logEvent.Str("request_id", payload.RequestID)
-			}
-		}
+	if cfg.WebhookSecret == "" {
+		logg.Warn().Msg("WEBHOOK_SECRET is not set, task completion webhooks will fail")
+	}
 
-		logEvent.
-			Str("task_type", t.Type()).
-			Msg("worker ping task processed - worker is alive!")
-		return nil
+	mux := asynq.NewServeMux()
+	worker.Register(mux, worker.Deps{
+		Logger:              logg,
+		Pool:                db.Get(),
+		Client:              schedulerClient,
+		Payloads:            payloads,
+		Redis:               rdb,
+		RateLimits:          rateLimits,
+		ProgressTTL:         cfg.ProgressTTL,
+		Metrics:             taskMetrics,
+		WebhookSecret:       cfg.WebhookSecret,
+		WebhookTimeout:      cfg.WebhookTimeout,
+		WebhookAllowPrivate: cfg.WebhookAllowPrivate,
 	})
 
-	workerErrors := make(chan error, 1)
+	metricsServer := &http.Server{
+		Addr:              ":" + cfg.WorkerMetricsPort,
+		Handler:           metrics.Handler(reg),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	// Hand tasks committed to the outbox to asynq; every worker runs a relay
+	relay := outbox.NewRelay(db.Get(), schedulerClient, cfg.OutboxPollInterval, cfg.OutboxBatchSize, cfg.OutboxRetention)
+	relayCtx, stopRelay := context.WithCancel(logger.WithContext(context.Background(), logg))
+	defer stopRelay()
+	relayDone := make(chan struct{})
+	go func() {
+		defer close(relayDone)
+		logg.Info().Msg("outbox relay starting")
+		relay.Run(relayCtx)
+	}()
+
+	// Delete offloaded payloads whose task is gone, e.g. archived tasks trimmed by asynq
+	sweepDone := make(chan struct{})
+	go func() {
+		defer close(sweepDone)
+		worker.SweepPayloads(relayCtx, schedulerClient, cfg.PayloadSweepInterval)
+	}()
+
+	workerErrors := make(chan error, 2)
 
 	go func() {
 		logg.Info().Msg("worker starting")
@@ -140,6 +227,15 @@ func main() {
 		}
 	}()
 
+	go func() {
+		logg.Info().
+			Str("port", cfg.WorkerMetricsPort).
+			Msg("metrics server starting")
+		if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			workerErrors <- fmt.Errorf("metrics server failed to start: %w", err)
+		}
+	}()
+
 	// Setup signal handling
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
@@ -157,6 +253,12 @@ func main() {
 	srv.Stop()
 	logg.Info().Msg("worker stopped accepting new tasks")
 
+	// Stop the relay before the database pool closes; unclaimed messages are left to other relays
+	stopRelay()
+	<-relayDone
+	<-sweepDone
+	logg.Info().Msg("outbox relay stopped")
+
 	// Shutdown with timeout enforcement for in-flight tasks
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.WorkerShutdownTimeout)
 	defer cancel()
@@ -175,44 +277,10 @@ func main() {
 		logg.Warn().Msg("worker shutdown timed out, forcing exit")
 	}
 
-	logg.Info().Msg("worker stopped cleanly")
-}
-
-// loggingMiddleware logs task execution duration and success/failure
-func loggingMiddleware(logg zerolog.Logger) asynq.MiddlewareFunc {
-	return func(next asynq.Handler) asynq.Handler {
-		return asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
-			start := time.Now()
-
-			taskID := "unknown"
-			if rw := task.ResultWriter(); rw != nil {
-				taskID = rw.TaskID()
-			}
-
-			logg.Info().
-				Str("task_type", task.Type()).
-				Str("task_id", taskID).
-				Msg("task started")
-
-			err := next.ProcessTask(ctx, task)
-			duration := time.Since(start)
-
-			if err != nil {
-				logg.Error().
-					Err(err).
-					Str("task_type", task.Type()).
-					Str("task_id", taskID).
-					Dur("duration", duration).
-					Msg("task failed")
-			} else {
-				logg.Info().
-					Str("task_type", task.Type()).
-					Str("task_id", taskID).
-					Dur("duration", duration).
-					Msg("task completed")
-			}
-
-			return err
-		})
+	// Keep serving metrics until in-flight tasks are done so their final counts are scraped
+	if err := metricsServer.Shutdown(shutdownCtx); err != nil {
+		logg.Error().Err(err).Msg("metrics server shutdown failed")
 	}
+
+	logg.Info().Msg("worker stopped cleanly")
 }
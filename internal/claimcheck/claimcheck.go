@@ -0,0 +1,95 @@
+// Package claimcheck keeps large task payloads out of Redis. Payloads above a size
+// threshold are stored in Postgres or on local disk under their task ID, and the
+// enqueued task carries an empty payload and a reference header instead.
+package claimcheck
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"boiler-go/internal/db"
+
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// HeaderRef is the task header carrying the key of an offloaded payload.
+const HeaderRef = "payload_ref"
+
+// Store kinds accepted by Open.
+const (
+	KindPostgres = "postgres"
+	KindDisk     = "disk"
+)
+
+// ErrNotFound is returned by Store.Get when no payload is stored under the key.
+var ErrNotFound = errors.New("payload not found")
+
+// Store keeps offloaded payloads. Keys are task IDs.
+type Store interface {
+	// Put stores data under key. An existing payload under the same key is kept,
+	// since it belongs to the task already queued with that ID.
+	Put(ctx context.Context, key string, data []byte) error
+	// Get returns the payload stored under key, or ErrNotFound.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Delete removes the payload stored under key. Missing payloads are not an error.
+	Delete(ctx context.Context, key string) error
+	// Keys returns the keys of the payloads stored before the given time, oldest first.
+	Keys(ctx context.Context, before time.Time) ([]string, error)
+}
+
+// Open returns the store of the given kind: the task_payloads table of pool,
+// or files in dir.
+func Open(kind, dir string, pool *pgxpool.Pool) (Store, error) {
+	switch kind {
+	case KindPostgres:
+		return NewPostgresStore(db.New(pool)), nil
+	case KindDisk:
+		return NewDiskStore(dir)
+	default:
+		return nil, fmt.Errorf("unknown payload store %q, must be %s or %s", kind, KindPostgres, KindDisk)
+	}
+}
+
+// RefPayload returns the JSON recorded in place of a payload offloaded under key,
+// e.g. in the jobs table, so the payload is not stored twice.
+func RefPayload(key string, size int) []byte {
+	data, _ := json.Marshal(struct {
+		Ref  string `json:"payload_ref"`
+		Size int    `json:"size"`
+	}{key, size})
+	return data
+}
+
+// Ref returns the key of the offloaded payload of t, and false if its payload is inline.
+func Ref(t *asynq.Task) (string, bool) {
+	ref := t.Headers()[HeaderRef]
+	return ref, ref != ""
+}
+
+type ctxKey struct{}
+
+// WithStore returns a context whose tasks load offloaded payloads from store.
+func WithStore(ctx context.Context, store Store) context.Context {
+	return context.WithValue(ctx, ctxKey{}, store)
+}
+
+// Payload returns the payload of t, loading it from the store in ctx when it was offloaded.
+func Payload(ctx context.Context, t *asynq.Task) ([]byte, error) {
+	ref, ok := Ref(t)
+	if !ok {
+		return t.Payload(), nil
+	}
+	store, ok := ctx.Value(ctxKey{}).(Store)
+	if !ok {
+		return nil, fmt.Errorf("payload of task is offloaded to %s but no payload store is configured", ref)
+	}
+	data, err := store.Get(ctx, ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load offloaded payload %s: %w", ref, err)
+	}
+	return data, nil
+}
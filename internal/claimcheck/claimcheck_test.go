@@ -0,0 +1,102 @@
+package claimcheck
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+)
+
+// testStore checks the behavior every Store shares.
+func testStore(t *testing.T, store Store) {
+	t.Helper()
+	ctx := context.Background()
+	key, other := uuid.NewString(), uuid.NewString()
+
+	if _, err := store.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get() of a missing payload = %v, want %v", err, ErrNotFound)
+	}
+	if err := store.Put(ctx, key, []byte("first")); err != nil {
+		t.Fatalf("Put() = %v", err)
+	}
+	// The payload of the task already queued with the key is kept
+	if err := store.Put(ctx, key, []byte("second")); err != nil {
+		t.Fatalf("Put() of an existing key = %v", err)
+	}
+	if data, err := store.Get(ctx, key); err != nil || string(data) != "first" {
+		t.Fatalf("Get() = %q, %v; want the first payload", data, err)
+	}
+	if err := store.Put(ctx, other, []byte("other")); err != nil {
+		t.Fatalf("Put() = %v", err)
+	}
+
+	keys, err := store.Keys(ctx, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("Keys() = %v", err)
+	}
+	if len(keys) != 2 || !slices.Contains(keys, key) || !slices.Contains(keys, other) {
+		t.Errorf("Keys() = %v, want %s and %s", keys, key, other)
+	}
+	if keys, err := store.Keys(ctx, time.Now().Add(-time.Minute)); err != nil || len(keys) != 0 {
+		t.Errorf("Keys() before the payloads = %v, %v; want none", keys, err)
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete() = %v", err)
+	}
+	if _, err := store.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() after Delete() = %v, want %v", err, ErrNotFound)
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		t.Errorf("Delete() of a missing payload = %v, want nil", err)
+	}
+}
+
+func TestPayload(t *testing.T) {
+	store, err := NewDiskStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := uuid.NewString()
+	if err := store.Put(context.Background(), key, []byte(`{"big":true}`)); err != nil {
+		t.Fatal(err)
+	}
+	withStore := WithStore(context.Background(), store)
+	offloaded := asynq.NewTaskWithHeaders("test:big", nil, map[string]string{HeaderRef: key})
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		task    *asynq.Task
+		want    string
+		wantErr bool
+	}{
+		{name: "inline", ctx: context.Background(), task: asynq.NewTask("test:small", []byte(`{}`)), want: `{}`},
+		{name: "offloaded", ctx: withStore, task: offloaded, want: `{"big":true}`},
+		{name: "no store", ctx: context.Background(), task: offloaded, wantErr: true},
+		{
+			name:    "missing payload",
+			ctx:     withStore,
+			task:    asynq.NewTaskWithHeaders("test:big", nil, map[string]string{HeaderRef: uuid.NewString()}),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Payload(tt.ctx, tt.task)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Payload() = %q, want an error", got)
+				}
+				return
+			}
+			if err != nil || string(got) != tt.want {
+				t.Errorf("Payload() = %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+}
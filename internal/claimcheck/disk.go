@@ -0,0 +1,126 @@
+package claimcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DiskStore keeps payloads as files in a directory. The API and the workers must
+// share the directory, e.g. through a mounted volume.
+type DiskStore struct {
+	dir string
+}
+
+// NewDiskStore returns a store writing to dir, creating it if needed.
+func NewDiskStore(dir string) (*DiskStore, error) {
+	if dir == "" {
+		return nil, errors.New("payload store directory is not set")
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create payload store directory: %w", err)
+	}
+	return &DiskStore{dir: dir}, nil
+}
+
+// path returns the file of key. Keys are task IDs, so anything else is rejected
+// rather than allowed to escape the directory.
+func (s *DiskStore) path(key string) (string, error) {
+	if _, err := uuid.Parse(key); err != nil {
+		return "", fmt.Errorf("invalid payload key %q: %w", key, err)
+	}
+	return filepath.Join(s.dir, key), nil
+}
+
+func (s *DiskStore) Put(_ context.Context, key string, data []byte) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so readers never see a partial payload
+	tmp, err := os.CreateTemp(s.dir, ".tmp-"+key+"-")
+	if err != nil {
+		return fmt.Errorf("failed to create payload file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write payload file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write payload file: %w", err)
+	}
+
+	// Link fails if the payload exists, keeping the one of the task already queued
+	if err := os.Link(tmp.Name(), path); err != nil && !errors.Is(err, fs.ErrExist) {
+		return fmt.Errorf("failed to store payload file: %w", err)
+	}
+	return nil
+}
+
+func (s *DiskStore) Get(_ context.Context, key string) ([]byte, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+func (s *DiskStore) Delete(_ context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete payload file: %w", err)
+	}
+	return nil
+}
+
+func (s *DiskStore) Keys(_ context.Context, before time.Time) ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list payload files: %w", err)
+	}
+
+	type file struct {
+		key     string
+		modTime time.Time
+	}
+	var files []file
+	for _, entry := range entries {
+		// Skips the temporary files of writes in progress, and anything else that is not a payload
+		if !entry.Type().IsRegular() || uuid.Validate(entry.Name()) != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat payload file: %w", err)
+		}
+		if info.ModTime().Before(before) {
+			files = append(files, file{key: entry.Name(), modTime: info.ModTime()})
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+
+	keys := make([]string, len(files))
+	for i, f := range files {
+		keys[i] = f.key
+	}
+	return keys, nil
+}
@@ -0,0 +1,71 @@
+package claimcheck
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestDiskStore(t *testing.T) {
+	store, err := NewDiskStore(filepath.Join(t.TempDir(), "payloads"))
+	if err != nil {
+		t.Fatalf("NewDiskStore() = %v", err)
+	}
+	testStore(t, store)
+}
+
+func TestDiskStoreKeys(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewDiskStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	older, newer := uuid.NewString(), uuid.NewString()
+	for key, age := range map[string]time.Duration{older: 2 * time.Hour, newer: time.Hour} {
+		if err := store.Put(ctx, key, []byte("x")); err != nil {
+			t.Fatal(err)
+		}
+		mtime := time.Now().Add(-age)
+		if err := os.Chtimes(filepath.Join(dir, key), mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Temporary files of writes in progress and foreign files are not payloads
+	for _, name := range []string{".tmp-" + older + "-1", "README"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	keys, err := store.Keys(ctx, time.Now().Add(time.Minute))
+	if err != nil || !slices.Equal(keys, []string{older, newer}) {
+		t.Errorf("Keys() = %v, %v; want %s then %s", keys, err, older, newer)
+	}
+	keys, err = store.Keys(ctx, time.Now().Add(-90*time.Minute))
+	if err != nil || !slices.Equal(keys, []string{older}) {
+		t.Errorf("Keys() before the newer payload = %v, %v; want %s", keys, err, older)
+	}
+}
+
+func TestDiskStoreInvalidKey(t *testing.T) {
+	store, err := NewDiskStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Keys are task IDs, so a path cannot escape the directory
+	for _, key := range []string{"../escape", "", "not-a-uuid"} {
+		if err := store.Put(context.Background(), key, []byte("x")); err == nil {
+			t.Errorf("Put(%q) succeeded, want an invalid key error", key)
+		}
+	}
+	if _, err := NewDiskStore(""); err == nil {
+		t.Error("NewDiskStore(\"\") succeeded, want an error")
+	}
+}
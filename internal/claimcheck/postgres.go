@@ -0,0 +1,63 @@
+package claimcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"boiler-go/internal/db"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// PostgresStore keeps payloads in the task_payloads table.
+type PostgresStore struct {
+	queries *db.Queries
+}
+
+// NewPostgresStore returns a store backed by the task_payloads table.
+func NewPostgresStore(queries *db.Queries) *PostgresStore {
+	return &PostgresStore{queries: queries}
+}
+
+func (s *PostgresStore) Put(ctx context.Context, key string, data []byte) error {
+	id, err := db.JobID(key)
+	if err != nil {
+		return fmt.Errorf("invalid payload key %q: %w", key, err)
+	}
+	return s.queries.CreateTaskPayload(ctx, db.CreateTaskPayloadParams{TaskID: id, Data: data})
+}
+
+func (s *PostgresStore) Get(ctx context.Context, key string) ([]byte, error) {
+	id, err := db.JobID(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid payload key %q: %w", key, err)
+	}
+	data, err := s.queries.GetTaskPayload(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+func (s *PostgresStore) Delete(ctx context.Context, key string) error {
+	id, err := db.JobID(key)
+	if err != nil {
+		return fmt.Errorf("invalid payload key %q: %w", key, err)
+	}
+	return s.queries.DeleteTaskPayload(ctx, id)
+}
+
+func (s *PostgresStore) Keys(ctx context.Context, before time.Time) ([]string, error) {
+	ids, err := s.queries.ListTaskPayloadKeys(ctx, pgtype.Timestamptz{Time: before, Valid: true})
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = id.String()
+	}
+	return keys, nil
+}
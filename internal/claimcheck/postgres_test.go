@@ -0,0 +1,14 @@
+//go:build integration
+
+package claimcheck
+
+import (
+	"testing"
+
+	"boiler-go/internal/db"
+	"boiler-go/internal/dbtest"
+)
+
+func TestPostgresStore(t *testing.T) {
+	testStore(t, NewPostgresStore(db.New(dbtest.Pool(t))))
+}
@@ -15,19 +15,96 @@ import (
 type Config struct {
 	// server
 	AppPort string `env:"APP_PORT" envDefault:"8080"`
+	// WorkerMetricsPort: port of the worker's Prometheus /metrics listener
+	WorkerMetricsPort string `env:"WORKER_METRICS_PORT" envDefault:"9091"`
 
 	// database
 	DatabaseURL string `env:"DATABASE_URL,required"`
 
 	// redis / asynq
-	RedisAddr     string `env:"REDIS_ADDR,required"`
+	// RedisAddr: required unless the API and worker run without Redis, see UsesRedis
+	RedisAddr     string `env:"REDIS_ADDR"`
 	RedisPassword string `env:"REDIS_PASSWORD"`
 	RedisDB       int    `env:"REDIS_DB" envDefault:"0"`
 
+	// queue backend
+	// QueueMode: "queue" hands tasks to QUEUE_BACKEND for cmd/worker to process;
+	// "inline" runs them in the API process, for development and tests
+	QueueMode string `env:"QUEUE_MODE" envDefault:"queue"`
+	// QueueInlineConcurrency: tasks run at once in inline mode; 0 runs each task before its enqueue returns
+	QueueInlineConcurrency int `env:"QUEUE_INLINE_CONCURRENCY" envDefault:"0"`
+	// QueueBackend: where tasks are queued: "asynq" (Redis) | "postgres" (queue_tasks table)
+	QueueBackend string `env:"QUEUE_BACKEND" envDefault:"asynq"`
+	// QueuePollInterval: how often idle workers poll the postgres backend for due tasks
+	QueuePollInterval time.Duration `env:"QUEUE_POLL_INTERVAL" envDefault:"1s"`
+	// QueueVisibilityTimeout: how long a postgres backend task stays leased to a worker that stopped heartbeating
+	QueueVisibilityTimeout time.Duration `env:"QUEUE_VISIBILITY_TIMEOUT" envDefault:"30s"`
+
 	// timeouts
-	HealthCheckTimeout    time.Duration `env:"HEALTH_CHECK_TIMEOUT" envDefault:"2s"`
-	APIShutdownTimeout    time.Duration `env:"API_SHUTDOWN_TIMEOUT" envDefault:"10s"`
-	WorkerShutdownTimeout time.Duration `env:"WORKER_SHUTDOWN_TIMEOUT" envDefault:"30s"`
+	HealthCheckTimeout       time.Duration `env:"HEALTH_CHECK_TIMEOUT" envDefault:"2s"`
+	APIShutdownTimeout       time.Duration `env:"API_SHUTDOWN_TIMEOUT" envDefault:"10s"`
+	WorkerShutdownTimeout    time.Duration `env:"WORKER_SHUTDOWN_TIMEOUT" envDefault:"30s"`
+	SchedulerShutdownTimeout time.Duration `env:"SCHEDULER_SHUTDOWN_TIMEOUT" envDefault:"10s"`
+
+	// health
+	// WorkerLivenessWindow: how long a queue may go without a live worker before /health reports degraded
+	WorkerLivenessWindow time.Duration `env:"WORKER_LIVENESS_WINDOW" envDefault:"1m"`
+
+	// rate limiting
+	// TaskRateLimits: token-bucket limits per task type shared by all workers,
+	// e.g. "email:send=10/s,report:build=100/m". Parsed and validated with ratelimit.ParseRules
+	// by the processes that run tasks.
+	TaskRateLimits map[string]string `env:"TASK_RATE_LIMITS" envKeyValSeparator:"="`
+
+	// progress
+	// ProgressTTL: how long the latest progress event of a task is kept in Redis
+	ProgressTTL time.Duration `env:"PROGRESS_TTL" envDefault:"24h"`
+
+	// webhooks
+	// WebhookSecret: HMAC key signing task completion webhooks; callbacks fail without it
+	WebhookSecret string `env:"WEBHOOK_SECRET"`
+	// WebhookTimeout: timeout of each webhook delivery request
+	WebhookTimeout time.Duration `env:"WEBHOOK_TIMEOUT" envDefault:"10s"`
+	// WebhookAllowPrivate: deliver webhooks to loopback, private and other non-global addresses; for development only
+	WebhookAllowPrivate bool `env:"WEBHOOK_ALLOW_PRIVATE" envDefault:"false"`
+
+	// payload offloading
+	// PayloadStore: where payloads above PayloadOffloadThreshold are kept: "postgres" | "disk"
+	PayloadStore string `env:"PAYLOAD_STORE" envDefault:"postgres"`
+	// PayloadStoreDir: directory of the "disk" payload store, shared by the API and workers
+	PayloadStoreDir string `env:"PAYLOAD_STORE_DIR" envDefault:"data/payloads"`
+	// PayloadOffloadThreshold: payloads larger than this many bytes are kept out of Redis; 0 disables offloading
+	PayloadOffloadThreshold int `env:"PAYLOAD_OFFLOAD_THRESHOLD" envDefault:"65536"`
+	// PayloadSweepInterval: how often offloaded payloads whose task is gone are deleted; 0 disables
+	PayloadSweepInterval time.Duration `env:"PAYLOAD_SWEEP_INTERVAL" envDefault:"1h"`
+
+	// outbox
+	// OutboxPollInterval: how often the outbox relay polls when no notification arrives
+	OutboxPollInterval time.Duration `env:"OUTBOX_POLL_INTERVAL" envDefault:"1s"`
+	// OutboxBatchSize: maximum number of outbox messages handed to asynq per transaction
+	OutboxBatchSize int `env:"OUTBOX_BATCH_SIZE" envDefault:"100"`
+	// OutboxRetention: how long dispatched outbox messages are kept
+	OutboxRetention time.Duration `env:"OUTBOX_RETENTION" envDefault:"168h"`
+
+	// task groups
+	// GroupGracePeriod: how long a group waits for another task before it is aggregated; at least 1s
+	GroupGracePeriod time.Duration `env:"GROUP_GRACE_PERIOD" envDefault:"1m"`
+	// GroupMaxDelay: longest a group waits before it is aggregated; 0 means no limit
+	GroupMaxDelay time.Duration `env:"GROUP_MAX_DELAY" envDefault:"10m"`
+	// GroupMaxSize: number of tasks that makes a group aggregate at once; 0 means no limit
+	GroupMaxSize int `env:"GROUP_MAX_SIZE" envDefault:"100"`
+
+	// batch enqueue
+	// BatchMaxSize: maximum number of tasks accepted by POST /worker/tasks/batch
+	BatchMaxSize int `env:"BATCH_MAX_SIZE" envDefault:"1000"`
+
+	// idempotency
+	// IdempotencyTTL: how long the first response to an Idempotency-Key is replayed
+	IdempotencyTTL time.Duration `env:"IDEMPOTENCY_TTL" envDefault:"24h"`
+
+	// scheduler
+	// PeriodicSyncInterval: how often the scheduler re-reads the periodic_tasks table
+	PeriodicSyncInterval time.Duration `env:"PERIODIC_SYNC_INTERVAL" envDefault:"1m"`
 
 	// logging
 	// LogOutput: "stdout" | "file" | "both" (default: "stdout")
@@ -35,6 +112,7 @@ type Config struct {
 	// LogFile: path to log file when LogOutput is "file" or "both"
 	// For API: defaults to "logs/api.log"
 	// For Worker: defaults to "logs/worker.log"
+	// For Scheduler: defaults to "logs/scheduler.log"
 	LogFile string `env:"LOG_FILE"`
 }
 
@@ -65,7 +143,7 @@ func Load(logg zerolog.Logger) *Config {
 		if err := validateDatabaseURL(c.DatabaseURL); err != nil {
 			logg.Fatal().Err(err).Msg("invalid DATABASE_URL")
 		}
-		if c.RedisAddr == "" {
+		if c.RedisAddr == "" && c.UsesRedis() {
 			logg.Fatal().Msg("REDIS_ADDR is required")
 		}
 		// Validate Redis DB index (0-15 typically, Redis supports 0-15 in default config)
@@ -76,15 +154,84 @@ func Load(logg zerolog.Logger) *Config {
 		if err := validatePort(c.AppPort); err != nil {
 			logg.Fatal().Err(err).Msg("invalid APP_PORT")
 		}
+		if err := validatePort(c.WorkerMetricsPort); err != nil {
+			logg.Fatal().Err(err).Msg("invalid WORKER_METRICS_PORT")
+		}
 		if c.HealthCheckTimeout <= 0 {
 			logg.Fatal().Msg("HEALTH_CHECK_TIMEOUT must be positive")
 		}
 		if c.APIShutdownTimeout <= 0 {
 			logg.Fatal().Msg("API_SHUTDOWN_TIMEOUT must be positive")
 		}
+		if c.QueueMode != "queue" && c.QueueMode != "inline" {
+			logg.Fatal().Msg("QUEUE_MODE must be one of: queue, inline")
+		}
+		if c.QueueInlineConcurrency < 0 {
+			logg.Fatal().Msg("QUEUE_INLINE_CONCURRENCY must not be negative")
+		}
+		if c.QueueBackend != "asynq" && c.QueueBackend != "postgres" {
+			logg.Fatal().Msg("QUEUE_BACKEND must be one of: asynq, postgres")
+		}
+		if c.QueuePollInterval <= 0 {
+			logg.Fatal().Msg("QUEUE_POLL_INTERVAL must be positive")
+		}
+		if c.QueueVisibilityTimeout <= 0 {
+			logg.Fatal().Msg("QUEUE_VISIBILITY_TIMEOUT must be positive")
+		}
 		if c.WorkerShutdownTimeout <= 0 {
 			logg.Fatal().Msg("WORKER_SHUTDOWN_TIMEOUT must be positive")
 		}
+		if c.SchedulerShutdownTimeout <= 0 {
+			logg.Fatal().Msg("SCHEDULER_SHUTDOWN_TIMEOUT must be positive")
+		}
+		if c.WorkerLivenessWindow <= 0 {
+			logg.Fatal().Msg("WORKER_LIVENESS_WINDOW must be positive")
+		}
+		if c.ProgressTTL <= 0 {
+			logg.Fatal().Msg("PROGRESS_TTL must be positive")
+		}
+		if c.WebhookTimeout <= 0 {
+			logg.Fatal().Msg("WEBHOOK_TIMEOUT must be positive")
+		}
+		if c.PayloadStore != "postgres" && c.PayloadStore != "disk" {
+			logg.Fatal().Msg("PAYLOAD_STORE must be one of: postgres, disk")
+		}
+		if c.PayloadStore == "disk" && c.PayloadStoreDir == "" {
+			logg.Fatal().Msg("PAYLOAD_STORE_DIR is required when PAYLOAD_STORE is disk")
+		}
+		if c.PayloadOffloadThreshold < 0 {
+			logg.Fatal().Msg("PAYLOAD_OFFLOAD_THRESHOLD must not be negative")
+		}
+		if c.PayloadSweepInterval < 0 {
+			logg.Fatal().Msg("PAYLOAD_SWEEP_INTERVAL must not be negative")
+		}
+		if c.OutboxPollInterval <= 0 {
+			logg.Fatal().Msg("OUTBOX_POLL_INTERVAL must be positive")
+		}
+		if c.OutboxBatchSize <= 0 {
+			logg.Fatal().Msg("OUTBOX_BATCH_SIZE must be positive")
+		}
+		if c.OutboxRetention <= 0 {
+			logg.Fatal().Msg("OUTBOX_RETENTION must be positive")
+		}
+		if c.GroupGracePeriod < time.Second {
+			logg.Fatal().Msg("GROUP_GRACE_PERIOD must be at least 1s")
+		}
+		if c.GroupMaxDelay < 0 {
+			logg.Fatal().Msg("GROUP_MAX_DELAY must not be negative")
+		}
+		if c.GroupMaxSize < 0 {
+			logg.Fatal().Msg("GROUP_MAX_SIZE must not be negative")
+		}
+		if c.BatchMaxSize <= 0 {
+			logg.Fatal().Msg("BATCH_MAX_SIZE must be positive")
+		}
+		if c.IdempotencyTTL <= 0 {
+			logg.Fatal().Msg("IDEMPOTENCY_TTL must be positive")
+		}
+		if c.PeriodicSyncInterval <= 0 {
+			logg.Fatal().Msg("PERIODIC_SYNC_INTERVAL must be positive")
+		}
 
 		// Validate LOG_OUTPUT
 		if c.LogOutput != "stdout" && c.LogOutput != "file" && c.LogOutput != "both" {
@@ -97,6 +244,14 @@ func Load(logg zerolog.Logger) *Config {
 	return cfg
 }
 
+// UsesRedis reports whether the API and the worker need Redis. The postgres backend
+// queues tasks without it, and inline mode runs them in the API; rate limits, progress
+// events and the idempotency response cache are then turned off. cmd/scheduler needs
+// Redis unless the backend is postgres.
+func (c *Config) UsesRedis() bool {
+	return c.QueueMode == "queue" && c.QueueBackend != "postgres"
+}
+
 // validatePort validates that the port string is a valid port number (1-65535)
 func validatePort(port string) error {
 	if port == "" {
@@ -0,0 +1,29 @@
+package db
+
+import (
+	"encoding/json"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// JobID converts an asynq task ID into a jobs primary key.
+// Task IDs are UUIDs unless a caller overrides them with asynq.TaskID.
+func JobID(taskID string) (pgtype.UUID, error) {
+	return ParseUUID(taskID)
+}
+
+// ParseUUID parses a UUID path or query parameter into a primary key.
+func ParseUUID(s string) (pgtype.UUID, error) {
+	var id pgtype.UUID
+	err := id.Scan(s)
+	return id, err
+}
+
+// JobPayload returns payload if it can be stored in the JSONB payload column.
+// Non-JSON payloads are not persisted.
+func JobPayload(payload []byte) []byte {
+	if len(payload) == 0 || !json.Valid(payload) {
+		return nil
+	}
+	return payload
+}
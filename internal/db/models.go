@@ -3,3 +3,144 @@
 //   sqlc v1.30.0
 
 package db
+
+import (
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type Job struct {
+	ID          pgtype.UUID        `json:"id"`
+	TaskType    string             `json:"task_type"`
+	Queue       string             `json:"queue"`
+	Payload     []byte             `json:"payload"`
+	Status      string             `json:"status"`
+	Attempts    int32              `json:"attempts"`
+	LastError   pgtype.Text        `json:"last_error"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
+	CompletedAt pgtype.Timestamptz `json:"completed_at"`
+}
+
+type Outbox struct {
+	ID           int64              `json:"id"`
+	TaskID       pgtype.UUID        `json:"task_id"`
+	TaskType     string             `json:"task_type"`
+	Payload      []byte             `json:"payload"`
+	Headers      []byte             `json:"headers"`
+	Options      []byte             `json:"options"`
+	Status       string             `json:"status"`
+	Attempts     int32              `json:"attempts"`
+	LastError    pgtype.Text        `json:"last_error"`
+	AvailableAt  pgtype.Timestamptz `json:"available_at"`
+	CreatedAt    pgtype.Timestamptz `json:"created_at"`
+	DispatchedAt pgtype.Timestamptz `json:"dispatched_at"`
+}
+
+type PeriodicTask struct {
+	ID        pgtype.UUID        `json:"id"`
+	Name      string             `json:"name"`
+	Cronspec  string             `json:"cronspec"`
+	TaskType  string             `json:"task_type"`
+	Payload   []byte             `json:"payload"`
+	Queue     string             `json:"queue"`
+	Timezone  string             `json:"timezone"`
+	Enabled   bool               `json:"enabled"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+}
+
+type QueueServer struct {
+	ID            pgtype.UUID        `json:"id"`
+	Host          string             `json:"host"`
+	Pid           int32              `json:"pid"`
+	Concurrency   int32              `json:"concurrency"`
+	Queues        []byte             `json:"queues"`
+	Status        string             `json:"status"`
+	ActiveWorkers int32              `json:"active_workers"`
+	StartedAt     pgtype.Timestamptz `json:"started_at"`
+	HeartbeatAt   pgtype.Timestamptz `json:"heartbeat_at"`
+	ExpiresAt     pgtype.Timestamptz `json:"expires_at"`
+}
+
+type QueueTask struct {
+	ID              pgtype.UUID        `json:"id"`
+	Queue           string             `json:"queue"`
+	TaskType        string             `json:"task_type"`
+	Payload         []byte             `json:"payload"`
+	Headers         []byte             `json:"headers"`
+	State           string             `json:"state"`
+	Retried         int32              `json:"retried"`
+	MaxRetry        int32              `json:"max_retry"`
+	TimeoutMs       int64              `json:"timeout_ms"`
+	Deadline        pgtype.Timestamptz `json:"deadline"`
+	RetentionMs     int64              `json:"retention_ms"`
+	RunAt           pgtype.Timestamptz `json:"run_at"`
+	LeaseExpiresAt  pgtype.Timestamptz `json:"lease_expires_at"`
+	CancelRequested bool               `json:"cancel_requested"`
+	LastError       pgtype.Text        `json:"last_error"`
+	LastFailedAt    pgtype.Timestamptz `json:"last_failed_at"`
+	Result          []byte             `json:"result"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
+	CompletedAt     pgtype.Timestamptz `json:"completed_at"`
+	ExpiresAt       pgtype.Timestamptz `json:"expires_at"`
+	LeaseToken      pgtype.UUID        `json:"lease_token"`
+}
+
+type TaskPayload struct {
+	TaskID    pgtype.UUID        `json:"task_id"`
+	Data      []byte             `json:"data"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type User struct {
+	ID        pgtype.UUID        `json:"id"`
+	Email     string             `json:"email"`
+	Name      string             `json:"name"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+}
+
+type WebhookDelivery struct {
+	ID             pgtype.UUID        `json:"id"`
+	TaskID         pgtype.UUID        `json:"task_id"`
+	TaskType       string             `json:"task_type"`
+	Event          string             `json:"event"`
+	CallbackUrl    string             `json:"callback_url"`
+	Payload        []byte             `json:"payload"`
+	Status         string             `json:"status"`
+	Attempts       int32              `json:"attempts"`
+	LastStatusCode pgtype.Int4        `json:"last_status_code"`
+	LastError      pgtype.Text        `json:"last_error"`
+	History        []byte             `json:"history"`
+	CreatedAt      pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt      pgtype.Timestamptz `json:"updated_at"`
+	DeliveredAt    pgtype.Timestamptz `json:"delivered_at"`
+}
+
+type Workflow struct {
+	ID          pgtype.UUID        `json:"id"`
+	Name        string             `json:"name"`
+	Status      string             `json:"status"`
+	OnFailure   string             `json:"on_failure"`
+	LastError   pgtype.Text        `json:"last_error"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
+	CompletedAt pgtype.Timestamptz `json:"completed_at"`
+}
+
+type WorkflowStep struct {
+	WorkflowID         pgtype.UUID        `json:"workflow_id"`
+	Name               string             `json:"name"`
+	Position           int32              `json:"position"`
+	TaskType           string             `json:"task_type"`
+	Payload            []byte             `json:"payload"`
+	DependsOn          []string           `json:"depends_on"`
+	CompensateTaskType pgtype.Text        `json:"compensate_task_type"`
+	CompensatePayload  []byte             `json:"compensate_payload"`
+	Status             string             `json:"status"`
+	JobID              pgtype.UUID        `json:"job_id"`
+	CompensationJobID  pgtype.UUID        `json:"compensation_job_id"`
+	CreatedAt          pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt          pgtype.Timestamptz `json:"updated_at"`
+}
@@ -0,0 +1,324 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"boiler-go/internal/claimcheck"
+	"boiler-go/internal/db"
+	"boiler-go/internal/progress"
+	"boiler-go/internal/queue"
+	"boiler-go/pkg/logger"
+
+	"github.com/hibiken/asynq"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
+)
+
+const (
+	defaultArchivedPageSize = 20
+	maxArchivedPageSize     = 100
+	// payloadPreviewBytes caps how much of each payload is returned in listings.
+	payloadPreviewBytes = 256
+)
+
+// ArchivedTask describes a task that exhausted its retries.
+type ArchivedTask struct {
+	ID               string     `json:"id"`
+	Type             string     `json:"type"`
+	Queue            string     `json:"queue"`
+	PayloadPreview   string     `json:"payload_preview"`
+	PayloadSize      int        `json:"payload_size"`
+	PayloadOffloaded bool       `json:"payload_offloaded,omitempty"`
+	Retried          int        `json:"retried"`
+	MaxRetry         int        `json:"max_retry"`
+	LastError        string     `json:"last_error"`
+	LastFailedAt     *time.Time `json:"last_failed_at,omitempty"`
+}
+
+// ArchivedListResponse represents a page of archived tasks
+type ArchivedListResponse struct {
+	Queue    string         `json:"queue"`
+	Page     int            `json:"page"`
+	PageSize int            `json:"page_size"`
+	Total    int            `json:"total"`
+	Tasks    []ArchivedTask `json:"tasks"`
+}
+
+// ListArchived returns a page of archived tasks in a queue
+// GET /worker/queues/:queue/archived?page=1&page_size=20
+func (h *WorkerHandler) ListArchived(c echo.Context) error {
+	log := logger.FromEchoContext(c)
+	queueName := c.Param("queue")
+	if !queue.Valid(queueName) {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "unknown queue",
+		})
+	}
+
+	page, err := positiveQueryInt(c, "page", 1)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "page must be a positive integer",
+		})
+	}
+	pageSize, err := positiveQueryInt(c, "page_size", defaultArchivedPageSize)
+	if err != nil || pageSize > maxArchivedPageSize {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "page_size must be between 1 and " + strconv.Itoa(maxArchivedPageSize),
+		})
+	}
+
+	response := ArchivedListResponse{
+		Queue:    queueName,
+		Page:     page,
+		PageSize: pageSize,
+		Tasks:    []ArchivedTask{},
+	}
+
+	infos, err := h.inspector.ListArchivedTasks(queueName, asynq.Page(page), asynq.PageSize(pageSize))
+	if err != nil {
+		// A queue that never received a task has nothing archived
+		if errors.Is(err, asynq.ErrQueueNotFound) {
+			return c.JSON(http.StatusOK, response)
+		}
+		log.Error().Err(err).Str("queue", queueName).Msg("failed to list archived tasks")
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{
+			"error":   "failed to list archived tasks",
+			"details": err.Error(),
+		})
+	}
+
+	if stats, err := h.inspector.GetQueueInfo(queueName); err == nil {
+		response.Total = stats.Archived
+	}
+
+	for _, info := range infos {
+		preview := info.Payload
+		if len(preview) > payloadPreviewBytes {
+			preview = preview[:payloadPreviewBytes]
+		}
+		response.Tasks = append(response.Tasks, ArchivedTask{
+			ID:               info.ID,
+			Type:             info.Type,
+			Queue:            info.Queue,
+			PayloadPreview:   string(preview),
+			PayloadSize:      len(info.Payload),
+			PayloadOffloaded: info.Headers[claimcheck.HeaderRef] != "",
+			Retried:          info.Retried,
+			MaxRetry:         info.MaxRetry,
+			LastError:        info.LastErr,
+			LastFailedAt:     timePtr(info.LastFailedAt),
+		})
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// RunArchived moves an archived task back to pending
+// POST /worker/queues/:queue/archived/:id/run
+func (h *WorkerHandler) RunArchived(c echo.Context) error {
+	log := archiveLogger(c, "run")
+	queueName, taskID := c.Param("queue"), c.Param("id")
+	if !queue.Valid(queueName) {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "unknown queue",
+		})
+	}
+
+	info, err := h.inspector.GetTaskInfo(queueName, taskID)
+	if err != nil {
+		return h.archiveActionError(c, log, err)
+	}
+	if info.State != asynq.TaskStateArchived {
+		return c.JSON(http.StatusConflict, map[string]string{
+			"error": "task is not archived",
+			"state": info.State.String(),
+		})
+	}
+
+	if err := h.inspector.RunTask(queueName, taskID); err != nil {
+		return h.archiveActionError(c, log, err)
+	}
+	h.markJobPending(c, log, taskID)
+
+	log.Info().
+		Str("task_id", taskID).
+		Str("task_type", info.Type).
+		Msg("archived task re-queued by operator")
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"task_id": taskID,
+		"queue":   queueName,
+		"state":   asynq.TaskStatePending.String(),
+	})
+}
+
+// DeleteArchived permanently removes an archived task from asynq.
+// The jobs row is kept as the audit record.
+// DELETE /worker/queues/:queue/archived/:id
+func (h *WorkerHandler) DeleteArchived(c echo.Context) error {
+	log := archiveLogger(c, "delete")
+	queueName, taskID := c.Param("queue"), c.Param("id")
+	if !queue.Valid(queueName) {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "unknown queue",
+		})
+	}
+
+	info, err := h.inspector.GetTaskInfo(queueName, taskID)
+	if err != nil {
+		return h.archiveActionError(c, log, err)
+	}
+	if info.State != asynq.TaskStateArchived {
+		return c.JSON(http.StatusConflict, map[string]string{
+			"error": "task is not archived",
+			"state": info.State.String(),
+		})
+	}
+
+	if err := h.inspector.DeleteTask(queueName, taskID); err != nil {
+		return h.archiveActionError(c, log, err)
+	}
+	// Payloads of failed tasks are kept for re-runs until the archived task is deleted
+	if info.Headers[claimcheck.HeaderRef] != "" {
+		if err := h.scheduler.DeletePayload(c.Request().Context(), taskID); err != nil {
+			log.Error().Err(err).Str("task_id", taskID).Msg("failed to delete offloaded payload")
+		}
+	}
+
+	log.Info().
+		Str("task_id", taskID).
+		Str("task_type", info.Type).
+		Msg("archived task deleted by operator")
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// RunAllArchived re-queues every archived task in a queue, optionally only those of one task type
+// POST /worker/queues/:queue/archived/run?task_type=worker:ping
+func (h *WorkerHandler) RunAllArchived(c echo.Context) error {
+	log := archiveLogger(c, "run_all")
+	queueName := c.Param("queue")
+	taskType := c.QueryParam("task_type")
+	if !queue.Valid(queueName) {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "unknown queue",
+		})
+	}
+
+	count, err := h.runArchivedTasks(c, log, queueName, taskType)
+	if err != nil && !errors.Is(err, asynq.ErrQueueNotFound) {
+		log.Error().Err(err).Str("task_type", taskType).Int("count", count).Msg("bulk re-run of archived tasks failed")
+		return c.JSON(http.StatusServiceUnavailable, map[string]any{
+			"error":   "failed to re-run archived tasks",
+			"details": err.Error(),
+			"count":   count,
+		})
+	}
+
+	log.Info().
+		Str("task_type", taskType).
+		Int("count", count).
+		Msg("archived tasks re-queued by operator")
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"queue":     queueName,
+		"task_type": taskType,
+		"count":     count,
+	})
+}
+
+// runArchivedTasks re-queues archived tasks one by one, keeping only taskType when set.
+// asynq's RunAllArchivedTasks cannot filter by type and would leave the jobs rows untouched.
+func (h *WorkerHandler) runArchivedTasks(c echo.Context, log zerolog.Logger, queueName, taskType string) (int, error) {
+	// Collect IDs first: re-running tasks while paging would shift the pages
+	var ids []string
+	for page := 1; ; page++ {
+		infos, err := h.inspector.ListArchivedTasks(queueName, asynq.Page(page), asynq.PageSize(maxArchivedPageSize))
+		if err != nil {
+			return 0, err
+		}
+		for _, info := range infos {
+			if taskType == "" || info.Type == taskType {
+				ids = append(ids, info.ID)
+			}
+		}
+		if len(infos) < maxArchivedPageSize {
+			break
+		}
+	}
+
+	count := 0
+	for _, id := range ids {
+		if err := h.inspector.RunTask(queueName, id); err != nil {
+			// Another operator may have handled the task in the meantime
+			if errors.Is(err, asynq.ErrTaskNotFound) {
+				continue
+			}
+			return count, err
+		}
+		h.markJobPending(c, log, id)
+		count++
+	}
+	return count, nil
+}
+
+// markJobPending reflects a re-queued task in the jobs table, and replaces its failed
+// progress event so event streams follow the new run.
+func (h *WorkerHandler) markJobPending(c echo.Context, log zerolog.Logger, taskID string) {
+	ctx := c.Request().Context()
+	if h.progress != nil {
+		if err := h.progress.Publish(ctx, progress.Event{TaskID: taskID, Type: progress.EventProgress, Stage: "requeued"}); err != nil {
+			log.Warn().Err(err).Str("task_id", taskID).Msg("failed to reset task progress")
+		}
+	}
+
+	jobID, err := db.JobID(taskID)
+	if err != nil {
+		return
+	}
+	if err := h.queries.MarkJobPending(ctx, jobID); err != nil {
+		log.Error().Err(err).Str("task_id", taskID).Msg("failed to update job status")
+	}
+}
+
+// archiveActionError maps inspector errors for a single task to a response.
+func (h *WorkerHandler) archiveActionError(c echo.Context, log zerolog.Logger, err error) error {
+	switch {
+	case errors.Is(err, asynq.ErrTaskNotFound), errors.Is(err, asynq.ErrQueueNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "task not found",
+		})
+	default:
+		log.Error().Err(err).Str("task_id", c.Param("id")).Msg("archived task action failed")
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{
+			"error":   "failed to update task",
+			"details": err.Error(),
+		})
+	}
+}
+
+// archiveLogger returns the request-scoped logger (which carries request_id)
+// annotated with the operator action being performed.
+func archiveLogger(c echo.Context, action string) zerolog.Logger {
+	return logger.FromEchoContext(c).With().
+		Str("action", action).
+		Str("queue", c.Param("queue")).
+		Logger()
+}
+
+// positiveQueryInt parses a positive integer query parameter, returning def when it is absent.
+func positiveQueryInt(c echo.Context, name string, def int) (int, error) {
+	raw := c.QueryParam(name)
+	if raw == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 1 {
+		return 0, errors.New(name + " must be a positive integer")
+	}
+	return n, nil
+}
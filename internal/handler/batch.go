@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"boiler-go/internal/scheduler"
+	"boiler-go/internal/tasks"
+	"boiler-go/pkg/logger"
+
+	"github.com/labstack/echo/v4"
+)
+
+// batchBodyLimit caps the request body of a batch enqueue.
+const batchBodyLimit = 10 << 20
+
+// BatchTaskRequest is one task of a batch enqueue
+type BatchTaskRequest struct {
+	TaskType string          `json:"task_type"`
+	Payload  json.RawMessage `json:"payload,omitempty"`
+	EnqueueOptions
+}
+
+// BatchEnqueueRequest represents the request body for a batch enqueue
+type BatchEnqueueRequest struct {
+	Tasks []BatchTaskRequest `json:"tasks"`
+}
+
+// BatchTaskResult is the outcome of the task at the same index of the request
+type BatchTaskResult struct {
+	Index    int    `json:"index"`
+	TaskType string `json:"task_type"`
+	TaskID   string `json:"task_id,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// BatchEnqueueResponse represents the per-task outcome of a batch enqueue
+type BatchEnqueueResponse struct {
+	Enqueued int               `json:"enqueued"`
+	Failed   int               `json:"failed"`
+	Results  []BatchTaskResult `json:"results"`
+}
+
+// EnqueueBatch enqueues many tasks in one request with partial-success semantics:
+// each task is enqueued or rejected on its own. Returns 202 if every task was enqueued,
+// 207 otherwise.
+// POST /worker/tasks/batch
+func (h *WorkerHandler) EnqueueBatch(c echo.Context) error {
+	log := logger.FromEchoContext(c)
+	req := c.Request()
+	req.Body = http.MaxBytesReader(c.Response(), req.Body, batchBodyLimit)
+
+	var body BatchEnqueueRequest
+	if err := c.Bind(&body); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "invalid request body",
+		})
+	}
+	if len(body.Tasks) == 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "tasks must not be empty",
+		})
+	}
+	if len(body.Tasks) > h.batchMaxSize {
+		return c.JSON(http.StatusRequestEntityTooLarge, map[string]string{
+			"error": fmt.Sprintf("a batch may contain at most %d tasks", h.batchMaxSize),
+		})
+	}
+
+	// Internal task types fail the whole request, unknown ones only their own task
+	for i, t := range body.Tasks {
+		if spec, ok := tasks.Lookup(t.TaskType); ok && !spec.API {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": fmt.Sprintf("tasks[%d]: task_type %q cannot be enqueued through the API", i, t.TaskType),
+			})
+		}
+	}
+
+	results := make([]BatchTaskResult, len(body.Tasks))
+	batch := make([]scheduler.BatchTask, 0, len(body.Tasks))
+	// index maps each valid task back to its position in the request
+	index := make([]int, 0, len(body.Tasks))
+	now := time.Now().UTC()
+	for i, t := range body.Tasks {
+		results[i] = BatchTaskResult{Index: i, TaskType: t.TaskType}
+
+		opts, ok := tasks.DefaultOptions(t.TaskType)
+		if !ok {
+			results[i].Error = "unknown task_type"
+			continue
+		}
+		if len(t.Payload) > 0 && !json.Valid(t.Payload) {
+			results[i].Error = "payload must be valid JSON"
+			continue
+		}
+		scheduling, _, err := t.options(now)
+		if err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		// Request options come after the defaults so they override them
+		opts = append(opts, scheduling...)
+
+		batch = append(batch, scheduler.BatchTask{
+			Type:    t.TaskType,
+			Payload: t.Payload,
+			Opts:    opts,
+			Headers: t.headers(),
+		})
+		index = append(index, i)
+	}
+
+	for j, res := range h.scheduler.EnqueueBatch(req.Context(), batch) {
+		i := index[j]
+		if res.Err != nil {
+			results[i].Error = res.Err.Error()
+			continue
+		}
+		results[i].TaskID = res.TaskID
+	}
+
+	response := BatchEnqueueResponse{Results: results}
+	for _, res := range results {
+		if res.Error != "" {
+			response.Failed++
+		} else {
+			response.Enqueued++
+		}
+	}
+
+	logEvent := log.Info()
+	if response.Failed > 0 {
+		logEvent = log.Warn()
+	}
+	logEvent.
+		Int("enqueued", response.Enqueued).
+		Int("failed", response.Failed).
+		Msg("batch enqueue completed")
+
+	status := http.StatusAccepted
+	if response.Failed > 0 {
+		status = http.StatusMultiStatus
+	}
+	return c.JSON(status, response)
+}
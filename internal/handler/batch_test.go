@@ -0,0 +1,113 @@
+//go:build integration
+
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"boiler-go/internal/db"
+	"boiler-go/internal/dbtest"
+	"boiler-go/internal/scheduler"
+	"boiler-go/internal/tasks"
+
+	"github.com/hibiken/asynq"
+	"github.com/labstack/echo/v4"
+)
+
+// pingRecorder records the payloads of the worker:ping tasks it processes.
+type pingRecorder struct {
+	mu       sync.Mutex
+	payloads []tasks.PingPayload
+}
+
+func (r *pingRecorder) handle(_ context.Context, _ *asynq.Task, payload tasks.PingPayload) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.payloads = append(r.payloads, payload)
+	return nil
+}
+
+// newTestWorkerHandler returns a handler enqueueing to an inline backend, which runs
+// worker:ping tasks with rec before Enqueue returns.
+func newTestWorkerHandler(t *testing.T, rec *pingRecorder) (*WorkerHandler, *db.Queries) {
+	t.Helper()
+	pool := dbtest.Pool(t)
+	mux := asynq.NewServeMux()
+	tasks.WorkerPing.Handle(mux, rec.handle)
+	client := scheduler.NewClientWithBackend(scheduler.NewInlineBackend(mux, scheduler.InlineConfig{}), pool)
+	t.Cleanup(func() { client.Close() })
+	queries := db.New(pool)
+	return NewWorkerHandler(client, nil, queries, nil, nil, 10), queries
+}
+
+func postBatch(t *testing.T, h *WorkerHandler, body string) (int, BatchEnqueueResponse) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/worker/tasks/batch", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	if err := h.EnqueueBatch(echo.New().NewContext(req, rec)); err != nil {
+		t.Fatalf("EnqueueBatch() = %v", err)
+	}
+	var res BatchEnqueueResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatalf("failed to decode response %q: %v", rec.Body.String(), err)
+	}
+	return rec.Code, res
+}
+
+func TestEnqueueBatchWithoutPayload(t *testing.T) {
+	rec := &pingRecorder{}
+	h, queries := newTestWorkerHandler(t, rec)
+
+	code, res := postBatch(t, h, `{"tasks":[{"task_type":"worker:ping"}]}`)
+	if code != http.StatusAccepted {
+		t.Fatalf("status = %d, want %d: %+v", code, http.StatusAccepted, res)
+	}
+	if res.Enqueued != 1 || res.Results[0].TaskID == "" {
+		t.Fatalf("response = %+v, want one enqueued task", res)
+	}
+	if len(rec.payloads) != 1 || rec.payloads[0] != (tasks.PingPayload{}) {
+		t.Fatalf("handler payloads = %+v, want one zero payload", rec.payloads)
+	}
+
+	id, err := db.ParseUUID(res.Results[0].TaskID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, err := queries.GetJob(context.Background(), id)
+	if err != nil {
+		t.Fatalf("GetJob() = %v", err)
+	}
+	if job.Payload != nil {
+		t.Errorf("job payload = %s, want none", job.Payload)
+	}
+}
+
+func TestEnqueueBatchWithPayload(t *testing.T) {
+	rec := &pingRecorder{}
+	h, _ := newTestWorkerHandler(t, rec)
+
+	code, res := postBatch(t, h, `{"tasks":[
+		{"task_type":"worker:ping","payload":{"message":"hello"}},
+		{"task_type":"worker:ping","queue":"unknown"},
+		{"task_type":"unknown:type"}
+	]}`)
+	if code != http.StatusMultiStatus {
+		t.Fatalf("status = %d, want %d: %+v", code, http.StatusMultiStatus, res)
+	}
+	if res.Enqueued != 1 || res.Failed != 2 {
+		t.Fatalf("response = %+v, want 1 enqueued and 2 failed", res)
+	}
+	if res.Results[0].TaskID == "" || res.Results[1].Error == "" || res.Results[2].Error == "" {
+		t.Errorf("results = %+v, want the first task enqueued and the others rejected", res.Results)
+	}
+	if len(rec.payloads) != 1 || rec.payloads[0].Message != "hello" {
+		t.Fatalf("handler payloads = %+v, want one with message hello", rec.payloads)
+	}
+}
@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"boiler-go/internal/claimcheck"
+	"boiler-go/internal/progress"
+	"boiler-go/internal/workflow"
+	"boiler-go/pkg/logger"
+
+	"github.com/hibiken/asynq"
+	"github.com/labstack/echo/v4"
+)
+
+// errTaskCancelled is the step outcome reported for workflow steps cancelled before they ran.
+var errTaskCancelled = errors.New("task cancelled")
+
+// CancelTaskResponse describes a cancelled task
+type CancelTaskResponse struct {
+	TaskID string `json:"task_id"`
+	// PreviousState is the asynq state the task was in, or its jobs status if asynq does not hold it.
+	PreviousState string `json:"previous_state"`
+	State         string `json:"state"`
+	Message       string `json:"message"`
+}
+
+// Cancel stops a task. Pending, scheduled and retry tasks are deleted from their queue,
+// and active tasks have their handler context canceled. Returns 409 once the task has
+// completed or been archived.
+// POST /worker/tasks/:id/cancel
+func (h *WorkerHandler) Cancel(c echo.Context) error {
+	log := logger.FromEchoContext(c)
+	ctx := c.Request().Context()
+	taskID := c.Param("id")
+
+	job, ok, err := h.loadJob(c, taskID)
+	if !ok {
+		return err
+	}
+
+	// Mark the job first: the worker revokes any attempt of a cancelled job before
+	// running its handler, which covers tasks that start while this request runs.
+	rows, err := h.queries.MarkJobCancelled(ctx, job.ID)
+	if err != nil {
+		log.Error().Err(err).Str("task_id", taskID).Msg("failed to mark job cancelled")
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "failed to cancel task",
+		})
+	}
+	if rows == 0 {
+		// Reload, the status may have changed since the job was loaded
+		if job, ok, err = h.loadJob(c, taskID); !ok {
+			return err
+		}
+		if job.Status == "cancelled" {
+			return c.JSON(http.StatusOK, CancelTaskResponse{
+				TaskID:        taskID,
+				PreviousState: job.Status,
+				State:         job.Status,
+				Message:       "Task was already cancelled.",
+			})
+		}
+		return c.JSON(http.StatusConflict, map[string]string{
+			"error":   "task can no longer be cancelled",
+			"details": "task is " + job.Status,
+		})
+	}
+
+	previous, message, removed, err := h.stopTask(c.Request().Context(), job.Queue, taskID)
+	if err != nil {
+		// The job stays cancelled, so the worker still revokes the task on its next attempt
+		log.Error().Err(err).Str("task_id", taskID).Msg("failed to cancel task")
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{
+			"error":   "failed to cancel task",
+			"details": err.Error(),
+		})
+	}
+	if previous == "" {
+		previous = job.Status
+	}
+
+	// A removed task never reaches the worker, so release its offloaded payload here
+	if removed != nil && removed.Headers[claimcheck.HeaderRef] != "" {
+		if err := h.scheduler.DeletePayload(ctx, taskID); err != nil {
+			log.Error().Err(err).Str("task_id", taskID).Msg("failed to delete offloaded payload")
+		}
+	}
+
+	// A removed step never reaches the worker, so report its outcome here to fail the workflow
+	if removed != nil {
+		if workflowID, step, ok := workflow.StepFromHeaders(removed.Headers); ok {
+			if err := h.workflows.StepFinished(ctx, workflowID, step, errTaskCancelled); err != nil {
+				log.Error().
+					Err(err).
+					Str("workflow_id", workflowID).
+					Str("workflow_step", step).
+					Msg("failed to advance workflow")
+			}
+		}
+	}
+
+	// Ends event streams now: an active task only reaches the worker's revocation on its retry
+	if h.progress != nil {
+		if err := h.progress.Publish(ctx, progress.Event{TaskID: taskID, Type: progress.EventCancelled}); err != nil {
+			log.Warn().Err(err).Str("task_id", taskID).Msg("failed to publish task cancellation")
+		}
+	}
+
+	log.Info().
+		Str("task_id", taskID).
+		Str("previous_state", previous).
+		Msg("task cancelled")
+
+	return c.JSON(http.StatusOK, CancelTaskResponse{
+		TaskID:        taskID,
+		PreviousState: previous,
+		State:         "cancelled",
+		Message:       message,
+	})
+}
+
+// stopTask removes the task from the queue backend, or cancels its handler if it is running.
+// It returns the queue state of the task, which is empty when the backend does not hold it,
+// and the task's info if it was removed before it could run.
+func (h *WorkerHandler) stopTask(ctx context.Context, queueName, taskID string) (state, message string, removed *asynq.TaskInfo, err error) {
+	info, err := h.scheduler.TaskInfo(ctx, queueName, taskID)
+	if errors.Is(err, asynq.ErrTaskNotFound) || errors.Is(err, asynq.ErrQueueNotFound) {
+		return "", "Task cancelled. It is not in a queue, and any later attempt will be revoked.", nil, nil
+	}
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	state = info.State.String()
+	switch info.State {
+	case asynq.TaskStateActive:
+		if err := h.scheduler.CancelProcessing(ctx, taskID); err != nil {
+			return state, "", nil, err
+		}
+		return state, "Cancellation sent to the running handler.", nil, nil
+	case asynq.TaskStateCompleted, asynq.TaskStateArchived:
+		return state, "Task had already finished in the queue.", nil, nil
+	}
+
+	err = h.scheduler.DeleteTask(ctx, queueName, taskID)
+	if err == nil {
+		return state, "Task removed from the queue.", info, nil
+	}
+	if errors.Is(err, asynq.ErrTaskNotFound) {
+		return state, "Task removed from the queue.", nil, nil
+	}
+
+	// DeleteTask refuses active tasks, so the task may have started since it was inspected
+	current, infoErr := h.scheduler.TaskInfo(ctx, queueName, taskID)
+	if infoErr != nil || current.State != asynq.TaskStateActive {
+		return state, "", nil, err
+	}
+	if err := h.scheduler.CancelProcessing(ctx, taskID); err != nil {
+		return state, "", nil, err
+	}
+	return current.State.String(), "Cancellation sent to the running handler.", nil, nil
+}
@@ -0,0 +1,116 @@
+//go:build integration
+
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"boiler-go/internal/db"
+	"boiler-go/internal/tasks"
+
+	"github.com/hibiken/asynq"
+	"github.com/labstack/echo/v4"
+)
+
+func cancelTask(t *testing.T, h *WorkerHandler, taskID string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/worker/tasks/"+taskID+"/cancel", nil)
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(taskID)
+	if err := h.Cancel(c); err != nil {
+		t.Fatalf("Cancel() = %v", err)
+	}
+	return rec
+}
+
+func TestCancelScheduledTask(t *testing.T) {
+	pings := &pingRecorder{}
+	h, queries := newTestWorkerHandler(t, pings)
+	ctx := context.Background()
+
+	taskID, err := tasks.WorkerPing.Enqueue(ctx, h.scheduler, tasks.PingPayload{}, asynq.ProcessIn(time.Hour))
+	if err != nil {
+		t.Fatalf("Enqueue() = %v", err)
+	}
+
+	rec := cancelTask(t, h, taskID)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	var res CancelTaskResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	if res.PreviousState != "scheduled" || res.State != "cancelled" {
+		t.Errorf("response = %+v, want a cancelled scheduled task", res)
+	}
+	if _, err := h.scheduler.TaskInfo(ctx, "default", taskID); !errors.Is(err, asynq.ErrTaskNotFound) {
+		t.Errorf("TaskInfo() = %v, want the task removed from its queue", err)
+	}
+	jobID, _ := db.JobID(taskID)
+	if job, err := queries.GetJob(ctx, jobID); err != nil || job.Status != "cancelled" {
+		t.Errorf("job = %q, %v; want cancelled", job.Status, err)
+	}
+
+	// Cancelling again is a no-op
+	rec = cancelTask(t, h, taskID)
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK || res.PreviousState != "cancelled" {
+		t.Errorf("second cancel = %d %+v, want 200 for an already cancelled task", rec.Code, res)
+	}
+	if len(pings.payloads) != 0 {
+		t.Errorf("handler ran %d times, want the task never run", len(pings.payloads))
+	}
+}
+
+func TestCancelFinishedTask(t *testing.T) {
+	h, queries := newTestWorkerHandler(t, &pingRecorder{})
+	ctx := context.Background()
+
+	taskID, err := tasks.WorkerPing.Enqueue(ctx, h.scheduler, tasks.PingPayload{})
+	if err != nil {
+		t.Fatalf("Enqueue() = %v", err)
+	}
+	// Without the worker's middlewares the outcome is not recorded, so record it here
+	jobID, _ := db.JobID(taskID)
+	if err := queries.MarkJobCompleted(ctx, jobID); err != nil {
+		t.Fatalf("MarkJobCompleted() = %v", err)
+	}
+
+	if rec := cancelTask(t, h, taskID); rec.Code != http.StatusConflict {
+		t.Errorf("status = %d, want %d: %s", rec.Code, http.StatusConflict, rec.Body)
+	}
+	if job, err := queries.GetJob(ctx, jobID); err != nil || job.Status != "completed" {
+		t.Errorf("job = %q, %v; want it still completed", job.Status, err)
+	}
+}
+
+func TestCancelErrors(t *testing.T) {
+	h, _ := newTestWorkerHandler(t, &pingRecorder{})
+
+	tests := []struct {
+		name   string
+		taskID string
+		want   int
+	}{
+		{name: "invalid id", taskID: "not-a-uuid", want: http.StatusBadRequest},
+		{name: "unknown task", taskID: "00000000-0000-0000-0000-000000000000", want: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := cancelTask(t, h, tt.taskID); rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
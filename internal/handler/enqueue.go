@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"boiler-go/internal/queue"
+	"boiler-go/internal/webhook"
+
+	"github.com/hibiken/asynq"
+)
+
+// EnqueueOptions are the scheduling options accepted by enqueue endpoints.
+// Unset fields keep the defaults registered for the task type.
+type EnqueueOptions struct {
+	// Queue overrides the task type's queue. Must be one of queue.Names().
+	Queue string `json:"queue,omitempty"`
+	// ProcessAt schedules the task for a point in time.
+	ProcessAt *time.Time `json:"process_at,omitempty"`
+	// ProcessIn schedules the task after a delay, as a Go duration such as "90s" or "24h".
+	ProcessIn string `json:"process_in,omitempty"`
+	// Deadline is the time by which the task must finish, across all retries.
+	Deadline *time.Time `json:"deadline,omitempty"`
+	// CallbackURL is notified with a signed webhook once the task completes or fails for good.
+	CallbackURL string `json:"callback_url,omitempty"`
+}
+
+// options validates the scheduling options and maps them to asynq options.
+// It also returns when the task becomes ready, which is zero for immediate tasks.
+func (o EnqueueOptions) options(now time.Time) ([]asynq.Option, time.Time, error) {
+	var opts []asynq.Option
+	var processAt time.Time
+
+	if o.Queue != "" {
+		if !queue.Valid(o.Queue) {
+			return nil, time.Time{}, fmt.Errorf("unknown queue %q", o.Queue)
+		}
+		opts = append(opts, asynq.Queue(o.Queue))
+	}
+
+	if o.ProcessAt != nil && o.ProcessIn != "" {
+		return nil, time.Time{}, errors.New("process_at and process_in are mutually exclusive")
+	}
+	if o.ProcessAt != nil {
+		processAt = o.ProcessAt.UTC()
+		opts = append(opts, asynq.ProcessAt(processAt))
+	}
+	if o.ProcessIn != "" {
+		delay, err := time.ParseDuration(o.ProcessIn)
+		if err != nil || delay <= 0 {
+			return nil, time.Time{}, errors.New("process_in must be a positive duration such as \"90s\" or \"24h\"")
+		}
+		processAt = now.Add(delay).UTC()
+		opts = append(opts, asynq.ProcessIn(delay))
+	}
+
+	if o.Deadline != nil {
+		if !o.Deadline.After(now) {
+			return nil, time.Time{}, errors.New("deadline must be in the future")
+		}
+		if !processAt.IsZero() && !o.Deadline.After(processAt) {
+			return nil, time.Time{}, errors.New("deadline must be after the scheduled process time")
+		}
+		opts = append(opts, asynq.Deadline(o.Deadline.UTC()))
+	}
+
+	if o.CallbackURL != "" {
+		if err := webhook.ValidateURL(o.CallbackURL); err != nil {
+			return nil, time.Time{}, err
+		}
+	}
+
+	// A process time in the past means run now
+	if !processAt.After(now) {
+		processAt = time.Time{}
+	}
+	return opts, processAt, nil
+}
+
+// headers returns the task headers carrying the options that are not asynq options.
+func (o EnqueueOptions) headers() map[string]string {
+	if o.CallbackURL == "" {
+		return nil
+	}
+	return map[string]string{webhook.HeaderCallbackURL: o.CallbackURL}
+}
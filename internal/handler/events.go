@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"boiler-go/internal/db"
+	"boiler-go/internal/progress"
+	"boiler-go/pkg/logger"
+
+	"github.com/labstack/echo/v4"
+)
+
+// sseHeartbeatInterval keeps idle streams from being closed by proxies.
+const sseHeartbeatInterval = 15 * time.Second
+
+// Events streams the progress of a task as Server-Sent Events. It sends the latest
+// known event first, then every event as it is published, and ends the stream after
+// the completed, failed or cancelled event. Events are kept in Redis, so without it
+// the endpoint answers 501.
+// GET /worker/tasks/:id/events
+func (h *WorkerHandler) Events(c echo.Context) error {
+	log := logger.FromEchoContext(c)
+	ctx := c.Request().Context()
+	taskID := c.Param("id")
+
+	if h.progress == nil {
+		return c.JSON(http.StatusNotImplemented, map[string]string{
+			"error": "task progress events need Redis",
+		})
+	}
+
+	job, ok, err := h.loadJob(c, taskID)
+	if !ok {
+		return err
+	}
+
+	// Subscribe before reading the latest event so nothing published in between is missed
+	sub, err := h.progress.Subscribe(ctx, taskID)
+	if err != nil {
+		log.Error().Err(err).Str("task_id", taskID).Msg("failed to subscribe to task progress")
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{
+			"error":   "failed to stream task progress",
+			"details": err.Error(),
+		})
+	}
+	defer sub.Close()
+
+	latest, found, err := h.progress.Latest(ctx, taskID)
+	if err != nil {
+		log.Error().Err(err).Str("task_id", taskID).Msg("failed to load task progress")
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{
+			"error":   "failed to stream task progress",
+			"details": err.Error(),
+		})
+	}
+	// The latest event expires with PROGRESS_TTL, the jobs row does not
+	if !latest.Terminal() {
+		if event, ok := jobEvent(taskID, job); ok {
+			latest, found = event, true
+		}
+	}
+
+	// Streams outlive the server's WriteTimeout
+	if err := http.NewResponseController(c.Response()).SetWriteDeadline(time.Time{}); err != nil {
+		log.Warn().Err(err).Msg("failed to clear write deadline of event stream")
+	}
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set(echo.HeaderCacheControl, "no-cache")
+	res.Header().Set(echo.HeaderConnection, "keep-alive")
+	// Disable response buffering in nginx
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+	res.Flush()
+
+	if found {
+		if err := writeEvent(res, latest); err != nil || latest.Terminal() {
+			return nil
+		}
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	// Write errors mean the client is gone, and the response is already committed
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(res, ": heartbeat\n\n"); err != nil {
+				return nil
+			}
+			res.Flush()
+		case event, ok := <-sub.Events():
+			if !ok {
+				return nil
+			}
+			if err := writeEvent(res, event); err != nil || event.Terminal() {
+				return nil
+			}
+		}
+	}
+}
+
+// writeEvent writes e as an SSE message named after its type.
+func writeEvent(res *echo.Response, e progress.Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(res, "event: %s\ndata: %s\n\n", e.Type, data); err != nil {
+		return err
+	}
+	res.Flush()
+	return nil
+}
+
+// jobEvent returns the terminal event matching the status of a finished job.
+func jobEvent(taskID string, job db.Job) (progress.Event, bool) {
+	event := progress.Event{
+		TaskID: taskID,
+		At:     job.CompletedAt.Time,
+	}
+	switch job.Status {
+	case "completed":
+		event.Type = progress.EventCompleted
+		event.Percent = 100
+	case "archived":
+		event.Type = progress.EventFailed
+		event.Error = job.LastError.String
+	case "cancelled":
+		event.Type = progress.EventCancelled
+	default:
+		return progress.Event{}, false
+	}
+	return event, true
+}
@@ -2,30 +2,151 @@ package handler
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
+	"sort"
+	"sync"
 	"time"
 
+	"boiler-go/internal/db"
+	"boiler-go/internal/queue"
 	"boiler-go/pkg/logger"
 
+	"github.com/hibiken/asynq"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/labstack/echo/v4"
 	"github.com/redis/go-redis/v9"
 )
 
 type HealthHandler struct {
-	db      *pgxpool.Pool
-	redis   *redis.Client
+	db *pgxpool.Pool
+	// redis is nil when the API runs without Redis, see config.UsesRedis.
+	redis *redis.Client
+	// servers is nil in inline mode, which has no worker processes to check.
+	servers ServerLister
 	timeout time.Duration
+	// workerWindow is how long a queue may go without a live worker before the check degrades.
+	workerWindow time.Duration
+
+	mu sync.Mutex
+	// lastSeen is when each queue was last seen with a live worker subscribed.
+	// Queues never seen count from startup, which gives workers one window to come up.
+	lastSeen map[string]time.Time
+	started  time.Time
 }
 
-func NewHealthHandler(db *pgxpool.Pool, redis *redis.Client, timeout time.Duration) *HealthHandler {
+func NewHealthHandler(db *pgxpool.Pool, redis *redis.Client, servers ServerLister, timeout, workerWindow time.Duration) *HealthHandler {
 	return &HealthHandler{
-		db:      db,
-		redis:   redis,
-		timeout: timeout,
+		db:           db,
+		redis:        redis,
+		servers:      servers,
+		timeout:      timeout,
+		workerWindow: workerWindow,
+		lastSeen:     make(map[string]time.Time),
+		started:      time.Now(),
+	}
+}
+
+// WorkerServer describes a running worker process
+type WorkerServer struct {
+	ID            string         `json:"id"`
+	Host          string         `json:"host"`
+	PID           int            `json:"pid"`
+	Status        string         `json:"status"`
+	Concurrency   int            `json:"concurrency"`
+	ActiveWorkers int            `json:"active_workers"`
+	Queues        map[string]int `json:"queues"`
+	Started       time.Time      `json:"started"`
+	// HeartbeatAt is the last heartbeat of a postgres backend server.
+	// asynq does not expose the heartbeats of its servers.
+	HeartbeatAt *time.Time `json:"heartbeat_at,omitempty"`
+}
+
+// ServerLister lists the live worker servers.
+type ServerLister func(ctx context.Context) ([]WorkerServer, error)
+
+// AsynqServers lists the servers registered with asynq in Redis.
+func AsynqServers(inspector *asynq.Inspector) ServerLister {
+	return func(ctx context.Context) ([]WorkerServer, error) {
+		// Inspector.Servers takes no context, so the call is bounded by ctx from outside
+		type result struct {
+			servers []*asynq.ServerInfo
+			err     error
+		}
+		done := make(chan result, 1)
+		go func() {
+			servers, err := inspector.Servers()
+			done <- result{servers, err}
+		}()
+
+		var res result
+		select {
+		case res = <-done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if res.err != nil {
+			return nil, res.err
+		}
+
+		servers := make([]WorkerServer, 0, len(res.servers))
+		for _, srv := range res.servers {
+			servers = append(servers, WorkerServer{
+				ID:            srv.ID,
+				Host:          srv.Host,
+				PID:           srv.PID,
+				Status:        srv.Status,
+				Concurrency:   srv.Concurrency,
+				ActiveWorkers: len(srv.ActiveWorkers),
+				Queues:        srv.Queues,
+				Started:       srv.Started.UTC(),
+			})
+		}
+		return servers, nil
 	}
 }
 
+// PostgresServers lists the postgres backend servers whose heartbeat in the
+// queue_servers table has not expired.
+func PostgresServers(queries *db.Queries) ServerLister {
+	return func(ctx context.Context) ([]WorkerServer, error) {
+		rows, err := queries.ListQueueServers(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		servers := make([]WorkerServer, 0, len(rows))
+		for _, row := range rows {
+			var queues map[string]int
+			_ = json.Unmarshal(row.Queues, &queues)
+			servers = append(servers, WorkerServer{
+				ID:            row.ID.String(),
+				Host:          row.Host,
+				PID:           int(row.Pid),
+				Status:        row.Status,
+				Concurrency:   int(row.Concurrency),
+				ActiveWorkers: int(row.ActiveWorkers),
+				Queues:        queues,
+				Started:       row.StartedAt.Time.UTC(),
+				HeartbeatAt:   timePtr(row.HeartbeatAt.Time.UTC()),
+			})
+		}
+		return servers, nil
+	}
+}
+
+// WorkerQueueHealth describes whether a queue has a live worker subscribed
+type WorkerQueueHealth struct {
+	Subscribed bool       `json:"subscribed"`
+	LastSeen   *time.Time `json:"last_seen,omitempty"`
+}
+
+// WorkerHealth is the worker section of the health check
+type WorkerHealth struct {
+	Servers []WorkerServer               `json:"servers"`
+	Queues  map[string]WorkerQueueHealth `json:"queues"`
+}
+
 // Check handles GET /health using Echo's context.
 func (h *HealthHandler) Check(c echo.Context) error {
 	start := time.Now()
@@ -39,6 +160,7 @@ func (h *HealthHandler) Check(c echo.Context) error {
 	status := echo.Map{
 		"database": "up",
 		"redis":    "up",
+		"worker":   "up",
 	}
 	overall := http.StatusOK
 
@@ -48,12 +170,30 @@ func (h *HealthHandler) Check(c echo.Context) error {
 		overall = http.StatusServiceUnavailable
 	}
 
-	if err := h.redis.Ping(ctx).Err(); err != nil {
+	if h.redis == nil {
+		status["redis"] = "disabled"
+	} else if err := h.redis.Ping(ctx).Err(); err != nil {
 		log.Error().Err(err).Msg("redis health check failed")
 		status["redis"] = "down"
 		overall = http.StatusServiceUnavailable
 	}
 
+	// A missing worker degrades the check but deliberately keeps the 200: the API still
+	// accepts requests and queues tasks for the workers to catch up on, so a load balancer
+	// must not take it out of rotation. Alert on status.worker instead.
+	workers, degraded, err := h.checkWorkers(ctx)
+	switch {
+	case h.servers == nil:
+		// Inline mode runs tasks in this process, so there is no worker to check
+		status["worker"] = "inline"
+	case err != nil:
+		log.Error().Err(err).Msg("worker health check failed")
+		status["worker"] = "unknown"
+	case degraded:
+		log.Warn().Dur("window", h.workerWindow).Msg("worker health check degraded: queue without a live worker")
+		status["worker"] = "degraded"
+	}
+
 	duration := time.Since(start)
 
 	response := echo.Map{
@@ -61,15 +201,75 @@ func (h *HealthHandler) Check(c echo.Context) error {
 		"checked":  time.Now().UTC(),
 		"duration": duration.Milliseconds(),
 	}
+	if workers != nil {
+		response["workers"] = workers
+	}
 
 	// Log health check completion at Info level for operational visibility
 	dbStatus, _ := status["database"].(string)
 	redisStatus, _ := status["redis"].(string)
+	workerStatus, _ := status["worker"].(string)
 	log.Info().
 		Dur("duration", duration).
 		Str("database", dbStatus).
 		Str("redis", redisStatus).
+		Str("worker", workerStatus).
 		Msg("health check completed")
 
 	return c.JSON(overall, response)
 }
+
+// checkWorkers lists the live worker servers and reports whether any queue
+// has gone longer than the worker window without a worker subscribed to it.
+func (h *HealthHandler) checkWorkers(ctx context.Context) (*WorkerHealth, bool, error) {
+	if h.servers == nil {
+		return nil, false, nil
+	}
+	servers, err := h.servers(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	now := time.Now().UTC()
+	health := &WorkerHealth{
+		Servers: servers,
+		Queues:  make(map[string]WorkerQueueHealth, len(queue.Names())),
+	}
+
+	subscribed := make(map[string]bool)
+	for _, srv := range servers {
+		// Servers that are shutting down are listed until their heartbeat expires
+		if srv.Status != "active" {
+			continue
+		}
+		for name := range srv.Queues {
+			subscribed[name] = true
+		}
+	}
+	sort.Slice(health.Servers, func(i, j int) bool {
+		return health.Servers[i].Started.Before(health.Servers[j].Started)
+	})
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	degraded := false
+	for _, name := range queue.Names() {
+		if subscribed[name] {
+			h.lastSeen[name] = now
+		}
+
+		queueHealth := WorkerQueueHealth{Subscribed: subscribed[name]}
+		since := h.started
+		if seen, ok := h.lastSeen[name]; ok {
+			queueHealth.LastSeen = timePtr(seen)
+			since = seen
+		}
+		if now.Sub(since) > h.workerWindow {
+			degraded = true
+		}
+		health.Queues[name] = queueHealth
+	}
+
+	return health, degraded, nil
+}
@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"boiler-go/internal/queue"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/labstack/echo/v4"
+)
+
+// staticServers lists servers, or fails with err when it is set.
+func staticServers(servers []WorkerServer, err error) ServerLister {
+	return func(context.Context) ([]WorkerServer, error) {
+		return servers, err
+	}
+}
+
+func allQueues(status string) []WorkerServer {
+	queues := make(map[string]int)
+	for _, name := range queue.Names() {
+		queues[name] = 1
+	}
+	return []WorkerServer{{ID: "a", Status: status, Queues: queues}}
+}
+
+func TestCheckWorkers(t *testing.T) {
+	tests := []struct {
+		name         string
+		servers      []WorkerServer
+		started      time.Duration // how long ago the API started
+		wantDegraded bool
+	}{
+		{name: "all queues subscribed", servers: allQueues("active"), started: time.Hour},
+		{name: "no worker within the window", started: time.Second},
+		{name: "no worker past the window", started: time.Hour, wantDegraded: true},
+		{name: "closed worker past the window", servers: allQueues("closed"), started: time.Hour, wantDegraded: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHealthHandler(nil, nil, staticServers(tt.servers, nil), time.Second, time.Minute)
+			h.started = time.Now().Add(-tt.started)
+
+			health, degraded, err := h.checkWorkers(context.Background())
+			if err != nil {
+				t.Fatalf("checkWorkers() = %v", err)
+			}
+			if degraded != tt.wantDegraded {
+				t.Errorf("degraded = %v, want %v", degraded, tt.wantDegraded)
+			}
+			if len(health.Queues) != len(queue.Names()) {
+				t.Errorf("queues = %v, want every queue", health.Queues)
+			}
+		})
+	}
+}
+
+func TestCheckWorkersLastSeen(t *testing.T) {
+	servers := allQueues("active")
+	h := NewHealthHandler(nil, nil, func(context.Context) ([]WorkerServer, error) { return servers, nil }, time.Second, time.Minute)
+	h.started = time.Now().Add(-time.Hour)
+	if _, degraded, _ := h.checkWorkers(context.Background()); degraded {
+		t.Fatal("degraded with every queue subscribed")
+	}
+
+	// The window counts from when the queues were last seen, not from startup
+	servers = nil
+	health, degraded, err := h.checkWorkers(context.Background())
+	if err != nil || degraded {
+		t.Fatalf("checkWorkers() = %v, degraded %v; want healthy within the window", err, degraded)
+	}
+	q := health.Queues[queue.QueueDefault]
+	if q.Subscribed || q.LastSeen == nil {
+		t.Errorf("default queue = %+v, want unsubscribed with its last sighting", q)
+	}
+
+	h.lastSeen[queue.QueueDefault] = time.Now().Add(-time.Hour)
+	if _, degraded, _ := h.checkWorkers(context.Background()); !degraded {
+		t.Error("not degraded once the window passed")
+	}
+}
+
+func TestHealthCheckStatus(t *testing.T) {
+	// Nothing listens on port 1, so the database check fails fast
+	pool, err := pgxpool.New(context.Background(), "postgres://health@127.0.0.1:1/health?connect_timeout=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	tests := []struct {
+		name       string
+		servers    ServerLister
+		wantWorker string
+	}{
+		{name: "inline", wantWorker: "inline"},
+		{name: "worker list unavailable", servers: staticServers(nil, errors.New("redis down")), wantWorker: "unknown"},
+		{name: "workers up", servers: staticServers(allQueues("active"), nil), wantWorker: "up"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHealthHandler(pool, nil, tt.servers, time.Second, time.Minute)
+			rec := httptest.NewRecorder()
+			c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/health", nil), rec)
+			if err := h.Check(c); err != nil {
+				t.Fatalf("Check() = %v", err)
+			}
+			if rec.Code != http.StatusServiceUnavailable {
+				t.Errorf("status = %d, want %d with the database down", rec.Code, http.StatusServiceUnavailable)
+			}
+
+			var body struct {
+				Status  map[string]string `json:"status"`
+				Workers *struct {
+					Servers []map[string]any `json:"servers"`
+				} `json:"workers"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			want := map[string]string{"database": "down", "redis": "disabled", "worker": tt.wantWorker}
+			for k, v := range want {
+				if body.Status[k] != v {
+					t.Errorf("status.%s = %q, want %q", k, body.Status[k], v)
+				}
+			}
+			if tt.wantWorker == "up" && (body.Workers == nil || len(body.Workers.Servers) != 1) {
+				t.Fatalf("workers = %s, want the listed server", rec.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,294 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"boiler-go/internal/db"
+	"boiler-go/internal/scheduler"
+	"boiler-go/internal/tasks"
+	"boiler-go/pkg/logger"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/labstack/echo/v4"
+)
+
+// pgUniqueViolation is the Postgres error code for unique constraint violations.
+const pgUniqueViolation = "23505"
+
+type PeriodicTaskHandler struct {
+	queries *db.Queries
+}
+
+func NewPeriodicTaskHandler(queries *db.Queries) *PeriodicTaskHandler {
+	return &PeriodicTaskHandler{
+		queries: queries,
+	}
+}
+
+// PeriodicTaskRequest represents the request body to create or replace a periodic task
+type PeriodicTaskRequest struct {
+	Name     string          `json:"name"`
+	Cronspec string          `json:"cronspec"`
+	TaskType string          `json:"task_type"`
+	Payload  json.RawMessage `json:"payload,omitempty"`
+	// Queue defaults to the queue registered for the task type.
+	Queue string `json:"queue,omitempty"`
+	// Timezone defaults to UTC.
+	Timezone string `json:"timezone,omitempty"`
+	// Enabled defaults to true.
+	Enabled *bool `json:"enabled,omitempty"`
+}
+
+// PeriodicTaskResponse represents a stored periodic task
+type PeriodicTaskResponse struct {
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Cronspec  string          `json:"cronspec"`
+	TaskType  string          `json:"task_type"`
+	Payload   json.RawMessage `json:"payload,omitempty"`
+	Queue     string          `json:"queue"`
+	Timezone  string          `json:"timezone"`
+	Enabled   bool            `json:"enabled"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+func newPeriodicTaskResponse(row db.PeriodicTask) PeriodicTaskResponse {
+	return PeriodicTaskResponse{
+		ID:        row.ID.String(),
+		Name:      row.Name,
+		Cronspec:  row.Cronspec,
+		TaskType:  row.TaskType,
+		Payload:   row.Payload,
+		Queue:     row.Queue,
+		Timezone:  row.Timezone,
+		Enabled:   row.Enabled,
+		CreatedAt: row.CreatedAt.Time,
+		UpdatedAt: row.UpdatedAt.Time,
+	}
+}
+
+// normalize applies defaults and validates the request the same way the scheduler will.
+func (r *PeriodicTaskRequest) normalize() error {
+	if r.Name == "" {
+		return errors.New("name is required")
+	}
+	spec, ok := tasks.Lookup(r.TaskType)
+	if !ok {
+		return errors.New("unknown task_type")
+	}
+	if !spec.API {
+		return fmt.Errorf("task_type %q cannot be enqueued through the API", r.TaskType)
+	}
+	if r.Queue == "" {
+		r.Queue = spec.Queue
+	}
+	if r.Timezone == "" {
+		r.Timezone = "UTC"
+	}
+	if r.Enabled == nil {
+		enabled := true
+		r.Enabled = &enabled
+	}
+	if len(r.Payload) > 0 && !json.Valid(r.Payload) {
+		return errors.New("payload must be valid JSON")
+	}
+
+	return scheduler.PeriodicTask{
+		Cronspec: r.Cronspec,
+		TaskType: r.TaskType,
+		Queue:    r.Queue,
+		Timezone: r.Timezone,
+	}.Validate()
+}
+
+// List returns all periodic tasks
+// GET /periodic-tasks
+func (h *PeriodicTaskHandler) List(c echo.Context) error {
+	log := logger.FromEchoContext(c)
+
+	rows, err := h.queries.ListPeriodicTasks(c.Request().Context())
+	if err != nil {
+		log.Error().Err(err).Msg("failed to list periodic tasks")
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "failed to list periodic tasks",
+		})
+	}
+
+	response := make([]PeriodicTaskResponse, 0, len(rows))
+	for _, row := range rows {
+		response = append(response, newPeriodicTaskResponse(row))
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// Get returns a single periodic task
+// GET /periodic-tasks/:id
+func (h *PeriodicTaskHandler) Get(c echo.Context) error {
+	log := logger.FromEchoContext(c)
+
+	id, err := db.ParseUUID(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "invalid periodic task id",
+		})
+	}
+
+	row, err := h.queries.GetPeriodicTask(c.Request().Context(), id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "periodic task not found",
+			})
+		}
+		log.Error().Err(err).Msg("failed to load periodic task")
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "failed to load periodic task",
+		})
+	}
+
+	return c.JSON(http.StatusOK, newPeriodicTaskResponse(row))
+}
+
+// Create stores a new periodic task. The scheduler picks it up on its next sync.
+// POST /periodic-tasks
+func (h *PeriodicTaskHandler) Create(c echo.Context) error {
+	log := logger.FromEchoContext(c)
+
+	var body PeriodicTaskRequest
+	if err := c.Bind(&body); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "invalid request body",
+		})
+	}
+	if err := body.normalize(); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	row, err := h.queries.CreatePeriodicTask(c.Request().Context(), db.CreatePeriodicTaskParams{
+		Name:     body.Name,
+		Cronspec: body.Cronspec,
+		TaskType: body.TaskType,
+		Payload:  db.JobPayload(body.Payload),
+		Queue:    body.Queue,
+		Timezone: body.Timezone,
+		Enabled:  *body.Enabled,
+	})
+	if err != nil {
+		return periodicWriteError(c, err)
+	}
+
+	log.Info().
+		Str("periodic_task_id", row.ID.String()).
+		Str("name", row.Name).
+		Str("task_type", row.TaskType).
+		Str("cronspec", row.Cronspec).
+		Msg("periodic task created")
+
+	return c.JSON(http.StatusCreated, newPeriodicTaskResponse(row))
+}
+
+// Update replaces a periodic task. The scheduler picks up the change on its next sync.
+// PUT /periodic-tasks/:id
+func (h *PeriodicTaskHandler) Update(c echo.Context) error {
+	log := logger.FromEchoContext(c)
+
+	id, err := db.ParseUUID(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "invalid periodic task id",
+		})
+	}
+
+	var body PeriodicTaskRequest
+	if err := c.Bind(&body); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "invalid request body",
+		})
+	}
+	if err := body.normalize(); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	row, err := h.queries.UpdatePeriodicTask(c.Request().Context(), db.UpdatePeriodicTaskParams{
+		ID:       id,
+		Name:     body.Name,
+		Cronspec: body.Cronspec,
+		TaskType: body.TaskType,
+		Payload:  db.JobPayload(body.Payload),
+		Queue:    body.Queue,
+		Timezone: body.Timezone,
+		Enabled:  *body.Enabled,
+	})
+	if err != nil {
+		return periodicWriteError(c, err)
+	}
+
+	log.Info().
+		Str("periodic_task_id", row.ID.String()).
+		Str("name", row.Name).
+		Bool("enabled", row.Enabled).
+		Msg("periodic task updated")
+
+	return c.JSON(http.StatusOK, newPeriodicTaskResponse(row))
+}
+
+// Delete removes a periodic task. The scheduler unregisters it on its next sync.
+// DELETE /periodic-tasks/:id
+func (h *PeriodicTaskHandler) Delete(c echo.Context) error {
+	log := logger.FromEchoContext(c)
+
+	id, err := db.ParseUUID(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "invalid periodic task id",
+		})
+	}
+
+	deleted, err := h.queries.DeletePeriodicTask(c.Request().Context(), id)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to delete periodic task")
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "failed to delete periodic task",
+		})
+	}
+	if deleted == 0 {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "periodic task not found",
+		})
+	}
+
+	log.Info().Str("periodic_task_id", c.Param("id")).Msg("periodic task deleted")
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// periodicWriteError maps insert/update errors to a response.
+func periodicWriteError(c echo.Context, err error) error {
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "periodic task not found",
+		})
+	case errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation:
+		return c.JSON(http.StatusConflict, map[string]string{
+			"error": "a periodic task with this name already exists",
+		})
+	default:
+		log := logger.FromEchoContext(c)
+		log.Error().Err(err).Msg("failed to save periodic task")
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "failed to save periodic task",
+		})
+	}
+}
@@ -4,42 +4,102 @@ import (
 	"net/http"
 
 	"boiler-go/internal/config"
+	"boiler-go/internal/db"
+	"boiler-go/internal/metrics"
 	custommiddleware "boiler-go/internal/middleware"
+	"boiler-go/internal/progress"
 	"boiler-go/internal/scheduler"
+	"boiler-go/internal/tracing"
+	"boiler-go/internal/workflow"
 
+	"github.com/hibiken/asynq"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/labstack/echo/v4"
 	echomiddleware "github.com/labstack/echo/v4/middleware"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog"
 )
 
-func NewRouter(log zerolog.Logger, cfg *config.Config, db *pgxpool.Pool, redis *redis.Client, scheduler *scheduler.Client) http.Handler {
+// NewRouter builds the API routes. redis and inspector are nil when the API runs without
+// Redis: idempotent responses are then not cached, and progress events and queue
+// inspection answer 501. Worker liveness then comes from the queue_servers table, or is
+// not checked in inline mode, where the API runs the tasks itself.
+func NewRouter(log zerolog.Logger, cfg *config.Config, pool *pgxpool.Pool, redis *redis.Client, scheduler *scheduler.Client, inspector *asynq.Inspector, reg *prometheus.Registry) http.Handler {
 	e := echo.New()
 	e.HideBanner = true
 	e.HidePort = true
 
+	// Outermost, so requests that panic are recorded with the 500 written by Recover
+	e.Use(custommiddleware.Metrics(metrics.NewHTTPMetrics(reg)))
 	e.Use(echomiddleware.Recover())
 	e.Use(echomiddleware.CORSWithConfig(echomiddleware.CORSConfig{
 		AllowOrigins:     []string{"*"},
 		AllowMethods:     []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete, http.MethodOptions},
-		AllowHeaders:     []string{"Accept", "Authorization", "Content-Type", "X-Request-ID"},
-		ExposeHeaders:    []string{"Link", "X-Request-ID"},
+		AllowHeaders:     []string{"Accept", "Authorization", "Content-Type", "X-Request-ID", tracing.HeaderTraceparent, custommiddleware.IdempotencyKeyHeader},
+		ExposeHeaders:    []string{"Link", "X-Request-ID", custommiddleware.IdempotentReplayedHeader},
 		AllowCredentials: false,
 		MaxAge:           300,
 	}))
 	// Use native Echo middleware for request logging and request ID handling
 	e.Use(custommiddleware.RequestLogger(log))
 
-	health := NewHealthHandler(db, redis, cfg.HealthCheckTimeout)
-	worker := NewWorkerHandler(scheduler)
+	queries := db.New(pool)
+	engine := workflow.NewEngine(pool, scheduler)
+
+	var servers ServerLister
+	switch {
+	case inspector != nil:
+		servers = AsynqServers(inspector)
+	case cfg.QueueMode == "queue":
+		servers = PostgresServers(queries)
+	}
+	var progressStore *progress.Store
+	if redis != nil {
+		progressStore = progress.NewStore(redis, cfg.ProgressTTL)
+	}
+
+	health := NewHealthHandler(pool, redis, servers, cfg.HealthCheckTimeout, cfg.WorkerLivenessWindow)
+	worker := NewWorkerHandler(scheduler, inspector, queries, engine, progressStore, cfg.BatchMaxSize)
+	periodic := NewPeriodicTaskHandler(queries)
+	workflows := NewWorkflowHandler(engine, queries)
+
+	// Deduplicates retried enqueue requests that carry an Idempotency-Key header
+	idempotency := custommiddleware.Idempotency(redis, cfg.IdempotencyTTL)
 
 	e.GET("/health", health.Check)
+	e.GET("/metrics", echo.WrapHandler(metrics.Handler(reg)))
 
 	// Worker routes
 	workerGroup := e.Group("/worker")
-	workerGroup.GET("/status", worker.Status)
-	workerGroup.POST("/ping", worker.Ping)
+	workerGroup.GET("/status", worker.Status, worker.RequireInspector)
+	workerGroup.POST("/ping", worker.Ping, idempotency)
+	workerGroup.POST("/tasks/batch", worker.EnqueueBatch, idempotency)
+	workerGroup.GET("/tasks/:id", worker.Task)
+	workerGroup.GET("/tasks/:id/result", worker.Result)
+	workerGroup.GET("/tasks/:id/events", worker.Events)
+	workerGroup.POST("/tasks/:id/cancel", worker.Cancel)
+	workerGroup.GET("/tasks/:id/webhooks", worker.WebhookDeliveries)
+
+	// Archived (dead-letter) task management
+	archivedGroup := workerGroup.Group("/queues/:queue/archived", worker.RequireInspector)
+	archivedGroup.GET("", worker.ListArchived)
+	archivedGroup.POST("/run", worker.RunAllArchived)
+	archivedGroup.POST("/:id/run", worker.RunArchived)
+	archivedGroup.DELETE("/:id", worker.DeleteArchived)
+
+	// Runtime-defined periodic tasks, synced by cmd/scheduler
+	periodicGroup := e.Group("/periodic-tasks")
+	periodicGroup.GET("", periodic.List)
+	periodicGroup.POST("", periodic.Create)
+	periodicGroup.GET("/:id", periodic.Get)
+	periodicGroup.PUT("/:id", periodic.Update)
+	periodicGroup.DELETE("/:id", periodic.Delete)
+
+	// Task workflows (DAGs), advanced by the worker as steps finish
+	workflowGroup := e.Group("/workflows")
+	workflowGroup.POST("", workflows.Create, idempotency)
+	workflowGroup.GET("/:id", workflows.Get)
 
 	return e
 }
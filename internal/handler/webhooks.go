@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"boiler-go/internal/db"
+	"boiler-go/internal/webhook"
+	"boiler-go/pkg/logger"
+
+	"github.com/labstack/echo/v4"
+)
+
+// WebhookDeliveryResponse describes a completion notification and its delivery attempts.
+type WebhookDeliveryResponse struct {
+	ID             string            `json:"id"`
+	Event          string            `json:"event"`
+	CallbackURL    string            `json:"callback_url"`
+	Status         string            `json:"status"`
+	Attempts       int32             `json:"attempts"`
+	LastStatusCode *int32            `json:"last_status_code,omitempty"`
+	LastError      string            `json:"last_error,omitempty"`
+	History        []webhook.Attempt `json:"history"`
+	CreatedAt      time.Time         `json:"created_at"`
+	DeliveredAt    *time.Time        `json:"delivered_at,omitempty"`
+}
+
+// WebhookDeliveries lists the completion webhook deliveries of a task
+// GET /worker/tasks/:id/webhooks
+func (h *WorkerHandler) WebhookDeliveries(c echo.Context) error {
+	log := logger.FromEchoContext(c)
+	taskID := c.Param("id")
+
+	job, ok, err := h.loadJob(c, taskID)
+	if !ok {
+		return err
+	}
+
+	deliveries, err := h.queries.ListWebhookDeliveriesByTask(c.Request().Context(), job.ID)
+	if err != nil {
+		log.Error().Err(err).Str("task_id", taskID).Msg("failed to list webhook deliveries")
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "failed to list webhook deliveries",
+		})
+	}
+
+	response := make([]WebhookDeliveryResponse, 0, len(deliveries))
+	for _, d := range deliveries {
+		response = append(response, webhookDeliveryResponse(d))
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+func webhookDeliveryResponse(d db.WebhookDelivery) WebhookDeliveryResponse {
+	response := WebhookDeliveryResponse{
+		ID:          d.ID.String(),
+		Event:       d.Event,
+		CallbackURL: d.CallbackUrl,
+		Status:      d.Status,
+		Attempts:    d.Attempts,
+		LastError:   d.LastError.String,
+		History:     []webhook.Attempt{},
+		CreatedAt:   d.CreatedAt.Time,
+		DeliveredAt: timePtr(d.DeliveredAt.Time),
+	}
+	if d.LastStatusCode.Valid {
+		response.LastStatusCode = &d.LastStatusCode.Int32
+	}
+	// history is written by the worker, so a malformed value only leaves it empty
+	_ = json.Unmarshal(d.History, &response.History)
+	return response
+}
@@ -0,0 +1,109 @@
+//go:build integration
+
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"boiler-go/internal/db"
+	"boiler-go/internal/tasks"
+	"boiler-go/internal/webhook"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+)
+
+func getWebhookDeliveries(t *testing.T, h *WorkerHandler, taskID string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/worker/tasks/"+taskID+"/webhooks", nil)
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(taskID)
+	if err := h.WebhookDeliveries(c); err != nil {
+		t.Fatalf("WebhookDeliveries() = %v", err)
+	}
+	return rec
+}
+
+func TestWebhookDeliveries(t *testing.T) {
+	h, queries := newTestWorkerHandler(t, &pingRecorder{})
+	ctx := context.Background()
+
+	taskID, err := tasks.WorkerPing.Enqueue(ctx, h.scheduler, tasks.PingPayload{})
+	if err != nil {
+		t.Fatalf("Enqueue() = %v", err)
+	}
+	rec := getWebhookDeliveries(t, h, taskID)
+	if rec.Code != http.StatusOK || rec.Body.String() != "[]\n" {
+		t.Fatalf("GET = %d %s, want 200 with no deliveries", rec.Code, rec.Body)
+	}
+
+	jobID, _ := db.JobID(taskID)
+	delivery, err := queries.CreateWebhookDelivery(ctx, db.CreateWebhookDeliveryParams{
+		TaskID:      jobID,
+		TaskType:    tasks.WorkerPing.Type,
+		Event:       webhook.EventCompleted,
+		CallbackUrl: "https://example.com/hooks",
+		Payload:     []byte(`{}`),
+	})
+	if err != nil {
+		t.Fatalf("CreateWebhookDelivery() = %v", err)
+	}
+	err = queries.RecordWebhookAttempt(ctx, db.RecordWebhookAttemptParams{
+		ID:             delivery.ID,
+		Status:         webhook.StatusPending,
+		LastStatusCode: pgtype.Int4{Int32: http.StatusServiceUnavailable, Valid: true},
+		LastError:      pgtype.Text{String: "callback responded 503 Service Unavailable", Valid: true},
+		Attempt:        []byte(`[{"attempt":1,"at":"2026-01-01T00:00:00Z","status_code":503,"duration_ms":12}]`),
+	})
+	if err != nil {
+		t.Fatalf("RecordWebhookAttempt() = %v", err)
+	}
+
+	rec = getWebhookDeliveries(t, h, taskID)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	var got []WebhookDeliveryResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("deliveries = %+v, want 1", got)
+	}
+	d := got[0]
+	if d.ID != delivery.ID.String() || d.Event != webhook.EventCompleted || d.Status != webhook.StatusPending || d.Attempts != 1 {
+		t.Errorf("delivery = %+v, want the pending delivery after one attempt", d)
+	}
+	if d.LastStatusCode == nil || *d.LastStatusCode != http.StatusServiceUnavailable || d.DeliveredAt != nil {
+		t.Errorf("delivery = %+v, want last status 503 and not delivered", d)
+	}
+	if len(d.History) != 1 || d.History[0].StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("history = %+v, want the recorded attempt", d.History)
+	}
+}
+
+func TestWebhookDeliveriesErrors(t *testing.T) {
+	h, _ := newTestWorkerHandler(t, &pingRecorder{})
+
+	tests := []struct {
+		name   string
+		taskID string
+		want   int
+	}{
+		{name: "invalid id", taskID: "not-a-uuid", want: http.StatusBadRequest},
+		{name: "unknown task", taskID: "00000000-0000-0000-0000-000000000000", want: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := getWebhookDeliveries(t, h, tt.taskID); rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
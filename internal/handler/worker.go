@@ -2,38 +2,48 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"time"
 
+	"boiler-go/internal/db"
+	"boiler-go/internal/progress"
 	"boiler-go/internal/queue"
 	"boiler-go/internal/scheduler"
 	"boiler-go/internal/tasks"
+	"boiler-go/internal/workflow"
 	"boiler-go/pkg/logger"
 
 	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5"
 	"github.com/labstack/echo/v4"
 )
 
 type WorkerHandler struct {
 	scheduler *scheduler.Client
+	// inspector and progress are nil when the API runs without Redis.
+	inspector    *asynq.Inspector
+	queries      *db.Queries
+	workflows    *workflow.Engine
+	progress     *progress.Store
+	batchMaxSize int
 }
 
-func NewWorkerHandler(scheduler *scheduler.Client) *WorkerHandler {
+func NewWorkerHandler(scheduler *scheduler.Client, inspector *asynq.Inspector, queries *db.Queries, workflows *workflow.Engine, progress *progress.Store, batchMaxSize int) *WorkerHandler {
 	return &WorkerHandler{
-		scheduler: scheduler,
+		scheduler:    scheduler,
+		inspector:    inspector,
+		queries:      queries,
+		workflows:    workflows,
+		progress:     progress,
+		batchMaxSize: batchMaxSize,
 	}
 }
 
 // PingRequest represents the request body for worker ping
 type PingRequest struct {
 	Message string `json:"message,omitempty"`
-}
-
-// PingTaskPayload is the payload for the worker ping task, including correlation ID.
-type PingTaskPayload struct {
-	Message   string    `json:"message"`
-	RequestID string    `json:"request_id"`
-	QueuedAt  time.Time `json:"queued_at"`
+	EnqueueOptions
 }
 
 // PingResponse represents the response from worker ping
@@ -41,8 +51,11 @@ type PingResponse struct {
 	Success  bool      `json:"success"`
 	TaskID   string    `json:"task_id"`
 	TaskType string    `json:"task_type"`
+	Queue    string    `json:"queue"`
 	QueuedAt time.Time `json:"queued_at"`
-	Message  string    `json:"message,omitempty"`
+	// ProcessAt is set when the task was scheduled for later processing.
+	ProcessAt *time.Time `json:"process_at,omitempty"`
+	Message   string     `json:"message,omitempty"`
 }
 
 // Ping enqueues a test task to verify worker is processing jobs
@@ -53,23 +66,32 @@ func (h *WorkerHandler) Ping(c echo.Context) error {
 
 	log := logger.FromEchoContext(c)
 
-	// Extract request ID for correlation
-	requestID := req.Header.Get("X-Request-ID")
-
 	// Limit request body size to 1MB
 	req.Body = http.MaxBytesReader(res, req.Body, 1<<20)
 
-	// Parse optional message from request body
-	var payloadMsg string
+	// Parse optional message and scheduling options from request body
+	var body PingRequest
 	if req.ContentLength > 0 {
-		var body PingRequest
 		if err := c.Bind(&body); err != nil {
 			log.Error().Err(err).Msg("failed to decode ping request")
 			return c.JSON(http.StatusBadRequest, map[string]string{
 				"error": "invalid request body",
 			})
 		}
-		payloadMsg = body.Message
+	}
+	payloadMsg := body.Message
+
+	now := time.Now().UTC()
+	opts, processAt, err := body.options(now)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error":   "invalid enqueue options",
+			"details": err.Error(),
+		})
+	}
+	queueName := tasks.WorkerPing.Queue
+	if body.Queue != "" {
+		queueName = body.Queue
 	}
 
 	// Default message if not provided
@@ -77,26 +99,20 @@ func (h *WorkerHandler) Ping(c echo.Context) error {
 		payloadMsg = "ping from API"
 	}
 
-	// Build payload with correlation ID
-	payload := PingTaskPayload{
-		Message:   payloadMsg,
-		RequestID: requestID,
-		QueuedAt:  time.Now().UTC(),
+	payload := tasks.PingPayload{
+		Message:  payloadMsg,
+		QueuedAt: now,
 	}
-	payloadBytes, err := json.Marshal(payload)
-	if err != nil {
-		log.Error().Err(err).Msg("failed to marshal ping payload")
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "failed to create task payload",
-		})
+
+	// The request context carries the request ID and trace context into the task headers
+	ctx := req.Context()
+	if headers := body.headers(); headers != nil {
+		ctx = scheduler.WithTaskHeaders(ctx, headers)
 	}
 
-	// Enqueue the ping task
-	taskID, err := h.scheduler.EnqueueWithID(req.Context(), tasks.TypeWorkerPing, payloadBytes,
-		asynq.Queue(queue.QueueDefault),
-		asynq.MaxRetry(3),
-		asynq.Timeout(30*time.Second),
-	)
+	// Enqueue the ping task with the options declared in the task registry,
+	// overridden by any scheduling options from the request
+	taskID, err := tasks.WorkerPing.Enqueue(ctx, h.scheduler, payload, opts...)
 	if err != nil {
 		log.Error().Err(err).Msg("failed to enqueue worker ping task")
 		return c.JSON(http.StatusServiceUnavailable, map[string]string{
@@ -105,28 +121,309 @@ func (h *WorkerHandler) Ping(c echo.Context) error {
 		})
 	}
 
-	log.Info().
+	logEvent := log.Info().
 		Str("task_id", taskID).
 		Str("task_type", tasks.TypeWorkerPing).
-		Str("request_id", requestID).
-		Msg("worker ping task enqueued")
+		Str("queue", queueName)
+	if !processAt.IsZero() {
+		logEvent = logEvent.Time("process_at", processAt)
+	}
+	logEvent.Msg("worker ping task enqueued")
 
 	return c.JSON(http.StatusAccepted, PingResponse{
-		Success:  true,
-		TaskID:   taskID,
-		TaskType: tasks.TypeWorkerPing,
-		QueuedAt: time.Now().UTC(),
-		Message:  "Task queued successfully. Check worker logs to verify processing.",
+		Success:   true,
+		TaskID:    taskID,
+		TaskType:  tasks.TypeWorkerPing,
+		Queue:     queueName,
+		QueuedAt:  now,
+		ProcessAt: timePtr(processAt),
+		Message:   "Task queued successfully. Poll GET /worker/tasks/" + taskID + " for its status.",
 	})
 }
 
-// Status returns the current worker/queue status
+// TaskStatusResponse describes a task by combining asynq's live view with its jobs row.
+type TaskStatusResponse struct {
+	TaskID   string `json:"task_id"`
+	TaskType string `json:"task_type"`
+	Queue    string `json:"queue"`
+	// State is the asynq task state, or the jobs status once asynq no longer retains the task
+	// or the task has been cancelled.
+	State         string     `json:"state"`
+	JobStatus     string     `json:"job_status"`
+	Attempts      int32      `json:"attempts"`
+	Retried       int        `json:"retried"`
+	MaxRetry      int        `json:"max_retry"`
+	LastError     string     `json:"last_error,omitempty"`
+	LastFailedAt  *time.Time `json:"last_failed_at,omitempty"`
+	NextProcessAt *time.Time `json:"next_process_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	CompletedAt   *time.Time `json:"completed_at,omitempty"`
+	Result        any        `json:"result,omitempty"`
+}
+
+// Task returns the state of a previously enqueued task
+// GET /worker/tasks/:id
+func (h *WorkerHandler) Task(c echo.Context) error {
+	log := logger.FromEchoContext(c)
+	taskID := c.Param("id")
+
+	job, ok, err := h.loadJob(c, taskID)
+	if !ok {
+		return err
+	}
+
+	response := TaskStatusResponse{
+		TaskID:      taskID,
+		TaskType:    job.TaskType,
+		Queue:       job.Queue,
+		State:       job.Status,
+		JobStatus:   job.Status,
+		Attempts:    job.Attempts,
+		LastError:   job.LastError.String,
+		CreatedAt:   job.CreatedAt.Time,
+		CompletedAt: timePtr(job.CompletedAt.Time),
+	}
+
+	// asynq drops tasks once their retention expires; the jobs row is then all we have
+	info, err := h.scheduler.TaskInfo(c.Request().Context(), job.Queue, taskID)
+	switch {
+	case err == nil:
+		response.State = info.State.String()
+		response.Retried = info.Retried
+		response.MaxRetry = info.MaxRetry
+		response.LastFailedAt = timePtr(info.LastFailedAt)
+		response.NextProcessAt = timePtr(info.NextProcessAt)
+		response.Result = resultValue(info.Result)
+		if info.LastErr != "" {
+			response.LastError = info.LastErr
+		}
+	case errors.Is(err, asynq.ErrTaskNotFound), errors.Is(err, asynq.ErrQueueNotFound):
+	default:
+		log.Error().Err(err).Str("task_id", taskID).Msg("failed to inspect task")
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{
+			"error":   "failed to inspect task",
+			"details": err.Error(),
+		})
+	}
+
+	// A cancelled active task sits in asynq's retry state until the worker revokes it
+	if job.Status == "cancelled" {
+		response.State = job.Status
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// TaskResultResponse represents the outcome of a task for polling clients
+type TaskResultResponse struct {
+	TaskID      string     `json:"task_id"`
+	State       string     `json:"state"`
+	Result      any        `json:"result,omitempty"`
+	Error       string     `json:"error,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// Result returns the stored result of a task.
+// It responds 202 while the task is still in flight, 200 once it is completed, archived
+// or cancelled, and 410 when the task completed but its result retention has expired.
+// GET /worker/tasks/:id/result
+func (h *WorkerHandler) Result(c echo.Context) error {
+	log := logger.FromEchoContext(c)
+	taskID := c.Param("id")
+
+	job, ok, err := h.loadJob(c, taskID)
+	if !ok {
+		return err
+	}
+
+	if job.Status == "cancelled" {
+		return c.JSON(http.StatusOK, TaskResultResponse{
+			TaskID:      taskID,
+			State:       job.Status,
+			Error:       "task was cancelled",
+			CompletedAt: timePtr(job.CompletedAt.Time),
+		})
+	}
+
+	info, err := h.scheduler.TaskInfo(c.Request().Context(), job.Queue, taskID)
+	if err != nil {
+		if errors.Is(err, asynq.ErrTaskNotFound) || errors.Is(err, asynq.ErrQueueNotFound) {
+			return c.JSON(http.StatusGone, TaskResultResponse{
+				TaskID:      taskID,
+				State:       job.Status,
+				Error:       "task result is no longer retained",
+				CompletedAt: timePtr(job.CompletedAt.Time),
+			})
+		}
+		log.Error().Err(err).Str("task_id", taskID).Msg("failed to inspect task")
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{
+			"error":   "failed to inspect task",
+			"details": err.Error(),
+		})
+	}
+
+	response := TaskResultResponse{
+		TaskID: taskID,
+		State:  info.State.String(),
+	}
+
+	switch info.State {
+	case asynq.TaskStateCompleted:
+		response.Result = resultValue(info.Result)
+		response.CompletedAt = timePtr(info.CompletedAt)
+		return c.JSON(http.StatusOK, response)
+	case asynq.TaskStateArchived:
+		response.Error = info.LastErr
+		return c.JSON(http.StatusOK, response)
+	default:
+		return c.JSON(http.StatusAccepted, response)
+	}
+}
+
+// loadJob loads the jobs row of taskID.
+// When ok is false an error response has been written and err must be returned by the caller.
+func (h *WorkerHandler) loadJob(c echo.Context, taskID string) (job db.Job, ok bool, err error) {
+	jobID, err := db.JobID(taskID)
+	if err != nil {
+		return job, false, c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "invalid task id",
+		})
+	}
+
+	job, err = h.queries.GetJob(c.Request().Context(), jobID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return job, false, c.JSON(http.StatusNotFound, map[string]string{
+				"error": "task not found",
+			})
+		}
+		log := logger.FromEchoContext(c)
+		log.Error().Err(err).Str("task_id", taskID).Msg("failed to load job")
+		return job, false, c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "failed to load task",
+		})
+	}
+	return job, true, nil
+}
+
+// timePtr returns nil for the zero time so it is omitted from JSON responses.
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// resultValue returns a task result as raw JSON when possible, or as a string otherwise.
+func resultValue(result []byte) any {
+	if len(result) == 0 {
+		return nil
+	}
+	if json.Valid(result) {
+		return json.RawMessage(result)
+	}
+	return string(result)
+}
+
+// QueueStats reports a queue's task counts and health as seen by asynq.
+type QueueStats struct {
+	Name        string `json:"name"`
+	Paused      bool   `json:"paused"`
+	Size        int    `json:"size"`
+	Pending     int    `json:"pending"`
+	Active      int    `json:"active"`
+	Scheduled   int    `json:"scheduled"`
+	Retry       int    `json:"retry"`
+	Archived    int    `json:"archived"`
+	Completed   int    `json:"completed"`
+	Aggregating int    `json:"aggregating"`
+	// LatencyMs is the age of the oldest pending task in milliseconds.
+	LatencyMs        int64 `json:"latency_ms"`
+	MemoryUsageBytes int64 `json:"memory_usage_bytes"`
+	// ProcessedToday and FailedToday reset daily; the totals are cumulative.
+	ProcessedToday int `json:"processed_today"`
+	FailedToday    int `json:"failed_today"`
+	ProcessedTotal int `json:"processed_total"`
+	FailedTotal    int `json:"failed_total"`
+}
+
+// StatusResponse represents the response from worker status
+type StatusResponse struct {
+	Queues  []QueueStats `json:"queues"`
+	Checked time.Time    `json:"checked"`
+}
+
+// RequireInspector is a middleware answering 501 on the routes that inspect asynq's
+// queues in Redis, when the API runs without it.
+func (h *WorkerHandler) RequireInspector(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if h.inspector == nil {
+			return c.JSON(http.StatusNotImplemented, map[string]string{
+				"error": "queue inspection needs the asynq backend",
+			})
+		}
+		return next(c)
+	}
+}
+
+// Status returns per-queue statistics from asynq
 // GET /worker/status
 func (h *WorkerHandler) Status(c echo.Context) error {
-	// Return queue info from shared package to ensure consistency
-	return c.JSON(http.StatusOK, map[string]any{
-		"scheduler": "connected",
-		"queues":    queue.Names(),
-		"note":      "Use POST /worker/ping to test task processing",
+	log := logger.FromEchoContext(c)
+
+	// Queues that have never received a task do not exist in Redis yet
+	existing, err := h.inspector.Queues()
+	if err != nil {
+		log.Error().Err(err).Msg("failed to list queues")
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{
+			"error":   "failed to inspect queues",
+			"details": err.Error(),
+		})
+	}
+	known := make(map[string]bool, len(existing))
+	for _, name := range existing {
+		known[name] = true
+	}
+
+	// Report queues from the shared package to stay consistent with the worker configuration
+	stats := make([]QueueStats, 0, len(queue.Names()))
+	for _, name := range queue.Names() {
+		if !known[name] {
+			stats = append(stats, QueueStats{Name: name})
+			continue
+		}
+
+		info, err := h.inspector.GetQueueInfo(name)
+		if err != nil {
+			log.Error().Err(err).Str("queue", name).Msg("failed to inspect queue")
+			return c.JSON(http.StatusServiceUnavailable, map[string]string{
+				"error":   "failed to inspect queues",
+				"details": err.Error(),
+			})
+		}
+
+		stats = append(stats, QueueStats{
+			Name:             name,
+			Paused:           info.Paused,
+			Size:             info.Size,
+			Pending:          info.Pending,
+			Active:           info.Active,
+			Scheduled:        info.Scheduled,
+			Retry:            info.Retry,
+			Archived:         info.Archived,
+			Completed:        info.Completed,
+			Aggregating:      info.Aggregating,
+			LatencyMs:        info.Latency.Milliseconds(),
+			MemoryUsageBytes: info.MemoryUsage,
+			ProcessedToday:   info.Processed,
+			FailedToday:      info.Failed,
+			ProcessedTotal:   info.ProcessedTotal,
+			FailedTotal:      info.FailedTotal,
+		})
+	}
+
+	return c.JSON(http.StatusOK, StatusResponse{
+		Queues:  stats,
+		Checked: time.Now().UTC(),
 	})
 }
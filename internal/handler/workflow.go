@@ -0,0 +1,185 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"boiler-go/internal/db"
+	"boiler-go/internal/tasks"
+	"boiler-go/internal/workflow"
+	"boiler-go/pkg/logger"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+)
+
+type WorkflowHandler struct {
+	engine  *workflow.Engine
+	queries *db.Queries
+}
+
+func NewWorkflowHandler(engine *workflow.Engine, queries *db.Queries) *WorkflowHandler {
+	return &WorkflowHandler{
+		engine:  engine,
+		queries: queries,
+	}
+}
+
+// WorkflowStepResponse represents the state of one workflow step
+type WorkflowStepResponse struct {
+	Name      string   `json:"name"`
+	TaskType  string   `json:"task_type"`
+	DependsOn []string `json:"depends_on"`
+	// Status is waiting, enqueued, completed, failed, cancelled or compensating
+	Status string `json:"status"`
+	// TaskID, JobStatus and Attempts are set once the step has been enqueued
+	TaskID             string    `json:"task_id,omitempty"`
+	JobStatus          string    `json:"job_status,omitempty"`
+	Attempts           int32     `json:"attempts,omitempty"`
+	LastError          string    `json:"last_error,omitempty"`
+	CompensationTaskID string    `json:"compensation_task_id,omitempty"`
+	UpdatedAt          time.Time `json:"updated_at"`
+}
+
+// WorkflowResponse represents a workflow and the state of each of its steps
+type WorkflowResponse struct {
+	ID          string                 `json:"id"`
+	Name        string                 `json:"name"`
+	Status      string                 `json:"status"`
+	OnFailure   string                 `json:"on_failure"`
+	LastError   string                 `json:"last_error,omitempty"`
+	CreatedAt   time.Time              `json:"created_at"`
+	UpdatedAt   time.Time              `json:"updated_at"`
+	CompletedAt *time.Time             `json:"completed_at,omitempty"`
+	Steps       []WorkflowStepResponse `json:"steps"`
+}
+
+// Create persists a workflow and enqueues its steps without dependencies
+// POST /workflows
+func (h *WorkflowHandler) Create(c echo.Context) error {
+	log := logger.FromEchoContext(c)
+
+	var def workflow.Definition
+	if err := c.Bind(&def); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "invalid request body",
+		})
+	}
+	if err := def.Validate(); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+	if err := checkAPITaskTypes(def); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	id, err := h.engine.Start(c.Request().Context(), def)
+	if err != nil {
+		log.Error().Err(err).Str("workflow_id", id).Msg("failed to start workflow")
+		response := map[string]string{
+			"error":   "failed to start workflow",
+			"details": err.Error(),
+		}
+		if id != "" {
+			response["workflow_id"] = id
+		}
+		return c.JSON(http.StatusServiceUnavailable, response)
+	}
+
+	log.Info().
+		Str("workflow_id", id).
+		Str("name", def.Name).
+		Int("steps", len(def.Steps)).
+		Msg("workflow started")
+
+	return h.respond(c, id, http.StatusCreated)
+}
+
+// Get returns a workflow with per-step state
+// GET /workflows/:id
+func (h *WorkflowHandler) Get(c echo.Context) error {
+	return h.respond(c, c.Param("id"), http.StatusOK)
+}
+
+// respond loads a workflow and its steps and writes them with status.
+func (h *WorkflowHandler) respond(c echo.Context, workflowID string, status int) error {
+	log := logger.FromEchoContext(c)
+	ctx := c.Request().Context()
+
+	id, err := db.ParseUUID(workflowID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "invalid workflow id",
+		})
+	}
+
+	wf, err := h.queries.GetWorkflow(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "workflow not found",
+			})
+		}
+		log.Error().Err(err).Msg("failed to load workflow")
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "failed to load workflow",
+		})
+	}
+
+	steps, err := h.queries.ListWorkflowStepStates(ctx, id)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to load workflow steps")
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "failed to load workflow steps",
+		})
+	}
+
+	response := WorkflowResponse{
+		ID:          wf.ID.String(),
+		Name:        wf.Name,
+		Status:      wf.Status,
+		OnFailure:   wf.OnFailure,
+		LastError:   wf.LastError.String,
+		CreatedAt:   wf.CreatedAt.Time,
+		UpdatedAt:   wf.UpdatedAt.Time,
+		CompletedAt: timePtr(wf.CompletedAt.Time),
+		Steps:       make([]WorkflowStepResponse, 0, len(steps)),
+	}
+	for _, step := range steps {
+		response.Steps = append(response.Steps, WorkflowStepResponse{
+			Name:               step.Name,
+			TaskType:           step.TaskType,
+			DependsOn:          step.DependsOn,
+			Status:             step.Status,
+			TaskID:             step.JobID.String(),
+			JobStatus:          step.JobStatus.String,
+			Attempts:           step.JobAttempts.Int32,
+			LastError:          step.JobLastError.String,
+			CompensationTaskID: step.CompensationJobID.String(),
+			UpdatedAt:          step.UpdatedAt.Time,
+		})
+	}
+
+	return c.JSON(status, response)
+}
+
+// checkAPITaskTypes rejects workflows whose steps or compensations use task types
+// that cannot be enqueued through the API, see tasks.Spec.API. def must be valid.
+func checkAPITaskTypes(def workflow.Definition) error {
+	for _, step := range def.Steps {
+		if spec, _ := tasks.Lookup(step.TaskType); !spec.API {
+			return fmt.Errorf("step %q: task_type %q cannot be enqueued through the API", step.Name, step.TaskType)
+		}
+		if c := step.Compensate; c != nil {
+			if spec, _ := tasks.Lookup(c.TaskType); !spec.API {
+				return fmt.Errorf("step %q: compensation task_type %q cannot be enqueued through the API", step.Name, c.TaskType)
+			}
+		}
+	}
+	return nil
+}
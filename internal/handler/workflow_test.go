@@ -0,0 +1,136 @@
+//go:build integration
+
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"boiler-go/internal/db"
+	"boiler-go/internal/dbtest"
+	"boiler-go/internal/scheduler"
+	"boiler-go/internal/tasks"
+	"boiler-go/internal/workflow"
+
+	"github.com/hibiken/asynq"
+	"github.com/labstack/echo/v4"
+)
+
+// newTestWorkflowHandler returns a handler whose steps run inline with rec. Without the
+// worker's middlewares, steps never report their outcome, so workflows stay running.
+func newTestWorkflowHandler(t *testing.T, rec *pingRecorder) *echo.Echo {
+	t.Helper()
+	pool := dbtest.Pool(t)
+	mux := asynq.NewServeMux()
+	tasks.WorkerPing.Handle(mux, rec.handle)
+	client := scheduler.NewClientWithBackend(scheduler.NewInlineBackend(mux, scheduler.InlineConfig{}), pool)
+	t.Cleanup(func() { client.Close() })
+
+	h := NewWorkflowHandler(workflow.NewEngine(pool, client), db.New(pool))
+	e := echo.New()
+	e.POST("/workflows", h.Create)
+	e.GET("/workflows/:id", h.Get)
+	return e
+}
+
+func serve(e *echo.Echo, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestWorkflowCreateAndGet(t *testing.T) {
+	pings := &pingRecorder{}
+	e := newTestWorkflowHandler(t, pings)
+
+	rec := serve(e, http.MethodPost, "/workflows", `{
+		"name": "onboarding",
+		"steps": [
+			{"name": "a", "task_type": "worker:ping", "payload": {"message": "a"}},
+			{"name": "b", "task_type": "worker:ping", "depends_on": ["a"]}
+		]
+	}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("POST status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body)
+	}
+	var created WorkflowResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	if len(pings.payloads) != 1 || pings.payloads[0].Message != "a" {
+		t.Errorf("ran %+v, want only the root step", pings.payloads)
+	}
+
+	rec = serve(e, http.MethodGet, "/workflows/"+created.ID, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	var got WorkflowResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != created.ID || got.Name != "onboarding" || got.Status != workflow.StatusRunning {
+		t.Errorf("workflow = %+v, want the running onboarding workflow", got)
+	}
+	if len(got.Steps) != 2 {
+		t.Fatalf("steps = %+v, want 2", got.Steps)
+	}
+	a, b := got.Steps[0], got.Steps[1]
+	if a.Name != "a" || a.Status != "enqueued" || a.TaskID == "" || a.JobStatus == "" {
+		t.Errorf("step a = %+v, want enqueued with its job", a)
+	}
+	if b.Name != "b" || b.Status != "waiting" || b.TaskID != "" {
+		t.Errorf("step b = %+v, want waiting without a task", b)
+	}
+}
+
+func TestWorkflowErrors(t *testing.T) {
+	e := newTestWorkflowHandler(t, &pingRecorder{})
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{
+			name:   "invalid definition",
+			method: http.MethodPost,
+			path:   "/workflows",
+			body:   `{"name":"w","steps":[{"name":"a","task_type":"worker:ping","depends_on":["a"]}]}`,
+			want:   http.StatusBadRequest,
+		},
+		{
+			name:   "internal task type",
+			method: http.MethodPost,
+			path:   "/workflows",
+			body:   `{"name":"w","steps":[{"name":"a","task_type":"webhook:deliver"}]}`,
+			want:   http.StatusBadRequest,
+		},
+		{
+			name:   "invalid id",
+			method: http.MethodGet,
+			path:   "/workflows/not-a-uuid",
+			want:   http.StatusBadRequest,
+		},
+		{
+			name:   "unknown workflow",
+			method: http.MethodGet,
+			path:   "/workflows/00000000-0000-0000-0000-000000000000",
+			want:   http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := serve(e, tt.method, tt.path, tt.body); rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
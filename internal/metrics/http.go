@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// HTTPMetrics records API request counts and latencies by method, route and status.
+type HTTPMetrics struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+// NewHTTPMetrics creates the HTTP metrics and registers them with reg.
+func NewHTTPMetrics(reg prometheus.Registerer) *HTTPMetrics {
+	labels := []string{"method", "route", "status"}
+	m := &HTTPMetrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "HTTP requests handled, by method, route and status code.",
+		}, labels),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "HTTP request latency, by method, route and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, labels),
+	}
+	reg.MustRegister(m.requests, m.duration)
+	return m
+}
+
+// Observe records one handled request. route must be the route template
+// (e.g. /worker/tasks/:id), not the raw path, to keep label cardinality bounded.
+func (m *HTTPMetrics) Observe(method, route string, status int, duration time.Duration) {
+	code := strconv.Itoa(status)
+	m.requests.WithLabelValues(method, route, code).Inc()
+	m.duration.WithLabelValues(method, route, code).Observe(duration.Seconds())
+}
@@ -0,0 +1,120 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// taskBuckets spans 10ms to roughly 5 minutes.
+var taskBuckets = prometheus.ExponentialBuckets(0.01, 2, 16)
+
+// groupSizeBuckets spans 1 to 1024 tasks.
+var groupSizeBuckets = prometheus.ExponentialBuckets(1, 2, 11)
+
+// TaskMetrics records worker task outcomes, durations and queue wait times
+// by task type and queue.
+type TaskMetrics struct {
+	processed *prometheus.CounterVec
+	failed    *prometheus.CounterVec
+	retried   *prometheus.CounterVec
+	limited   *prometheus.CounterVec
+	duration  *prometheus.HistogramVec
+	queueWait *prometheus.HistogramVec
+	// aggregated and groupSize are labelled by the type of the grouped tasks only,
+	// since aggregators are not told the queue of a group
+	aggregated *prometheus.CounterVec
+	groupSize  *prometheus.HistogramVec
+}
+
+// NewTaskMetrics creates the task metrics and registers them with reg.
+func NewTaskMetrics(reg prometheus.Registerer) *TaskMetrics {
+	labels := []string{"task_type", "queue"}
+	m := &TaskMetrics{
+		processed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "task",
+			Name:      "processed_total",
+			Help:      "Task attempts processed, successful or not.",
+		}, labels),
+		failed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "task",
+			Name:      "failed_total",
+			Help:      "Task attempts whose handler returned an error.",
+		}, labels),
+		retried: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "task",
+			Name:      "retried_total",
+			Help:      "Task attempts that were retries of an earlier failed attempt.",
+		}, labels),
+		limited: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "task",
+			Name:      "rate_limited_total",
+			Help:      "Task attempts pushed back because their task type was over its rate limit.",
+		}, labels),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "task",
+			Name:      "duration_seconds",
+			Help:      "Time spent in the task handler per attempt.",
+			Buckets:   taskBuckets,
+		}, labels),
+		queueWait: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "task",
+			Name:      "queue_wait_seconds",
+			Help:      "Time from a task becoming ready to its first attempt starting.",
+			Buckets:   taskBuckets,
+		}, labels),
+		aggregated: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "task",
+			Name:      "groups_aggregated_total",
+			Help:      "Task groups aggregated into a single task.",
+		}, []string{"task_type"}),
+		groupSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "task",
+			Name:      "group_size",
+			Help:      "Number of tasks in each aggregated group.",
+			Buckets:   groupSizeBuckets,
+		}, []string{"task_type"}),
+	}
+	reg.MustRegister(m.processed, m.failed, m.retried, m.limited, m.duration, m.queueWait, m.aggregated, m.groupSize)
+	return m
+}
+
+// Started records the start of an attempt. retried is the number of earlier attempts.
+func (m *TaskMetrics) Started(taskType, queue string, retried int) {
+	if retried > 0 {
+		m.retried.WithLabelValues(taskType, queue).Inc()
+	}
+}
+
+// RateLimited records an attempt that was pushed back by the rate limiter.
+func (m *TaskMetrics) RateLimited(taskType, queue string) {
+	m.limited.WithLabelValues(taskType, queue).Inc()
+}
+
+// Aggregated records a group of size tasks of taskType aggregated into one task.
+func (m *TaskMetrics) Aggregated(taskType string, size int) {
+	m.aggregated.WithLabelValues(taskType).Inc()
+	m.groupSize.WithLabelValues(taskType).Observe(float64(size))
+}
+
+// ObserveQueueWait records how long a task waited in its queue before its first attempt.
+func (m *TaskMetrics) ObserveQueueWait(taskType, queue string, wait time.Duration) {
+	m.queueWait.WithLabelValues(taskType, queue).Observe(wait.Seconds())
+}
+
+// Finished records the outcome and duration of an attempt.
+func (m *TaskMetrics) Finished(taskType, queue string, duration time.Duration, err error) {
+	m.processed.WithLabelValues(taskType, queue).Inc()
+	if err != nil {
+		m.failed.WithLabelValues(taskType, queue).Inc()
+	}
+	m.duration.WithLabelValues(taskType, queue).Observe(duration.Seconds())
+}
@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestTaskMetrics(t *testing.T) {
+	m := NewTaskMetrics(prometheus.NewRegistry())
+
+	// A first attempt that succeeds, then a retry that fails
+	m.Started("email:send", "default", 0)
+	m.ObserveQueueWait("email:send", "default", 2*time.Second)
+	m.Finished("email:send", "default", time.Second, nil)
+	m.Started("email:send", "default", 1)
+	m.Finished("email:send", "default", time.Second, errors.New("failed"))
+	m.RateLimited("email:send", "default")
+	m.Aggregated("user:updated", 5)
+
+	tests := []struct {
+		name      string
+		collector prometheus.Collector
+		want      float64
+	}{
+		{name: "processed", collector: m.processed.WithLabelValues("email:send", "default"), want: 2},
+		{name: "failed", collector: m.failed.WithLabelValues("email:send", "default"), want: 1},
+		{name: "retried", collector: m.retried.WithLabelValues("email:send", "default"), want: 1},
+		{name: "rate limited", collector: m.limited.WithLabelValues("email:send", "default"), want: 1},
+		{name: "aggregated", collector: m.aggregated.WithLabelValues("user:updated"), want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := testutil.ToFloat64(tt.collector); got != tt.want {
+				t.Errorf("%s = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+
+	// Histograms get one series per label set
+	for name, c := range map[string]prometheus.Collector{"duration": m.duration, "queue wait": m.queueWait, "group size": m.groupSize} {
+		if n := testutil.CollectAndCount(c); n != 1 {
+			t.Errorf("%s series = %d, want 1", name, n)
+		}
+	}
+}
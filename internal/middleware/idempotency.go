@@ -0,0 +1,160 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"boiler-go/internal/scheduler"
+	"boiler-go/pkg/logger"
+
+	"github.com/labstack/echo/v4"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// IdempotencyKeyHeader is the request header carrying the client's idempotency key.
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set on responses replayed from the cache.
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	idempotencyKeyPrefix = "idempotency:"
+	maxIdempotencyKeyLen = 255
+	// idempotencyLockTTL bounds how long a crashed request can block retries with the same key.
+	idempotencyLockTTL = time.Minute
+)
+
+// idempotentResponse is the cached first response for an idempotency key.
+// A zero Status marks a request that is still in flight.
+type idempotentResponse struct {
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
+
+// bodyRecorder tees the response body so it can be cached after the handler returns.
+type bodyRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *bodyRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// Idempotency returns an Echo middleware for enqueue endpoints.
+// Requests carrying an Idempotency-Key header are processed once: the first response
+// is cached in Redis for ttl and replayed, with the same status code, to every retry.
+// The key is also passed to scheduler.Client so the enqueued task ID is derived from it.
+// Requests without the header are passed through untouched.
+// With a nil rdb, responses are not cached: a retry runs the handler again, and only
+// the task ID derived from the key keeps it from enqueueing a second task.
+func Idempotency(rdb *redis.Client, ttl time.Duration) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			key := c.Request().Header.Get(IdempotencyKeyHeader)
+			if key == "" {
+				return next(c)
+			}
+			if len(key) > maxIdempotencyKeyLen {
+				return c.JSON(http.StatusBadRequest, map[string]string{
+					"error": "Idempotency-Key must be at most 255 characters",
+				})
+			}
+
+			req := c.Request()
+			ctx := req.Context()
+			if rdb == nil {
+				c.SetRequest(req.WithContext(scheduler.WithIdempotencyKey(ctx, key)))
+				return next(c)
+			}
+
+			log := logger.FromEchoContext(c)
+			cacheKey := idempotencyKeyPrefix + req.Method + ":" + req.URL.Path + ":" + key
+
+			// Claim the key; only the first request gets to run the handler
+			marker, _ := json.Marshal(idempotentResponse{})
+			claimed, err := rdb.SetNX(ctx, cacheKey, marker, idempotencyLockTTL).Result()
+			if err != nil {
+				log.Error().Err(err).Msg("failed to claim idempotency key")
+				return c.JSON(http.StatusServiceUnavailable, map[string]string{
+					"error": "idempotency store unavailable",
+				})
+			}
+
+			if !claimed {
+				return replayIdempotent(c, rdb, cacheKey)
+			}
+
+			rec := &bodyRecorder{ResponseWriter: c.Response().Writer}
+			c.Response().Writer = rec
+			c.SetRequest(req.WithContext(scheduler.WithIdempotencyKey(ctx, key)))
+
+			handlerErr := next(c)
+			if handlerErr != nil {
+				// Let Echo write the error response before we look at the status
+				c.Error(handlerErr)
+			}
+
+			res := c.Response()
+			storeCtx := context.WithoutCancel(ctx)
+			if res.Status >= http.StatusInternalServerError {
+				// Server errors are not final; release the key so the client can retry
+				if err := rdb.Del(storeCtx, cacheKey).Err(); err != nil {
+					log.Error().Err(err).Msg("failed to release idempotency key")
+				}
+				return nil
+			}
+
+			data, _ := json.Marshal(idempotentResponse{
+				Status:      res.Status,
+				ContentType: res.Header().Get(echo.HeaderContentType),
+				Body:        rec.body.Bytes(),
+			})
+			if err := rdb.Set(storeCtx, cacheKey, data, ttl).Err(); err != nil {
+				log.Error().Err(err).Msg("failed to cache idempotent response")
+			}
+			return nil
+		}
+	}
+}
+
+// replayIdempotent writes the cached response for cacheKey,
+// or 409 if the first request with the key has not finished yet.
+func replayIdempotent(c echo.Context, rdb *redis.Client, cacheKey string) error {
+	log := logger.FromEchoContext(c)
+
+	data, err := rdb.Get(c.Request().Context(), cacheKey).Bytes()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		log.Error().Err(err).Msg("failed to load idempotent response")
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{
+			"error": "idempotency store unavailable",
+		})
+	}
+
+	var cached idempotentResponse
+	if err == nil {
+		if err := json.Unmarshal(data, &cached); err != nil {
+			log.Error().Err(err).Msg("failed to decode idempotent response")
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "corrupt idempotency record",
+			})
+		}
+	}
+
+	// The key expired between SETNX and GET, or the first request is still running
+	if cached.Status == 0 {
+		return c.JSON(http.StatusConflict, map[string]string{
+			"error": "a request with this Idempotency-Key is still being processed",
+		})
+	}
+
+	log.Info().Int("status", cached.Status).Msg("replaying idempotent response")
+
+	c.Response().Header().Set(IdempotentReplayedHeader, "true")
+	return c.Blob(cached.Status, cached.ContentType, cached.Body)
+}
@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"boiler-go/internal/scheduler"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/labstack/echo/v4"
+	"github.com/redis/go-redis/v9"
+)
+
+// newIdempotentServer serves POST /enqueue, which answers with status and the number of
+// times it ran, and records the idempotency key it got through the request context.
+func newIdempotentServer(rdb *redis.Client, status int) (*echo.Echo, *int, *string) {
+	runs := 0
+	var gotKey string
+	e := echo.New()
+	e.Use(Idempotency(rdb, time.Hour))
+	e.POST("/enqueue", func(c echo.Context) error {
+		runs++
+		gotKey, _ = scheduler.IdempotencyKeyFrom(c.Request().Context())
+		return c.JSON(status, map[string]int{"run": runs})
+	})
+	return e, &runs, &gotKey
+}
+
+func post(e *echo.Echo, path, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, nil)
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func newTestRedis(t *testing.T) (*redis.Client, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	// One dial attempt, so tests that stop the server fail fast
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr(), DialerRetries: 1, MaxRetries: -1})
+	t.Cleanup(func() { rdb.Close() })
+	return rdb, mr
+}
+
+func TestIdempotencyReplay(t *testing.T) {
+	rdb, _ := newTestRedis(t)
+	e, runs, gotKey := newIdempotentServer(rdb, http.StatusAccepted)
+
+	first := post(e, "/enqueue", "key-1")
+	if first.Code != http.StatusAccepted || *gotKey != "key-1" {
+		t.Fatalf("first response = %d with key %q, want %d with key-1", first.Code, *gotKey, http.StatusAccepted)
+	}
+	if first.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Error("first response marked as replayed")
+	}
+
+	retry := post(e, "/enqueue", "key-1")
+	if retry.Code != first.Code || retry.Body.String() != first.Body.String() {
+		t.Errorf("retry = %d %s, want the first response %d %s", retry.Code, retry.Body, first.Code, first.Body)
+	}
+	if retry.Header().Get(IdempotentReplayedHeader) != "true" || *runs != 1 {
+		t.Errorf("retry ran the handler %d times, replayed %q; want a replay", *runs, retry.Header().Get(IdempotentReplayedHeader))
+	}
+
+	// Other keys, and requests without a key, run the handler
+	if rec := post(e, "/enqueue", "key-2"); rec.Code != http.StatusAccepted || *runs != 2 {
+		t.Errorf("other key = %d after %d runs, want a new run", rec.Code, *runs)
+	}
+	if rec := post(e, "/enqueue", ""); rec.Code != http.StatusAccepted || *runs != 3 || *gotKey != "" {
+		t.Errorf("no key = %d after %d runs with key %q, want a new run without a key", rec.Code, *runs, *gotKey)
+	}
+}
+
+func TestIdempotencyServerErrorReleasesKey(t *testing.T) {
+	rdb, _ := newTestRedis(t)
+	e, runs, _ := newIdempotentServer(rdb, http.StatusInternalServerError)
+
+	post(e, "/enqueue", "key-1")
+	post(e, "/enqueue", "key-1")
+	if *runs != 2 {
+		t.Errorf("runs = %d, want a retry after a server error to run again", *runs)
+	}
+}
+
+func TestIdempotencyInFlight(t *testing.T) {
+	rdb, mr := newTestRedis(t)
+	e, runs, _ := newIdempotentServer(rdb, http.StatusAccepted)
+
+	// The marker of a first request that has not finished yet
+	if err := mr.Set(idempotencyKeyPrefix+"POST:/enqueue:key-1", `{"status":0}`); err != nil {
+		t.Fatal(err)
+	}
+	if rec := post(e, "/enqueue", "key-1"); rec.Code != http.StatusConflict || *runs != 0 {
+		t.Errorf("response = %d after %d runs, want %d without running", rec.Code, *runs, http.StatusConflict)
+	}
+}
+
+func TestIdempotencyInvalidKey(t *testing.T) {
+	rdb, _ := newTestRedis(t)
+	e, runs, _ := newIdempotentServer(rdb, http.StatusAccepted)
+
+	if rec := post(e, "/enqueue", strings.Repeat("k", maxIdempotencyKeyLen+1)); rec.Code != http.StatusBadRequest || *runs != 0 {
+		t.Errorf("response = %d after %d runs, want %d", rec.Code, *runs, http.StatusBadRequest)
+	}
+}
+
+func TestIdempotencyRedisDown(t *testing.T) {
+	rdb, mr := newTestRedis(t)
+	e, runs, _ := newIdempotentServer(rdb, http.StatusAccepted)
+	mr.Close()
+
+	if rec := post(e, "/enqueue", "key-1"); rec.Code != http.StatusServiceUnavailable || *runs != 0 {
+		t.Errorf("response = %d after %d runs, want %d", rec.Code, *runs, http.StatusServiceUnavailable)
+	}
+}
+
+func TestIdempotencyWithoutRedis(t *testing.T) {
+	e, runs, gotKey := newIdempotentServer(nil, http.StatusAccepted)
+
+	// Without a cache every request runs, and only the task ID derived from the key dedupes
+	for i := range 2 {
+		rec := post(e, "/enqueue", "key-1")
+		if rec.Code != http.StatusAccepted || *gotKey != "key-1" {
+			t.Fatalf("request %d = %d with key %q, want %d with key-1", i+1, rec.Code, *gotKey, http.StatusAccepted)
+		}
+	}
+	if *runs != 2 {
+		t.Errorf("runs = %d, want 2", *runs)
+	}
+}
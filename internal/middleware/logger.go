@@ -3,6 +3,8 @@ package middleware
 import (
 	"time"
 
+	"boiler-go/internal/tracing"
+
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 	"github.com/rs/zerolog"
@@ -10,6 +12,8 @@ import (
 
 // RequestLogger returns an Echo middleware that logs requests and injects
 // a request-scoped logger with request_id into the context.
+// The request ID and a span continuing the caller's traceparent (or starting a new trace)
+// are also stored in the request context, so scheduler.Client copies them into enqueued tasks.
 func RequestLogger(base zerolog.Logger) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
@@ -27,9 +31,21 @@ func RequestLogger(base zerolog.Logger) echo.MiddlewareFunc {
 			// Also set it on the request header so it's available to wrapped handlers
 			c.Request().Header.Set("X-Request-ID", reqID)
 
+			// Continue the caller's trace if it sent a valid traceparent
+			span := tracing.NewRoot()
+			if parent, err := tracing.ParseTraceparent(c.Request().Header.Get(tracing.HeaderTraceparent)); err == nil {
+				span = parent.Child()
+			}
+
+			// Make correlation data available to anything holding the request context
+			ctx := tracing.WithSpan(tracing.WithRequestID(c.Request().Context(), reqID), span)
+			c.SetRequest(c.Request().WithContext(ctx))
+
 			// Create request-scoped logger
 			reqLogger := base.With().
 				Str("request_id", reqID).
+				Str("trace_id", span.TraceID).
+				Str("span_id", span.SpanID).
 				Str("method", c.Request().Method).
 				Str("path", c.Request().URL.Path).
 				Logger()
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"boiler-go/internal/tracing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
+)
+
+func TestRequestLoggerCorrelation(t *testing.T) {
+	const parent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	tests := []struct {
+		name          string
+		requestID     string
+		traceparent   string
+		wantRequestID string
+		wantTraceID   string
+		wantParentID  string
+	}{
+		{name: "new request", traceparent: ""},
+		{name: "caller's request ID", requestID: "req-1", wantRequestID: "req-1"},
+		{name: "caller's trace", traceparent: parent, wantTraceID: "4bf92f3577b34da6a3ce929d0e0e4736", wantParentID: "00f067aa0ba902b7"},
+		{name: "invalid traceparent", traceparent: "00-zz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotRequestID string
+			var gotSpan tracing.Span
+			e := echo.New()
+			e.Use(RequestLogger(zerolog.Nop()))
+			e.GET("/", func(c echo.Context) error {
+				gotRequestID, _ = tracing.RequestIDFrom(c.Request().Context())
+				gotSpan, _ = tracing.SpanFrom(c.Request().Context())
+				return c.NoContent(http.StatusNoContent)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.requestID != "" {
+				req.Header.Set("X-Request-ID", tt.requestID)
+			}
+			if tt.traceparent != "" {
+				req.Header.Set(tracing.HeaderTraceparent, tt.traceparent)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if gotRequestID == "" || rec.Header().Get("X-Request-ID") != gotRequestID {
+				t.Errorf("request ID = %q, response header %q; want them set and equal", gotRequestID, rec.Header().Get("X-Request-ID"))
+			}
+			if tt.wantRequestID != "" && gotRequestID != tt.wantRequestID {
+				t.Errorf("request ID = %q, want %q", gotRequestID, tt.wantRequestID)
+			}
+			if len(gotSpan.TraceID) != 32 || len(gotSpan.SpanID) != 16 {
+				t.Fatalf("span = %+v, want a valid span", gotSpan)
+			}
+			if gotSpan.ParentID != tt.wantParentID {
+				t.Errorf("parent span = %q, want %q", gotSpan.ParentID, tt.wantParentID)
+			}
+			if tt.wantTraceID != "" && gotSpan.TraceID != tt.wantTraceID {
+				t.Errorf("trace ID = %q, want %q", gotSpan.TraceID, tt.wantTraceID)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"boiler-go/internal/metrics"
+
+	"github.com/labstack/echo/v4"
+)
+
+// unmatchedRoute labels requests that matched no route, so arbitrary paths
+// cannot blow up metric cardinality.
+const unmatchedRoute = "unmatched"
+
+// Metrics returns an Echo middleware that records request count and latency
+// by method, route template and status code.
+func Metrics(m *metrics.HTTPMetrics) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+
+			err := next(c)
+
+			// Echo writes the error response after the middleware chain returns,
+			// so derive the status it is going to send
+			status := c.Response().Status
+			if err != nil {
+				var he *echo.HTTPError
+				if errors.As(err, &he) {
+					status = he.Code
+				} else if !c.Response().Committed {
+					status = http.StatusInternalServerError
+				}
+			}
+
+			route := c.Path()
+			if route == "" {
+				route = unmatchedRoute
+			}
+
+			m.Observe(c.Request().Method, route, status, time.Since(start))
+			return err
+		}
+	}
+}
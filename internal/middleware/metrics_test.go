@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"boiler-go/internal/metrics"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	e := echo.New()
+	e.Use(Metrics(metrics.NewHTTPMetrics(reg)))
+	e.GET("/tasks/:id", func(c echo.Context) error {
+		switch c.Param("id") {
+		case "missing":
+			return echo.NewHTTPError(http.StatusNotFound, "task not found")
+		case "broken":
+			return errors.New("database down")
+		}
+		return c.NoContent(http.StatusOK)
+	})
+
+	for _, path := range []string{"/tasks/a", "/tasks/b", "/tasks/missing", "/tasks/broken", "/unknown/path"} {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	// Requests are labelled by route template, and errors by the status Echo sends for them
+	want := `
+# HELP boiler_http_requests_total HTTP requests handled, by method, route and status code.
+# TYPE boiler_http_requests_total counter
+boiler_http_requests_total{method="GET",route="/tasks/:id",status="200"} 2
+boiler_http_requests_total{method="GET",route="/tasks/:id",status="404"} 1
+boiler_http_requests_total{method="GET",route="/tasks/:id",status="500"} 1
+boiler_http_requests_total{method="GET",route="unmatched",status="404"} 1
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want), "boiler_http_requests_total"); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,144 @@
+// Package outbox enqueues tasks atomically with database writes. Enqueue writes the task
+// to the outbox table inside the caller's transaction, so it exists exactly when the
+// business data commits, and the Relay hands committed tasks to asynq.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"boiler-go/internal/db"
+	"boiler-go/internal/scheduler"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+)
+
+// Message statuses.
+const (
+	StatusPending    = "pending"
+	StatusDispatched = "dispatched"
+)
+
+// options is the JSON form of the asynq options of a message. Relative options are
+// resolved when the message is written, e.g. ProcessIn becomes ProcessAt.
+type options struct {
+	Queue     string        `json:"queue,omitempty"`
+	MaxRetry  *int          `json:"max_retry,omitempty"`
+	Timeout   time.Duration `json:"timeout,omitempty"`
+	Deadline  *time.Time    `json:"deadline,omitempty"`
+	Unique    time.Duration `json:"unique,omitempty"`
+	ProcessAt *time.Time    `json:"process_at,omitempty"`
+	Retention time.Duration `json:"retention,omitempty"`
+	Group     string        `json:"group,omitempty"`
+}
+
+// Enqueue writes a task to the outbox through q and returns its task ID. Bind q to the
+// transaction of the business data with Queries.WithTx: the task is handed to asynq by
+// the relay once the transaction commits, and discarded with it on rollback.
+// Task headers are taken from ctx as scheduler.Client does.
+func Enqueue(ctx context.Context, q *db.Queries, taskType string, payload []byte, opts ...asynq.Option) (string, error) {
+	taskID, stored, err := encodeOptions(opts, time.Now())
+	if err != nil {
+		return "", err
+	}
+	if taskID == "" {
+		taskID = uuid.NewString()
+	}
+	id, err := db.JobID(taskID)
+	if err != nil {
+		return "", fmt.Errorf("task ID %q is not a valid job ID: %w", taskID, err)
+	}
+
+	headers, err := json.Marshal(scheduler.TaskHeadersFrom(ctx))
+	if err != nil {
+		return "", fmt.Errorf("failed to encode task headers: %w", err)
+	}
+	optionsJSON, err := json.Marshal(stored)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode task options: %w", err)
+	}
+
+	err = q.CreateOutboxMessage(ctx, db.CreateOutboxMessageParams{
+		TaskID:   id,
+		TaskType: taskType,
+		Payload:  payload,
+		Headers:  headers,
+		Options:  optionsJSON,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to write outbox message: %w", err)
+	}
+	return taskID, nil
+}
+
+// encodeOptions converts opts to their stored form and extracts the task ID.
+// Later options win, matching asynq's own option handling.
+func encodeOptions(opts []asynq.Option, now time.Time) (string, options, error) {
+	var taskID string
+	var o options
+	for _, opt := range opts {
+		switch opt.Type() {
+		case asynq.TaskIDOpt:
+			taskID, _ = opt.Value().(string)
+		case asynq.QueueOpt:
+			o.Queue, _ = opt.Value().(string)
+		case asynq.MaxRetryOpt:
+			n, _ := opt.Value().(int)
+			o.MaxRetry = &n
+		case asynq.TimeoutOpt:
+			o.Timeout, _ = opt.Value().(time.Duration)
+		case asynq.DeadlineOpt:
+			t, _ := opt.Value().(time.Time)
+			o.Deadline = &t
+		case asynq.UniqueOpt:
+			o.Unique, _ = opt.Value().(time.Duration)
+		case asynq.ProcessAtOpt:
+			t, _ := opt.Value().(time.Time)
+			o.ProcessAt = &t
+		case asynq.ProcessInOpt:
+			d, _ := opt.Value().(time.Duration)
+			t := now.Add(d)
+			o.ProcessAt = &t
+		case asynq.RetentionOpt:
+			o.Retention, _ = opt.Value().(time.Duration)
+		case asynq.GroupOpt:
+			o.Group, _ = opt.Value().(string)
+		default:
+			return "", options{}, fmt.Errorf("outbox does not support asynq option %s", opt.String())
+		}
+	}
+	return taskID, o, nil
+}
+
+// asynqOptions converts stored options back to asynq options.
+func (o options) asynqOptions() []asynq.Option {
+	var opts []asynq.Option
+	if o.Queue != "" {
+		opts = append(opts, asynq.Queue(o.Queue))
+	}
+	if o.MaxRetry != nil {
+		opts = append(opts, asynq.MaxRetry(*o.MaxRetry))
+	}
+	if o.Timeout > 0 {
+		opts = append(opts, asynq.Timeout(o.Timeout))
+	}
+	if o.Deadline != nil {
+		opts = append(opts, asynq.Deadline(*o.Deadline))
+	}
+	if o.Unique > 0 {
+		opts = append(opts, asynq.Unique(o.Unique))
+	}
+	if o.ProcessAt != nil {
+		opts = append(opts, asynq.ProcessAt(*o.ProcessAt))
+	}
+	if o.Retention > 0 {
+		opts = append(opts, asynq.Retention(o.Retention))
+	}
+	if o.Group != "" {
+		opts = append(opts, asynq.Group(o.Group))
+	}
+	return opts
+}
@@ -0,0 +1,237 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"boiler-go/internal/db"
+	"boiler-go/internal/scheduler"
+	"boiler-go/pkg/logger"
+
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// channel is notified by the outbox_notify trigger when messages are inserted.
+	channel = "outbox"
+	// maxRetryDelay caps the backoff between attempts to hand a message to asynq.
+	maxRetryDelay = time.Minute
+	// pruneInterval is how often dispatched messages older than the retention are deleted.
+	pruneInterval = time.Hour
+)
+
+// Relay hands committed outbox messages to asynq. It wakes up on notifications from the
+// outbox_notify trigger and polls every interval in case one is missed.
+// Several relays can run at once: each message is locked by the relay dispatching it.
+// Each message is enqueued exactly once, see dispatch.
+type Relay struct {
+	pool      *pgxpool.Pool
+	queries   *db.Queries
+	client    *scheduler.Client
+	interval  time.Duration
+	batchSize int
+	retention time.Duration
+}
+
+// NewRelay returns a relay enqueueing messages with client, batchSize at a time.
+// Dispatched messages are deleted after retention.
+func NewRelay(pool *pgxpool.Pool, client *scheduler.Client, interval time.Duration, batchSize int, retention time.Duration) *Relay {
+	return &Relay{
+		pool:      pool,
+		queries:   db.New(pool),
+		client:    client,
+		interval:  interval,
+		batchSize: batchSize,
+		retention: retention,
+	}
+}
+
+// Run dispatches messages until ctx is done.
+func (r *Relay) Run(ctx context.Context) {
+	log := logger.FromContext(ctx)
+
+	var listener *pgxpool.Conn
+	defer func() {
+		if listener != nil {
+			listener.Release()
+		}
+	}()
+
+	var lastPrune time.Time
+	for {
+		for {
+			n, err := r.dispatch(ctx)
+			if err != nil && ctx.Err() == nil {
+				log.Error().Err(err).Msg("failed to dispatch outbox messages")
+			}
+			if err != nil || n < r.batchSize {
+				break
+			}
+		}
+
+		if time.Since(lastPrune) >= pruneInterval {
+			lastPrune = time.Now()
+			if err := r.prune(ctx); err != nil && ctx.Err() == nil {
+				log.Error().Err(err).Msg("failed to prune dispatched outbox messages")
+			}
+		}
+
+		if listener == nil {
+			conn, err := r.listen(ctx)
+			if err != nil && ctx.Err() == nil {
+				log.Warn().Err(err).Msg("failed to listen for outbox messages, polling only")
+			}
+			listener = conn
+		}
+
+		if listener != nil {
+			if err := r.wait(ctx, listener); err != nil && ctx.Err() == nil {
+				log.Warn().Err(err).Msg("outbox listener connection lost")
+				// Close so the pool discards the connection instead of reusing it
+				listener.Conn().Close(context.WithoutCancel(ctx))
+				listener.Release()
+				listener = nil
+			}
+		} else {
+			select {
+			case <-ctx.Done():
+			case <-time.After(r.interval):
+			}
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// listen acquires a dedicated connection subscribed to the outbox channel.
+func (r *Relay) listen(ctx context.Context) (*pgxpool.Conn, error) {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.Exec(ctx, "LISTEN "+channel); err != nil {
+		conn.Release()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// wait blocks until a notification arrives, the poll interval elapses or ctx is done.
+func (r *Relay) wait(ctx context.Context, listener *pgxpool.Conn) error {
+	waitCtx, cancel := context.WithTimeout(ctx, r.interval)
+	defer cancel()
+
+	_, err := listener.Conn().WaitForNotification(waitCtx)
+	// A timeout leaves the connection usable
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
+
+// dispatch claims a batch of due messages and enqueues them, then marks them dispatched
+// in the claiming transaction. If that transaction is lost after an enqueue, the message
+// is claimed again but not enqueued twice: its task ID comes from the message, so the
+// second enqueue conflicts while the first task is queued, and delivered skips it once
+// the task has left the queue. It returns the number of messages claimed.
+func (r *Relay) dispatch(ctx context.Context) (int, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(context.WithoutCancel(ctx))
+	q := r.queries.WithTx(tx)
+
+	messages, err := q.ClaimOutboxMessages(ctx, int32(r.batchSize))
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim outbox messages: %w", err)
+	}
+	if len(messages) == 0 {
+		return 0, nil
+	}
+
+	log := logger.FromContext(ctx)
+	for _, m := range messages {
+		done, err := delivered(ctx, q, m)
+		if err != nil {
+			return 0, err
+		}
+		var enqueueErr error
+		if !done {
+			enqueueErr = r.enqueue(ctx, m)
+		}
+		if enqueueErr == nil || errors.Is(enqueueErr, asynq.ErrTaskIDConflict) {
+			if err := q.MarkOutboxDispatched(ctx, m.ID); err != nil {
+				return 0, fmt.Errorf("failed to mark outbox message dispatched: %w", err)
+			}
+			continue
+		}
+
+		delay := min(time.Second<<min(m.Attempts, 6), maxRetryDelay)
+		log.Warn().
+			Err(enqueueErr).
+			Str("task_id", m.TaskID.String()).
+			Str("task_type", m.TaskType).
+			Int32("attempts", m.Attempts+1).
+			Dur("retry_in", delay).
+			Msg("failed to hand outbox message to asynq")
+		if err := q.RetryOutboxMessage(ctx, db.RetryOutboxMessageParams{
+			ID:          m.ID,
+			LastError:   pgtype.Text{String: enqueueErr.Error(), Valid: true},
+			AvailableAt: pgtype.Timestamptz{Time: time.Now().Add(delay), Valid: true},
+		}); err != nil {
+			return 0, fmt.Errorf("failed to reschedule outbox message: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit outbox dispatch: %w", err)
+	}
+	return len(messages), nil
+}
+
+// delivered reports whether the task of m was enqueued by an earlier dispatch and has
+// since been picked up: its job row, recorded by scheduler.Client, has moved past pending.
+// A task completed without retention is gone from its queue, so its job row is the only
+// trace of the earlier enqueue.
+func delivered(ctx context.Context, q *db.Queries, m db.Outbox) (bool, error) {
+	job, err := q.GetJob(ctx, m.TaskID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to load job %s: %w", m.TaskID.String(), err)
+	}
+	return job.Status != "pending", nil
+}
+
+// enqueue hands one message to asynq with its stored headers and options.
+func (r *Relay) enqueue(ctx context.Context, m db.Outbox) error {
+	var headers map[string]string
+	if err := json.Unmarshal(m.Headers, &headers); err != nil {
+		return fmt.Errorf("failed to decode task headers: %w", err)
+	}
+	var stored options
+	if err := json.Unmarshal(m.Options, &stored); err != nil {
+		return fmt.Errorf("failed to decode task options: %w", err)
+	}
+
+	opts := append(stored.asynqOptions(), asynq.TaskID(m.TaskID.String()))
+	_, err := r.client.EnqueueWithID(scheduler.WithTaskHeaders(ctx, headers), m.TaskType, m.Payload, opts...)
+	return err
+}
+
+// prune deletes dispatched messages older than the retention.
+func (r *Relay) prune(ctx context.Context) error {
+	cutoff := pgtype.Timestamptz{Time: time.Now().Add(-r.retention), Valid: true}
+	_, err := r.queries.DeleteDispatchedOutboxMessages(ctx, cutoff)
+	return err
+}
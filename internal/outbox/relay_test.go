@@ -0,0 +1,134 @@
+//go:build integration
+
+package outbox
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"boiler-go/internal/db"
+	"boiler-go/internal/dbtest"
+	"boiler-go/internal/scheduler"
+
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// newTestRelay returns a relay whose tasks run inline and move their job row past
+// pending, like the worker's jobs middleware does. runs counts the tasks run.
+func newTestRelay(t *testing.T, pool *pgxpool.Pool, runs *atomic.Int32) *Relay {
+	t.Helper()
+	queries := db.New(pool)
+	backend := scheduler.NewInlineBackend(asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
+		runs.Add(1)
+		taskID, _ := scheduler.TaskID(ctx)
+		id, err := db.JobID(taskID)
+		if err != nil {
+			return err
+		}
+		return queries.MarkJobCompleted(ctx, id)
+	}), scheduler.InlineConfig{})
+	client := scheduler.NewClientWithBackend(backend, pool)
+	t.Cleanup(func() { client.Close() })
+	return NewRelay(pool, client, time.Second, 10, time.Hour)
+}
+
+// writeMessage commits a message to the outbox and returns its task ID.
+func writeMessage(t *testing.T, pool *pgxpool.Pool, opts ...asynq.Option) string {
+	t.Helper()
+	ctx := context.Background()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback(ctx)
+	taskID, err := Enqueue(ctx, db.New(pool).WithTx(tx), "test:outbox", []byte(`{"n":1}`), opts...)
+	if err != nil {
+		t.Fatalf("Enqueue() = %v", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return taskID
+}
+
+// loseDispatch undoes the marking of a dispatched message, as if the claiming
+// transaction had been lost after the enqueue.
+func loseDispatch(t *testing.T, pool *pgxpool.Pool, taskID string) {
+	t.Helper()
+	_, err := pool.Exec(context.Background(), `UPDATE outbox SET status = 'pending', dispatched_at = NULL WHERE task_id = $1`, taskID)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func messageStatus(t *testing.T, pool *pgxpool.Pool, taskID string) string {
+	t.Helper()
+	var status string
+	if err := pool.QueryRow(context.Background(), `SELECT status FROM outbox WHERE task_id = $1`, taskID).Scan(&status); err != nil {
+		t.Fatal(err)
+	}
+	return status
+}
+
+func TestRelayDispatch(t *testing.T) {
+	pool := dbtest.Pool(t)
+	var runs atomic.Int32
+	r := newTestRelay(t, pool, &runs)
+	taskID := writeMessage(t, pool)
+
+	n, err := r.dispatch(context.Background())
+	if err != nil || n != 1 {
+		t.Fatalf("dispatch() = %d, %v; want 1 message", n, err)
+	}
+	if runs.Load() != 1 {
+		t.Errorf("runs = %d, want 1", runs.Load())
+	}
+	if status := messageStatus(t, pool, taskID); status != StatusDispatched {
+		t.Errorf("message = %s, want %s", status, StatusDispatched)
+	}
+
+	// Dispatched messages are not claimed again
+	if n, err := r.dispatch(context.Background()); err != nil || n != 0 {
+		t.Errorf("second dispatch() = %d, %v; want no message", n, err)
+	}
+}
+
+func TestRelayRedispatch(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []asynq.Option
+		runs int32
+	}{
+		// The task completed without retention and is gone from its queue
+		{name: "task finished", runs: 1},
+		// The task still waits in its queue
+		{name: "task queued", opts: []asynq.Option{asynq.ProcessIn(time.Hour)}, runs: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := dbtest.Pool(t)
+			var runs atomic.Int32
+			r := newTestRelay(t, pool, &runs)
+			taskID := writeMessage(t, pool, tt.opts...)
+
+			if _, err := r.dispatch(context.Background()); err != nil {
+				t.Fatalf("dispatch() = %v", err)
+			}
+			loseDispatch(t, pool, taskID)
+			n, err := r.dispatch(context.Background())
+			if err != nil || n != 1 {
+				t.Fatalf("second dispatch() = %d, %v; want the message claimed again", n, err)
+			}
+
+			if runs.Load() != tt.runs {
+				t.Errorf("runs = %d, want %d", runs.Load(), tt.runs)
+			}
+			if status := messageStatus(t, pool, taskID); status != StatusDispatched {
+				t.Errorf("message = %s, want %s", status, StatusDispatched)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+// Package progress lets task handlers report how far along they are.
+// The latest event of each task is stored in Redis with a TTL and published on a
+// per-task channel, which the API streams to clients as Server-Sent Events.
+package progress
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// keyPrefix namespaces the latest event of each task in Redis.
+	keyPrefix = "progress:"
+	// channelPrefix namespaces the pub/sub channel of each task.
+	channelPrefix = "progress:events:"
+)
+
+// Event types. Completed, failed and cancelled are terminal: no event follows them.
+const (
+	EventProgress  = "progress"
+	EventRetrying  = "retrying"
+	EventCompleted = "completed"
+	EventFailed    = "failed"
+	EventCancelled = "cancelled"
+)
+
+// Event is a progress update or a change in the outcome of a task.
+type Event struct {
+	TaskID  string    `json:"task_id"`
+	Type    string    `json:"type"`
+	Percent int       `json:"percent"`
+	Stage   string    `json:"stage,omitempty"`
+	Message string    `json:"message,omitempty"`
+	Error   string    `json:"error,omitempty"`
+	At      time.Time `json:"at"`
+}
+
+// Terminal reports whether the task has finished for good.
+func (e Event) Terminal() bool {
+	switch e.Type {
+	case EventCompleted, EventFailed, EventCancelled:
+		return true
+	}
+	return false
+}
+
+// Store saves and publishes task events in Redis.
+type Store struct {
+	rdb redis.UniversalClient
+	ttl time.Duration
+}
+
+// NewStore returns a store keeping the latest event of each task for ttl.
+func NewStore(rdb redis.UniversalClient, ttl time.Duration) *Store {
+	return &Store{rdb: rdb, ttl: ttl}
+}
+
+// Publish stores e as the latest event of its task and publishes it to live subscribers.
+// Percent is clamped to 0-100 and At defaults to now.
+func (s *Store) Publish(ctx context.Context, e Event) error {
+	e.Percent = min(max(e.Percent, 0), 100)
+	if e.At.IsZero() {
+		e.At = time.Now().UTC()
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to marshal progress event: %w", err)
+	}
+
+	_, err = s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, keyPrefix+e.TaskID, data, s.ttl)
+		pipe.Publish(ctx, channelPrefix+e.TaskID, data)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish progress of task %s: %w", e.TaskID, err)
+	}
+	return nil
+}
+
+// Latest returns the latest event of taskID, and false if there is none or it expired.
+func (s *Store) Latest(ctx context.Context, taskID string) (Event, bool, error) {
+	data, err := s.rdb.Get(ctx, keyPrefix+taskID).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return Event{}, false, nil
+	}
+	if err != nil {
+		return Event{}, false, fmt.Errorf("failed to load progress of task %s: %w", taskID, err)
+	}
+
+	var e Event
+	if err := json.Unmarshal(data, &e); err != nil {
+		return Event{}, false, fmt.Errorf("failed to decode progress of task %s: %w", taskID, err)
+	}
+	return e, true, nil
+}
+
+// Subscription receives the events of one task as they are published.
+type Subscription struct {
+	pubsub *redis.PubSub
+	events chan Event
+	done   chan struct{}
+	once   sync.Once
+}
+
+// Subscribe starts receiving the events of taskID. The subscription is active once
+// Subscribe returns, so a Latest call made afterwards cannot miss an event.
+// Close the subscription when done.
+func (s *Store) Subscribe(ctx context.Context, taskID string) (*Subscription, error) {
+	pubsub := s.rdb.Subscribe(ctx, channelPrefix+taskID)
+	// Wait for the confirmation so no event published from now on is lost
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, fmt.Errorf("failed to subscribe to progress of task %s: %w", taskID, err)
+	}
+
+	sub := &Subscription{
+		pubsub: pubsub,
+		events: make(chan Event),
+		done:   make(chan struct{}),
+	}
+	go sub.run()
+	return sub, nil
+}
+
+func (sub *Subscription) run() {
+	defer close(sub.events)
+	for msg := range sub.pubsub.Channel() {
+		var e Event
+		if err := json.Unmarshal([]byte(msg.Payload), &e); err != nil {
+			continue
+		}
+		select {
+		case sub.events <- e:
+		case <-sub.done:
+			return
+		}
+	}
+}
+
+// Events returns the channel of received events. It is closed once the subscription is.
+func (sub *Subscription) Events() <-chan Event {
+	return sub.events
+}
+
+// Close ends the subscription.
+func (sub *Subscription) Close() error {
+	sub.once.Do(func() { close(sub.done) })
+	return sub.pubsub.Close()
+}
@@ -0,0 +1,117 @@
+package progress
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestStore(t *testing.T) (*Store, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	return NewStore(rdb, time.Hour), mr
+}
+
+func TestStoreLatest(t *testing.T) {
+	s, mr := newTestStore(t)
+	ctx := context.Background()
+
+	if _, ok, err := s.Latest(ctx, "a"); ok || err != nil {
+		t.Fatalf("Latest() = %v, %v; want no event", ok, err)
+	}
+
+	if err := s.Publish(ctx, Event{TaskID: "a", Type: EventProgress, Percent: 140, Stage: "sending"}); err != nil {
+		t.Fatalf("Publish() = %v", err)
+	}
+	e, ok, err := s.Latest(ctx, "a")
+	if err != nil || !ok {
+		t.Fatalf("Latest() = %v, %v; want the event", ok, err)
+	}
+	if e.Type != EventProgress || e.Percent != 100 || e.Stage != "sending" || e.At.IsZero() {
+		t.Errorf("event = %+v, want progress clamped to 100 with its time set", e)
+	}
+	if ttl := mr.TTL(keyPrefix + "a"); ttl != time.Hour {
+		t.Errorf("TTL = %v, want %v", ttl, time.Hour)
+	}
+
+	// The latest event expires with its TTL
+	mr.FastForward(time.Hour)
+	if _, ok, err := s.Latest(ctx, "a"); ok || err != nil {
+		t.Errorf("Latest() after the TTL = %v, %v; want no event", ok, err)
+	}
+}
+
+func TestStoreSubscribe(t *testing.T) {
+	s, _ := newTestStore(t)
+	ctx := context.Background()
+
+	sub, err := s.Subscribe(ctx, "a")
+	if err != nil {
+		t.Fatalf("Subscribe() = %v", err)
+	}
+	defer sub.Close()
+
+	// Events of other tasks are not received
+	for _, e := range []Event{
+		{TaskID: "b", Type: EventProgress, Percent: 10},
+		{TaskID: "a", Type: EventProgress, Percent: 50},
+		{TaskID: "a", Type: EventCompleted, Percent: 100},
+	} {
+		if err := s.Publish(ctx, e); err != nil {
+			t.Fatalf("Publish() = %v", err)
+		}
+	}
+
+	for _, want := range []Event{{Type: EventProgress, Percent: 50}, {Type: EventCompleted, Percent: 100}} {
+		select {
+		case e := <-sub.Events():
+			if e.TaskID != "a" || e.Type != want.Type || e.Percent != want.Percent {
+				t.Errorf("event = %+v, want %s at %d%%", e, want.Type, want.Percent)
+			}
+			if terminal := want.Type == EventCompleted; e.Terminal() != terminal {
+				t.Errorf("%s.Terminal() = %v, want %v", e.Type, e.Terminal(), terminal)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no %s event received", want.Type)
+		}
+	}
+
+	if err := sub.Close(); err != nil {
+		t.Fatalf("Close() = %v", err)
+	}
+	select {
+	case _, open := <-sub.Events():
+		if open {
+			t.Error("received an event after Close")
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("events channel not closed after Close")
+	}
+}
+
+func TestReport(t *testing.T) {
+	s, _ := newTestStore(t)
+	ctx := context.Background()
+
+	// Outside the worker, reports are dropped
+	if err := Report(ctx, Update{Percent: 10}); err != nil {
+		t.Fatalf("Report() without a reporter = %v", err)
+	}
+
+	ctx = WithReporter(ctx, s, "a")
+	if err := Report(ctx, Update{Percent: 40, Stage: "downloading", Message: "2 of 5 files"}); err != nil {
+		t.Fatalf("Report() = %v", err)
+	}
+	e, ok, err := s.Latest(ctx, "a")
+	if err != nil || !ok {
+		t.Fatalf("Latest() = %v, %v; want the report", ok, err)
+	}
+	if e.TaskID != "a" || e.Type != EventProgress || e.Percent != 40 || e.Stage != "downloading" || e.Message != "2 of 5 files" {
+		t.Errorf("event = %+v, want the reported progress", e)
+	}
+}
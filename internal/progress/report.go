@@ -0,0 +1,41 @@
+package progress
+
+import "context"
+
+// Update is a progress report from a task handler.
+type Update struct {
+	// Percent is how far along the task is, from 0 to 100.
+	Percent int
+	// Stage names the current step, e.g. "downloading".
+	Stage   string
+	Message string
+}
+
+type reporterCtxKey struct{}
+
+type reporter struct {
+	store  *Store
+	taskID string
+}
+
+// WithReporter returns a copy of ctx through which handlers report the progress of taskID.
+// The worker sets it for every task, see Report.
+func WithReporter(ctx context.Context, store *Store, taskID string) context.Context {
+	return context.WithValue(ctx, reporterCtxKey{}, reporter{store: store, taskID: taskID})
+}
+
+// Report publishes a progress update for the task being processed with ctx.
+// It does nothing when ctx does not come from the worker.
+func Report(ctx context.Context, u Update) error {
+	r, ok := ctx.Value(reporterCtxKey{}).(reporter)
+	if !ok {
+		return nil
+	}
+	return r.store.Publish(ctx, Event{
+		TaskID:  r.taskID,
+		Type:    EventProgress,
+		Percent: u.Percent,
+		Stage:   u.Stage,
+		Message: u.Message,
+	})
+}
@@ -16,6 +16,16 @@ func Names() []string {
 	return []string{QueueCritical, QueueDefault, QueueLow}
 }
 
+// Valid reports whether name is one of the configured queues.
+func Valid(name string) bool {
+	for _, n := range Names() {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
 // Priorities returns the queue priority configuration map.
 // Higher values indicate higher priority.
 func Priorities() map[string]int {
@@ -0,0 +1,148 @@
+// Package ratelimit implements a token bucket stored in Redis, so a limit is shared
+// by every worker process instead of applying per process.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// keyPrefix namespaces bucket keys in Redis.
+const keyPrefix = "ratelimit:"
+
+// Rule allows Limit tokens per Period. The bucket holds at most Limit tokens,
+// so up to Limit tasks can start back to back after an idle period.
+type Rule struct {
+	Limit  int
+	Period time.Duration
+}
+
+// rate returns the refill rate in tokens per microsecond.
+func (r Rule) rate() float64 {
+	return float64(r.Limit) / float64(r.Period.Microseconds())
+}
+
+func (r Rule) String() string {
+	return fmt.Sprintf("%d/%v", r.Limit, r.Period)
+}
+
+// ParseRule parses a rule of the form "limit/period", e.g. "10/s", "600/m" or "5/30s".
+// A bare unit means one of it.
+func ParseRule(s string) (Rule, error) {
+	limit, period, ok := strings.Cut(strings.TrimSpace(s), "/")
+	if !ok {
+		return Rule{}, fmt.Errorf("rate limit %q must have the form limit/period, e.g. 10/s", s)
+	}
+
+	n, err := strconv.Atoi(limit)
+	if err != nil || n <= 0 {
+		return Rule{}, fmt.Errorf("rate limit %q must have a positive integer limit", s)
+	}
+
+	if period != "" && (period[0] < '0' || period[0] > '9') {
+		period = "1" + period
+	}
+	d, err := time.ParseDuration(period)
+	if err != nil || d < time.Millisecond {
+		return Rule{}, fmt.Errorf("rate limit %q must have a period of at least 1ms", s)
+	}
+
+	return Rule{Limit: n, Period: d}, nil
+}
+
+// ParseRules parses a rule per key, see ParseRule.
+func ParseRules(rules map[string]string) (map[string]Rule, error) {
+	parsed := make(map[string]Rule, len(rules))
+	for key, s := range rules {
+		rule, err := ParseRule(s)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		parsed[key] = rule
+	}
+	return parsed, nil
+}
+
+// takeScript refills the bucket for the time elapsed since it was last used, then takes
+// one token if there is one. It returns whether a token was taken and, if not, the number
+// of microseconds until one is available. Redis's clock is used so workers agree on time.
+var takeScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local ttl = tonumber(ARGV[3])
+
+local now = redis.call('TIME')
+now = tonumber(now[1]) * 1000000 + tonumber(now[2])
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or burst
+local ts = tonumber(state[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate)
+
+local allowed = 0
+local wait = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	wait = math.ceil((1 - tokens) / rate)
+end
+
+-- Format explicitly, tostring would round microsecond timestamps
+redis.call('HSET', KEYS[1], 'tokens', string.format('%.6f', tokens), 'ts', string.format('%.0f', now))
+redis.call('PEXPIRE', KEYS[1], ttl)
+return {allowed, wait}
+`)
+
+// Limiter takes tokens from buckets stored in Redis.
+type Limiter struct {
+	rdb redis.UniversalClient
+}
+
+// NewLimiter returns a limiter storing its buckets in rdb.
+func NewLimiter(rdb redis.UniversalClient) *Limiter {
+	return &Limiter{rdb: rdb}
+}
+
+// Allow takes a token from the bucket of key, limited by rule.
+// When the bucket is empty it returns false and how long until a token is available.
+func (l *Limiter) Allow(ctx context.Context, key string, rule Rule) (bool, time.Duration, error) {
+	// A full bucket is the same as no bucket, so it can expire once it has refilled
+	ttl := rule.Period + time.Second
+
+	res, err := takeScript.Run(ctx, l.rdb, []string{keyPrefix + key},
+		strconv.FormatFloat(rule.rate(), 'f', -1, 64),
+		rule.Limit,
+		ttl.Milliseconds(),
+	).Int64Slice()
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to take rate limit token for %s: %w", key, err)
+	}
+	if len(res) != 2 {
+		return false, 0, fmt.Errorf("unexpected rate limit reply for %s: %v", key, res)
+	}
+	return res[0] == 1, time.Duration(res[1]) * time.Microsecond, nil
+}
+
+// Wait blocks until a token is taken from the bucket of key or ctx is done.
+func (l *Limiter) Wait(ctx context.Context, key string, rule Rule) error {
+	for {
+		ok, wait, err := l.Allow(ctx, key, rule)
+		if err != nil || ok {
+			return err
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    Rule
+		wantErr bool
+	}{
+		{name: "per second", value: "10/s", want: Rule{Limit: 10, Period: time.Second}},
+		{name: "per minute", value: "600/m", want: Rule{Limit: 600, Period: time.Minute}},
+		{name: "per hour", value: "1/h", want: Rule{Limit: 1, Period: time.Hour}},
+		{name: "explicit period", value: "5/30s", want: Rule{Limit: 5, Period: 30 * time.Second}},
+		{name: "milliseconds", value: "1/ms", want: Rule{Limit: 1, Period: time.Millisecond}},
+		{name: "surrounding whitespace", value: " 10/s ", want: Rule{Limit: 10, Period: time.Second}},
+		{name: "empty", value: "", wantErr: true},
+		{name: "missing period", value: "10", wantErr: true},
+		{name: "empty period", value: "10/", wantErr: true},
+		{name: "unknown unit", value: "10/day", wantErr: true},
+		{name: "non-integer limit", value: "ten/s", wantErr: true},
+		{name: "zero limit", value: "0/s", wantErr: true},
+		{name: "negative limit", value: "-1/s", wantErr: true},
+		{name: "period below 1ms", value: "1/500us", wantErr: true},
+		{name: "negative period", value: "1/-1s", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRule(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseRule(%q) = %v, want error", tt.value, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseRule(%q) error: %v", tt.value, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseRule(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   map[string]string
+		want    map[string]Rule
+		wantErr bool
+	}{
+		{name: "none", rules: nil, want: map[string]Rule{}},
+		{
+			name:  "several",
+			rules: map[string]string{"email:send": "10/s", "report:build": "5/m"},
+			want: map[string]Rule{
+				"email:send":   {Limit: 10, Period: time.Second},
+				"report:build": {Limit: 5, Period: time.Minute},
+			},
+		},
+		{
+			name:    "one invalid",
+			rules:   map[string]string{"email:send": "10/s", "report:build": "fast"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRules(tt.rules)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseRules() = %v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseRules() error: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ParseRules() = %v, want %v", got, tt.want)
+			}
+			for key, rule := range tt.want {
+				if got[key] != rule {
+					t.Errorf("ParseRules()[%q] = %v, want %v", key, got[key], rule)
+				}
+			}
+		})
+	}
+}
+
+func newTestLimiter(t *testing.T) *Limiter {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	return NewLimiter(rdb)
+}
+
+func TestLimiterAllow(t *testing.T) {
+	l := newTestLimiter(t)
+	ctx := context.Background()
+	rule := Rule{Limit: 3, Period: time.Hour}
+
+	// A new bucket is full, so the limit is the burst
+	for i := range rule.Limit {
+		ok, _, err := l.Allow(ctx, "email:send", rule)
+		if err != nil || !ok {
+			t.Fatalf("Allow() #%d = %v, %v; want a token", i+1, ok, err)
+		}
+	}
+	ok, wait, err := l.Allow(ctx, "email:send", rule)
+	if err != nil || ok {
+		t.Fatalf("Allow() on an empty bucket = %v, %v; want no token", ok, err)
+	}
+	// One token refills every 20m
+	if wait <= 19*time.Minute || wait > 20*time.Minute {
+		t.Errorf("wait = %v, want about 20m", wait)
+	}
+
+	// Buckets are per key
+	if ok, _, err := l.Allow(ctx, "report:build", rule); err != nil || !ok {
+		t.Errorf("Allow() on another key = %v, %v; want a token", ok, err)
+	}
+}
+
+func TestLimiterWait(t *testing.T) {
+	l := newTestLimiter(t)
+	rule := Rule{Limit: 1, Period: 50 * time.Millisecond}
+	ctx := context.Background()
+
+	if err := l.Wait(ctx, "email:send", rule); err != nil {
+		t.Fatalf("Wait() = %v", err)
+	}
+	start := time.Now()
+	if err := l.Wait(ctx, "email:send", rule); err != nil {
+		t.Fatalf("Wait() = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("Wait() returned after %v, want about 50ms for the next token", elapsed)
+	}
+
+	// A done context ends the wait
+	slow := Rule{Limit: 1, Period: time.Hour}
+	if err := l.Wait(ctx, "report:build", slow); err != nil {
+		t.Fatalf("Wait() = %v", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx, "report:build", slow); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wait() = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
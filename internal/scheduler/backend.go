@@ -0,0 +1,93 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Backend kinds accepted by NewBackend.
+const (
+	BackendAsynq    = "asynq"
+	BackendPostgres = "postgres"
+)
+
+// ErrUnsupported is returned for features a backend does not provide, such as task
+// groups or asynq.Unique with the Postgres backend or inline mode.
+var ErrUnsupported = errors.New("not supported by the queue backend")
+
+// Backend is the queue tasks are handed to. Client records the job row and resolves
+// the task ID and headers before calling Enqueue, so backends only store and deliver tasks.
+// Errors follow asynq's: asynq.ErrTaskIDConflict for a duplicate task ID, and
+// asynq.ErrTaskNotFound or asynq.ErrQueueNotFound for unknown tasks.
+type Backend interface {
+	// Enqueue stores task with opts, which always include asynq.TaskID.
+	Enqueue(ctx context.Context, task *asynq.Task, opts ...asynq.Option) (*asynq.TaskInfo, error)
+	// GetTaskInfo returns the state of a task, including its result once completed.
+	GetTaskInfo(ctx context.Context, queue, taskID string) (*asynq.TaskInfo, error)
+	// DeleteTask removes a task that is not active.
+	DeleteTask(ctx context.Context, queue, taskID string) error
+	// CancelProcessing asks the worker running an active task to cancel its context.
+	CancelProcessing(ctx context.Context, taskID string) error
+	Close() error
+}
+
+// NewBackend returns the backend of the given kind: asynq on Redis, or the
+// queue_tasks table of pool.
+func NewBackend(kind string, redisOpt asynq.RedisClientOpt, pool *pgxpool.Pool) (Backend, error) {
+	switch kind {
+	case BackendAsynq:
+		return NewAsynqBackend(redisOpt), nil
+	case BackendPostgres:
+		return NewPostgresBackend(pool), nil
+	default:
+		return nil, fmt.Errorf("unknown queue backend %q, must be %s or %s", kind, BackendAsynq, BackendPostgres)
+	}
+}
+
+// supportsGroups reports whether backend aggregates grouped tasks. Only asynq does.
+func supportsGroups(backend Backend) bool {
+	_, ok := backend.(*AsynqBackend)
+	return ok
+}
+
+// AsynqBackend stores tasks in Redis through asynq. Its tasks are processed by an asynq.Server.
+type AsynqBackend struct {
+	client    *asynq.Client
+	inspector *asynq.Inspector
+}
+
+// NewAsynqBackend returns a backend connected to Redis with redisOpt.
+func NewAsynqBackend(redisOpt asynq.RedisClientOpt) *AsynqBackend {
+	return &AsynqBackend{
+		client:    asynq.NewClient(redisOpt),
+		inspector: asynq.NewInspector(redisOpt),
+	}
+}
+
+func (b *AsynqBackend) Enqueue(ctx context.Context, task *asynq.Task, opts ...asynq.Option) (*asynq.TaskInfo, error) {
+	return b.client.EnqueueContext(ctx, task, opts...)
+}
+
+func (b *AsynqBackend) GetTaskInfo(_ context.Context, queue, taskID string) (*asynq.TaskInfo, error) {
+	return b.inspector.GetTaskInfo(queue, taskID)
+}
+
+func (b *AsynqBackend) DeleteTask(_ context.Context, queue, taskID string) error {
+	return b.inspector.DeleteTask(queue, taskID)
+}
+
+func (b *AsynqBackend) CancelProcessing(_ context.Context, taskID string) error {
+	return b.inspector.CancelProcessing(taskID)
+}
+
+func (b *AsynqBackend) Close() error {
+	inspectorErr := b.inspector.Close()
+	if err := b.client.Close(); err != nil {
+		return err
+	}
+	return inspectorErr
+}
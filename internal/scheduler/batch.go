@@ -0,0 +1,155 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"boiler-go/internal/db"
+
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// batchEnqueueConcurrency bounds the concurrent Redis enqueues of one EnqueueBatch call.
+const batchEnqueueConcurrency = 16
+
+// BatchTask is one task of an EnqueueBatch call.
+type BatchTask struct {
+	Type    string
+	Payload []byte
+	Opts    []asynq.Option
+	// Headers are added to the task like WithTaskHeaders does.
+	Headers map[string]string
+}
+
+// BatchResult is the outcome of the BatchTask at the same index.
+type BatchResult struct {
+	TaskID string
+	Err    error
+}
+
+// EnqueueBatch enqueues many tasks at once. The job rows of the whole batch are recorded
+// in one pipelined round trip and the tasks are then enqueued concurrently.
+// Once their rows are recorded, tasks succeed or fail on their own: results[i] is the
+// outcome of batch[i]. The rows are recorded in one transaction, so if any of them
+// cannot be, no task is enqueued.
+// With a context from WithIdempotencyKey, task i derives its ID from the key and i.
+func (c *Client) EnqueueBatch(ctx context.Context, batch []BatchTask) []BatchResult {
+	results := make([]BatchResult, len(batch))
+	idempotencyKey, idempotent := IdempotencyKeyFrom(ctx)
+
+	// index maps each pending task back to its position in batch
+	pending := make([]pendingTask, 0, len(batch))
+	index := make([]int, 0, len(batch))
+	for i, t := range batch {
+		var key string
+		if idempotent {
+			key = idempotencyKey + "\x00" + strconv.Itoa(i)
+		}
+		taskCtx := ctx
+		if len(t.Headers) > 0 {
+			taskCtx = WithTaskHeaders(ctx, t.Headers)
+		}
+		p, err := c.prepare(taskCtx, t.Type, t.Payload, key, t.Opts)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		pending = append(pending, p)
+		index = append(index, i)
+	}
+	if len(pending) == 0 {
+		return results
+	}
+
+	if err := c.recordJobs(ctx, pending); err != nil {
+		for _, i := range index {
+			results[i].Err = fmt.Errorf("failed to record job: %w", err)
+		}
+		return results
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, batchEnqueueConcurrency)
+	for i, p := range pending {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			info, err := c.submit(ctx, p)
+			if err != nil {
+				results[index[i]].Err = err
+				return
+			}
+			results[index[i]].TaskID = info.ID
+		}()
+	}
+	wg.Wait()
+
+	// Drop the rows of tasks that never reached the queue, as enqueue does.
+	// A conflicting task ID, or a row this call did not insert, means the row belongs
+	// to the task already queued.
+	var orphaned []pgtype.UUID
+	var orphanedAt []int
+	for i, p := range pending {
+		err := results[index[i]].Err
+		if p.inserted && err != nil && !errors.Is(err, asynq.ErrTaskIDConflict) {
+			orphaned = append(orphaned, p.job.ID)
+			orphanedAt = append(orphanedAt, index[i])
+		}
+	}
+	if len(orphaned) > 0 {
+		if delErr := c.queries.DeleteJobs(context.WithoutCancel(ctx), orphaned); delErr != nil {
+			for _, i := range orphanedAt {
+				results[i].Err = fmt.Errorf("%w (failed to remove job row: %v)", results[i].Err, delErr)
+			}
+		}
+	}
+
+	return results
+}
+
+// recordJobs records the job rows of pending in one transaction. Each task is marked
+// inserted only once the transaction has committed: a failed row aborts the whole
+// transaction, including the rows that were inserted before it.
+func (c *Client) recordJobs(ctx context.Context, pending []pendingTask) error {
+	tx, err := c.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(context.WithoutCancel(ctx))
+
+	params := make([]db.CreateJobsParams, len(pending))
+	for i, p := range pending {
+		params[i] = db.CreateJobsParams(p.job)
+	}
+	inserted := make([]bool, len(pending))
+	var batchErr error
+	c.queries.WithTx(tx).CreateJobs(ctx, params).QueryRow(func(i int, _ pgtype.UUID, err error) {
+		switch {
+		case err == nil:
+			inserted[i] = true
+		case errors.Is(err, pgx.ErrNoRows):
+			// No row means the job was recorded by an earlier enqueue of the same task
+		case batchErr == nil:
+			batchErr = err
+		}
+	})
+	if batchErr != nil {
+		return batchErr
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit jobs: %w", err)
+	}
+
+	for i := range pending {
+		pending[i].inserted = inserted[i]
+	}
+	return nil
+}
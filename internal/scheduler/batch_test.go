@@ -0,0 +1,86 @@
+//go:build integration
+
+package scheduler
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"boiler-go/internal/dbtest"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestEnqueueBatch(t *testing.T) {
+	pool := dbtest.Pool(t)
+	var runs atomic.Int32
+	client := NewClientWithBackend(NewInlineBackend(asynq.HandlerFunc(func(context.Context, *asynq.Task) error {
+		runs.Add(1)
+		return nil
+	}), InlineConfig{}), pool)
+	defer client.Close()
+	ctx := WithIdempotencyKey(context.Background(), "batch-1")
+
+	batch := []BatchTask{
+		{Type: "test:batch", Payload: []byte(`{"n":1}`), Opts: []asynq.Option{asynq.Retention(time.Hour)}},
+		{Type: "test:batch", Opts: []asynq.Option{asynq.Retention(time.Hour)}},
+	}
+	results := client.EnqueueBatch(ctx, batch)
+	for i, r := range results {
+		if r.Err != nil || r.TaskID == "" {
+			t.Fatalf("results[%d] = %+v, want a task ID", i, r)
+		}
+	}
+	if runs.Load() != 2 {
+		t.Errorf("runs = %d, want 2", runs.Load())
+	}
+
+	// Replaying the batch with the same key finds the same tasks
+	again := client.EnqueueBatch(ctx, batch)
+	for i, r := range again {
+		if r.Err != nil || r.TaskID != results[i].TaskID {
+			t.Errorf("replayed results[%d] = %+v, want task %s", i, r, results[i].TaskID)
+		}
+	}
+	var jobs int
+	if err := pool.QueryRow(context.Background(), `SELECT count(*) FROM jobs`).Scan(&jobs); err != nil {
+		t.Fatal(err)
+	}
+	if jobs != 2 || runs.Load() != 2 {
+		t.Errorf("jobs = %d after %d runs, want 2 jobs run once", jobs, runs.Load())
+	}
+}
+
+func TestEnqueueBatchFailingRow(t *testing.T) {
+	pool := dbtest.Pool(t)
+	var runs atomic.Int32
+	client := NewClientWithBackend(NewInlineBackend(asynq.HandlerFunc(func(context.Context, *asynq.Task) error {
+		runs.Add(1)
+		return nil
+	}), InlineConfig{}), pool)
+	defer client.Close()
+
+	// Valid JSON that jsonb rejects, so the second row fails after the first was inserted
+	results := client.EnqueueBatch(context.Background(), []BatchTask{
+		{Type: "test:batch", Payload: []byte(`{"n":1}`)},
+		{Type: "test:batch", Payload: []byte(`{"n":"\u0000"}`)},
+		{Type: "test:batch", Payload: []byte(`{"n":3}`)},
+	})
+	for i, r := range results {
+		if r.Err == nil {
+			t.Errorf("results[%d] succeeded, want the batch to fail", i)
+		}
+	}
+	if runs.Load() != 0 {
+		t.Errorf("runs = %d, want no task enqueued", runs.Load())
+	}
+	var jobs int
+	if err := pool.QueryRow(context.Background(), `SELECT count(*) FROM jobs`).Scan(&jobs); err != nil {
+		t.Fatal(err)
+	}
+	if jobs != 0 {
+		t.Errorf("jobs = %d, want none recorded", jobs)
+	}
+}
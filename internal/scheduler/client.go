@@ -2,40 +2,302 @@ package scheduler
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"time"
 
+	"boiler-go/internal/claimcheck"
+	"boiler-go/internal/db"
+	"boiler-go/internal/queue"
+
+	"github.com/google/uuid"
 	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-// Client wraps asynq.Client for task scheduling
+// Client schedules tasks on a Backend, asynq on Redis by default.
+// Every enqueued task is recorded as a pending row in the jobs table.
+// Enqueues made with a context from WithIdempotencyKey are deduplicated by task ID.
+// The request ID and trace context found in ctx, and the enqueue time, are copied into the task headers.
+// With OffloadPayloads, payloads above a size threshold are kept out of Redis.
 type Client struct {
-	client *asynq.Client
+	backend Backend
+	pool    *pgxpool.Pool
+	queries *db.Queries
+
+	payloads         claimcheck.Store
+	offloadThreshold int
 }
 
 // NewClient creates a new scheduler client
-func NewClient(redisOpt asynq.RedisClientOpt) *Client {
+func NewClient(redisOpt asynq.RedisClientOpt, pool *pgxpool.Pool) *Client {
+	return NewClientWithBackend(NewAsynqBackend(redisOpt), pool)
+}
+
+// NewClientWithBackend creates a scheduler client that hands tasks to backend
+func NewClientWithBackend(backend Backend, pool *pgxpool.Pool) *Client {
 	return &Client{
-		client: asynq.NewClient(redisOpt),
+		backend: backend,
+		pool:    pool,
+		queries: db.New(pool),
+	}
+}
+
+// OffloadPayloads makes the client store payloads larger than threshold bytes in store
+// and enqueue only a reference to them. It must be called before the client is used.
+func (c *Client) OffloadPayloads(store claimcheck.Store, threshold int) {
+	c.payloads = store
+	c.offloadThreshold = threshold
+}
+
+// DeletePayload deletes the offloaded payload of a task, if it has one.
+// It is a no-op unless payloads are offloaded.
+func (c *Client) DeletePayload(ctx context.Context, taskID string) error {
+	if c.payloads == nil {
+		return nil
 	}
+	return c.payloads.Delete(ctx, taskID)
+}
+
+// SweepPayloads deletes the offloaded payloads stored before the given time whose task
+// is gone from its queue, e.g. archived tasks trimmed by asynq, or tasks lost with an
+// inline backend. It returns the number of payloads deleted. It is a no-op unless
+// payloads are offloaded.
+func (c *Client) SweepPayloads(ctx context.Context, before time.Time) (int, error) {
+	if c.payloads == nil {
+		return 0, nil
+	}
+	keys, err := c.payloads.Keys(ctx, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list offloaded payloads: %w", err)
+	}
+
+	deleted := 0
+	for _, key := range keys {
+		live, err := c.taskExists(ctx, key)
+		if err != nil {
+			return deleted, err
+		}
+		if live {
+			continue
+		}
+		if err := c.payloads.Delete(ctx, key); err != nil {
+			return deleted, fmt.Errorf("failed to delete offloaded payload %s: %w", key, err)
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// taskExists reports whether the task with taskID is still in its queue, in any state.
+// The queue is read from the task's job row; a task without one was never enqueued.
+func (c *Client) taskExists(ctx context.Context, taskID string) (bool, error) {
+	jobID, err := db.JobID(taskID)
+	if err != nil {
+		return false, nil
+	}
+	job, err := c.queries.GetJob(ctx, jobID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to load job %s: %w", taskID, err)
+	}
+	_, err = c.backend.GetTaskInfo(ctx, job.Queue, taskID)
+	if errors.Is(err, asynq.ErrTaskNotFound) || errors.Is(err, asynq.ErrQueueNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to look up task %s: %w", taskID, err)
+	}
+	return true, nil
 }
 
 // Close closes the client connection
 func (c *Client) Close() error {
-	return c.client.Close()
+	return c.backend.Close()
+}
+
+// TaskInfo returns the state of a task in queue, including its result once completed.
+func (c *Client) TaskInfo(ctx context.Context, queue, taskID string) (*asynq.TaskInfo, error) {
+	return c.backend.GetTaskInfo(ctx, queue, taskID)
+}
+
+// DeleteTask removes a task that is not active from queue.
+func (c *Client) DeleteTask(ctx context.Context, queue, taskID string) error {
+	return c.backend.DeleteTask(ctx, queue, taskID)
+}
+
+// CancelProcessing asks the worker running an active task to cancel it.
+func (c *Client) CancelProcessing(ctx context.Context, taskID string) error {
+	return c.backend.CancelProcessing(ctx, taskID)
 }
 
 // Enqueue enqueues a task
 func (c *Client) Enqueue(ctx context.Context, taskType string, payload []byte, opts ...asynq.Option) error {
-	task := asynq.NewTask(taskType, payload)
-	_, err := c.client.EnqueueContext(ctx, task, opts...)
+	_, err := c.enqueue(ctx, taskType, payload, opts...)
 	return err
 }
 
+// EnqueueToGroup enqueues a task to group and returns the task ID. The tasks of a group
+// are held in their queue until the worker aggregates them into a single task, see
+// asynq.Group. Backends other than asynq fail with ErrUnsupported, before any job row
+// is recorded.
+func (c *Client) EnqueueToGroup(ctx context.Context, taskType, group string, payload []byte, opts ...asynq.Option) (string, error) {
+	if !supportsGroups(c.backend) {
+		return "", fmt.Errorf("task groups: %w", ErrUnsupported)
+	}
+	// Copy so the caller's slice is never written to
+	opts = append(opts[:len(opts):len(opts)], asynq.Group(group))
+	return c.EnqueueWithID(ctx, taskType, payload, opts...)
+}
+
 // EnqueueWithID enqueues a task and returns the task ID
 func (c *Client) EnqueueWithID(ctx context.Context, taskType string, payload []byte, opts ...asynq.Option) (string, error) {
-	task := asynq.NewTask(taskType, payload)
-	info, err := c.client.EnqueueContext(ctx, task, opts...)
+	info, err := c.enqueue(ctx, taskType, payload, opts...)
 	if err != nil {
 		return "", err
 	}
 	return info.ID, nil
 }
+
+// enqueue records the job row before handing the task to asynq, so a worker
+// that picks the task up immediately always finds the row to update.
+func (c *Client) enqueue(ctx context.Context, taskType string, payload []byte, opts ...asynq.Option) (*asynq.TaskInfo, error) {
+	idempotencyKey, _ := IdempotencyKeyFrom(ctx)
+	p, err := c.prepare(ctx, taskType, payload, idempotencyKey, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := c.queries.CreateJob(ctx, p.job)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record job: %w", err)
+	}
+	p.inserted = rows > 0
+
+	info, err := c.submit(ctx, p)
+	if err != nil {
+		// A conflicting task ID means the row belongs to the task that is already queued,
+		// and a row this call did not insert belongs to an earlier enqueue of the same task.
+		if errors.Is(err, asynq.ErrTaskIDConflict) || !p.inserted {
+			return nil, err
+		}
+		// The task never reached the queue, so drop the row rather than leave it pending forever.
+		if delErr := c.queries.DeleteJob(context.WithoutCancel(ctx), p.job.ID); delErr != nil {
+			return nil, fmt.Errorf("%w (failed to remove job row: %v)", err, delErr)
+		}
+		return nil, err
+	}
+	return info, nil
+}
+
+// pendingTask is a task whose ID, headers and job row are resolved,
+// ready to be recorded and handed to asynq.
+type pendingTask struct {
+	task       *asynq.Task
+	opts       []asynq.Option
+	taskID     string
+	queue      string
+	job        db.CreateJobParams
+	idempotent bool
+	// inserted reports whether the job row was inserted by this enqueue rather than an
+	// earlier one with the same task ID. Only then may a failed enqueue remove what it stored.
+	inserted bool
+	// offloaded is the payload to store before enqueueing, if it is too large for Redis.
+	offloaded []byte
+}
+
+// prepare resolves the task ID of a task: the one in opts, one derived from
+// idempotencyKey when it is set, or a new UUID.
+func (c *Client) prepare(ctx context.Context, taskType string, payload []byte, idempotencyKey string, opts []asynq.Option) (pendingTask, error) {
+	taskID, queueName, group, processAt := resolveOptions(opts)
+	if taskID == "" {
+		if idempotencyKey != "" {
+			taskID = idempotentTaskID(taskType, idempotencyKey)
+		} else {
+			taskID = uuid.NewString()
+		}
+		// Copy so the caller's slice is never written to
+		opts = append(opts[:len(opts):len(opts)], asynq.TaskID(taskID))
+	}
+
+	jobID, err := db.JobID(taskID)
+	if err != nil {
+		return pendingTask{}, fmt.Errorf("task ID %q is not a valid job ID: %w", taskID, err)
+	}
+
+	p := pendingTask{
+		opts:   opts,
+		taskID: taskID,
+		queue:  queueName,
+		job: db.CreateJobParams{
+			ID:       jobID,
+			TaskType: taskType,
+			Queue:    queueName,
+			Payload:  db.JobPayload(payload),
+		},
+		idempotent: idempotencyKey != "",
+	}
+
+	headers := taskHeaders(ctx, time.Now(), processAt)
+	if group != "" {
+		// Aggregators read the payloads of grouped tasks directly, so they are never offloaded
+		headers[HeaderTaskID] = taskID
+	} else if c.payloads != nil && c.offloadThreshold > 0 && len(payload) > c.offloadThreshold {
+		// The payload is stored under the task ID and the task only carries the reference
+		headers[claimcheck.HeaderRef] = taskID
+		p.offloaded = payload
+		p.job.Payload = claimcheck.RefPayload(taskID, len(payload))
+		payload = nil
+	}
+	p.task = asynq.NewTaskWithHeaders(taskType, payload, headers)
+	return p, nil
+}
+
+// submit stores the offloaded payload of a recorded task, then hands the task to the backend.
+func (c *Client) submit(ctx context.Context, p pendingTask) (*asynq.TaskInfo, error) {
+	if p.offloaded != nil {
+		if err := c.payloads.Put(ctx, p.taskID, p.offloaded); err != nil {
+			return nil, fmt.Errorf("failed to offload payload: %w", err)
+		}
+	}
+
+	info, err := c.backend.Enqueue(ctx, p.task, p.opts...)
+	// For an idempotent enqueue the conflicting task is the original, so report it as the result.
+	if errors.Is(err, asynq.ErrTaskIDConflict) && p.idempotent {
+		return &asynq.TaskInfo{ID: p.taskID, Queue: p.queue, Type: p.task.Type()}, nil
+	}
+	// A conflicting task ID, or a job row recorded by an earlier enqueue, means the stored
+	// payload belongs to the task that is already queued.
+	if err != nil && p.offloaded != nil && p.inserted && !errors.Is(err, asynq.ErrTaskIDConflict) {
+		if delErr := c.payloads.Delete(context.WithoutCancel(ctx), p.taskID); delErr != nil {
+			return nil, fmt.Errorf("%w (failed to remove offloaded payload: %v)", err, delErr)
+		}
+	}
+	return info, err
+}
+
+// resolveOptions extracts the task ID, queue name, group and process time from opts.
+// Later options win, matching asynq's own option handling.
+func resolveOptions(opts []asynq.Option) (taskID, queueName, group string, processAt time.Time) {
+	queueName = queue.QueueDefault
+	for _, opt := range opts {
+		switch opt.Type() {
+		case asynq.TaskIDOpt:
+			taskID, _ = opt.Value().(string)
+		case asynq.QueueOpt:
+			queueName, _ = opt.Value().(string)
+		case asynq.GroupOpt:
+			group, _ = opt.Value().(string)
+		case asynq.ProcessAtOpt:
+			processAt, _ = opt.Value().(time.Time)
+		case asynq.ProcessInOpt:
+			if d, ok := opt.Value().(time.Duration); ok {
+				processAt = time.Now().Add(d)
+			}
+		}
+	}
+	return taskID, queueName, group, processAt
+}
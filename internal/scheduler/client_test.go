@@ -0,0 +1,38 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestEnqueueToGroupUnsupported(t *testing.T) {
+	ran := false
+	backend := NewInlineBackend(asynq.HandlerFunc(func(context.Context, *asynq.Task) error {
+		ran = true
+		return nil
+	}), InlineConfig{})
+	// The client has no pool, so recording a job row would panic
+	client := NewClientWithBackend(backend, nil)
+	defer client.Close()
+
+	if _, err := client.EnqueueToGroup(context.Background(), "test:grouped", "tenant:1", nil); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("EnqueueToGroup() = %v, want %v", err, ErrUnsupported)
+	}
+	if ran {
+		t.Error("grouped task ran")
+	}
+}
+
+func TestParseOptionsUnsupported(t *testing.T) {
+	for _, opt := range []asynq.Option{asynq.Group("tenant:1"), asynq.Unique(time.Hour)} {
+		t.Run(opt.String(), func(t *testing.T) {
+			if _, err := parseOptions([]asynq.Option{asynq.TaskID("a"), opt}, time.Now()); !errors.Is(err, ErrUnsupported) {
+				t.Errorf("parseOptions() = %v, want %v", err, ErrUnsupported)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package scheduler
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+type ctxKey int
+
+const (
+	idempotencyKeyCtxKey ctxKey = iota
+	taskHeadersCtxKey
+)
+
+// idempotencyNamespace scopes task IDs derived from Idempotency-Key values.
+var idempotencyNamespace = uuid.MustParse("6f0c9d52-3f8e-4c1b-9a57-2d4e8b1f7a30")
+
+// WithIdempotencyKey returns a context whose enqueues derive their task ID from key.
+// Enqueueing the same task type twice with the same key yields the same task ID,
+// which asynq rejects while the first task is still retained.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyCtxKey, key)
+}
+
+// IdempotencyKeyFrom returns the idempotency key stored in ctx, if any.
+func IdempotencyKeyFrom(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(idempotencyKeyCtxKey).(string)
+	return key, ok && key != ""
+}
+
+// WithTaskHeaders returns a context whose enqueues add headers to the task,
+// e.g. to tag a task with the workflow step it runs. They are merged with any
+// headers already in ctx, with headers taking precedence.
+func WithTaskHeaders(ctx context.Context, headers map[string]string) context.Context {
+	if existing, ok := ctx.Value(taskHeadersCtxKey).(map[string]string); ok {
+		merged := make(map[string]string, len(existing)+len(headers))
+		for k, v := range existing {
+			merged[k] = v
+		}
+		for k, v := range headers {
+			merged[k] = v
+		}
+		headers = merged
+	}
+	return context.WithValue(ctx, taskHeadersCtxKey, headers)
+}
+
+// idempotentTaskID derives a stable task ID from the task type and idempotency key.
+func idempotentTaskID(taskType, key string) string {
+	return uuid.NewSHA1(idempotencyNamespace, []byte(taskType+"\x00"+key)).String()
+}
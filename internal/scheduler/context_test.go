@@ -0,0 +1,37 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestIdempotentTaskID(t *testing.T) {
+	id := idempotentTaskID("email:send", "key-1")
+	if err := uuid.Validate(id); err != nil {
+		t.Fatalf("idempotentTaskID() = %q, want a UUID: %v", id, err)
+	}
+	if again := idempotentTaskID("email:send", "key-1"); again != id {
+		t.Errorf("idempotentTaskID() = %s then %s, want a stable ID", id, again)
+	}
+	// The same key names different tasks for different types
+	if other := idempotentTaskID("report:build", "key-1"); other == id {
+		t.Error("idempotentTaskID() is the same for two task types")
+	}
+	if other := idempotentTaskID("email:send", "key-2"); other == id {
+		t.Error("idempotentTaskID() is the same for two keys")
+	}
+}
+
+func TestIdempotencyKeyFrom(t *testing.T) {
+	if key, ok := IdempotencyKeyFrom(context.Background()); ok {
+		t.Errorf("IdempotencyKeyFrom() = %q, want none", key)
+	}
+	if key, ok := IdempotencyKeyFrom(WithIdempotencyKey(context.Background(), "")); ok {
+		t.Errorf("IdempotencyKeyFrom() of an empty key = %q, want none", key)
+	}
+	if key, ok := IdempotencyKeyFrom(WithIdempotencyKey(context.Background(), "key-1")); !ok || key != "key-1" {
+		t.Errorf("IdempotencyKeyFrom() = %q, %v; want key-1", key, ok)
+	}
+}
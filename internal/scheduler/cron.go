@@ -0,0 +1,165 @@
+package scheduler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/robfig/cron/v3"
+	"github.com/rs/zerolog"
+)
+
+// periodicEnqueueTimeout bounds each enqueue made by a PeriodicScheduler.
+const periodicEnqueueTimeout = 10 * time.Second
+
+// PeriodicScheduler enqueues periodic tasks through a Client, so they reach whatever
+// backend the client uses. asynq's PeriodicTaskManager only enqueues to Redis; this is
+// its counterpart for the Postgres backend. Like the manager, it re-reads its provider
+// every sync interval and keeps its current entries when a sync fails.
+type PeriodicScheduler struct {
+	client   *Client
+	provider asynq.PeriodicTaskConfigProvider
+	interval time.Duration
+	logg     zerolog.Logger
+
+	cron *cron.Cron
+	// entries maps the hash of each config to its cron entry
+	entries map[string]cron.EntryID
+	stop    chan struct{}
+	done    chan struct{}
+
+	mu      sync.Mutex
+	started bool
+	stopped bool
+}
+
+// NewPeriodicScheduler creates a scheduler enqueueing the configs of provider with client.
+func NewPeriodicScheduler(logg zerolog.Logger, client *Client, provider asynq.PeriodicTaskConfigProvider, syncInterval time.Duration) *PeriodicScheduler {
+	return &PeriodicScheduler{
+		client:   client,
+		provider: provider,
+		interval: syncInterval,
+		logg:     logg,
+		cron:     cron.New(),
+		entries:  make(map[string]cron.EntryID),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start performs the initial sync, then runs the entries and re-syncs in the background.
+// A failed initial sync is returned, so a broken table fails startup.
+func (s *PeriodicScheduler) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		return errors.New("periodic scheduler is shut down")
+	}
+	if err := s.sync(); err != nil {
+		return err
+	}
+	s.cron.Start()
+	go s.syncLoop()
+	s.started = true
+	return nil
+}
+
+// Shutdown stops syncing and scheduling, and waits for the enqueues in flight.
+func (s *PeriodicScheduler) Shutdown() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		return
+	}
+	s.stopped = true
+	close(s.stop)
+	if s.started {
+		<-s.done
+		<-s.cron.Stop().Done()
+	}
+}
+
+func (s *PeriodicScheduler) syncLoop() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			if err := s.sync(); err != nil {
+				s.logg.Error().Err(err).Msg("periodic task sync failed, keeping current entries")
+			}
+		}
+	}
+}
+
+// sync adds the configs the provider returns that are not scheduled yet, and removes
+// the entries it no longer returns. Unchanged entries keep their schedule.
+func (s *PeriodicScheduler) sync() error {
+	configs, err := s.provider.GetConfigs()
+	if err != nil {
+		return fmt.Errorf("failed to get periodic task configs: %w", err)
+	}
+
+	seen := make(map[string]bool, len(configs))
+	for _, cfg := range configs {
+		key := configHash(cfg)
+		seen[key] = true
+		if _, ok := s.entries[key]; ok {
+			continue
+		}
+		id, err := s.cron.AddFunc(cfg.Cronspec, s.enqueueFunc(cfg))
+		if err != nil {
+			return fmt.Errorf("failed to schedule %s (%s): %w", cfg.Task.Type(), cfg.Cronspec, err)
+		}
+		s.entries[key] = id
+	}
+	for key, id := range s.entries {
+		if !seen[key] {
+			s.cron.Remove(id)
+			delete(s.entries, key)
+		}
+	}
+	return nil
+}
+
+// enqueueFunc returns the cron job enqueueing cfg, logged like the asynq scheduler's enqueues.
+func (s *PeriodicScheduler) enqueueFunc(cfg *asynq.PeriodicTaskConfig) func() {
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), periodicEnqueueTimeout)
+		defer cancel()
+
+		info, err := s.client.enqueue(ctx, cfg.Task.Type(), cfg.Task.Payload(), cfg.Opts...)
+		if err != nil {
+			s.logg.Error().
+				Err(err).
+				Str("task_type", cfg.Task.Type()).
+				Msg("periodic task enqueue failed")
+			return
+		}
+		s.logg.Info().
+			Str("task_id", info.ID).
+			Str("task_type", info.Type).
+			Str("queue", info.Queue).
+			Msg("periodic task enqueued")
+	}
+}
+
+// configHash identifies a config by its schedule, task and options, like asynq's
+// PeriodicTaskManager does, so a changed row replaces its entry on the next sync.
+func configHash(cfg *asynq.PeriodicTaskConfig) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00", cfg.Cronspec, cfg.Task.Type())
+	h.Write(cfg.Task.Payload())
+	for _, opt := range cfg.Opts {
+		fmt.Fprintf(h, "\x00%s", opt.String())
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
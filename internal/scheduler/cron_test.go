@@ -0,0 +1,93 @@
+package scheduler
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hibiken/asynq"
+	"github.com/rs/zerolog"
+)
+
+// staticProvider serves configs, or err when it is set.
+type staticProvider struct {
+	configs []*asynq.PeriodicTaskConfig
+	err     error
+}
+
+func (p *staticProvider) GetConfigs() ([]*asynq.PeriodicTaskConfig, error) {
+	return p.configs, p.err
+}
+
+func periodicConfig(spec, payload string) *asynq.PeriodicTaskConfig {
+	return &asynq.PeriodicTaskConfig{
+		Cronspec: spec,
+		Task:     asynq.NewTask("test:periodic", []byte(payload)),
+		Opts:     []asynq.Option{asynq.Queue("default")},
+	}
+}
+
+func TestPeriodicSchedulerSync(t *testing.T) {
+	hourly := periodicConfig("CRON_TZ=UTC @hourly", `{"a":1}`)
+	daily := periodicConfig("CRON_TZ=UTC 0 3 * * *", "")
+	provider := &staticProvider{configs: []*asynq.PeriodicTaskConfig{hourly, daily}}
+	s := NewPeriodicScheduler(zerolog.Nop(), nil, provider, 0)
+
+	entryIDs := func() map[string]bool {
+		ids := make(map[string]bool)
+		for key := range s.entries {
+			ids[key] = true
+		}
+		if len(s.cron.Entries()) != len(s.entries) {
+			t.Fatalf("cron has %d entries, want %d", len(s.cron.Entries()), len(s.entries))
+		}
+		return ids
+	}
+
+	if err := s.sync(); err != nil {
+		t.Fatalf("sync() = %v", err)
+	}
+	first := entryIDs()
+	if len(first) != 2 {
+		t.Fatalf("entries = %d, want 2", len(first))
+	}
+
+	// Unchanged configs keep their entry, a changed one replaces it
+	provider.configs = []*asynq.PeriodicTaskConfig{hourly, periodicConfig("CRON_TZ=UTC 0 3 * * *", `{"b":2}`)}
+	if err := s.sync(); err != nil {
+		t.Fatalf("sync() = %v", err)
+	}
+	second := entryIDs()
+	if len(second) != 2 || !second[configHash(hourly)] || second[configHash(daily)] {
+		t.Errorf("entries after change = %v, want hourly kept and daily replaced", second)
+	}
+
+	// A failed sync keeps the current entries
+	provider.err = errors.New("database down")
+	if err := s.sync(); err == nil {
+		t.Error("sync() succeeded, want the provider error")
+	}
+	if got := entryIDs(); len(got) != 2 {
+		t.Errorf("entries after failed sync = %d, want 2", len(got))
+	}
+
+	// Removed configs are unscheduled
+	provider.err = nil
+	provider.configs = nil
+	if err := s.sync(); err != nil {
+		t.Fatalf("sync() = %v", err)
+	}
+	if got := entryIDs(); len(got) != 0 {
+		t.Errorf("entries after removal = %d, want 0", len(got))
+	}
+}
+
+func TestPeriodicSchedulerInvalidSpec(t *testing.T) {
+	provider := &staticProvider{configs: []*asynq.PeriodicTaskConfig{periodicConfig("every hour", "")}}
+	s := NewPeriodicScheduler(zerolog.Nop(), nil, provider, 0)
+	if err := s.Start(); err == nil {
+		s.Shutdown()
+		t.Fatal("Start() succeeded, want an invalid cronspec error")
+	}
+	// Shutdown after a failed start must not block
+	s.Shutdown()
+}
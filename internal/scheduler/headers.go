@@ -0,0 +1,71 @@
+package scheduler
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"boiler-go/internal/tracing"
+)
+
+const (
+	// HeaderEnqueuedAt is the task header carrying the time the task was enqueued (RFC 3339).
+	HeaderEnqueuedAt = "enqueued_at"
+	// HeaderProcessAt is the task header carrying the time a scheduled task becomes ready (RFC 3339).
+	HeaderProcessAt = "process_at"
+	// HeaderTaskID is the task header carrying the ID of a grouped task. asynq hands
+	// aggregators the tasks of a group without their IDs.
+	HeaderTaskID = "task_id"
+	// HeaderAggregatedTaskIDs is the task header listing the comma-separated IDs of the
+	// tasks aggregated into a task.
+	HeaderAggregatedTaskIDs = "aggregated_task_ids"
+)
+
+// TaskHeadersFrom returns the headers an enqueue made with ctx copies from it:
+// the correlation data and any WithTaskHeaders headers.
+func TaskHeadersFrom(ctx context.Context) map[string]string {
+	headers := tracing.TaskHeaders(ctx)
+	if headers == nil {
+		headers = make(map[string]string, 2)
+	}
+	if extra, ok := ctx.Value(taskHeadersCtxKey).(map[string]string); ok {
+		for k, v := range extra {
+			headers[k] = v
+		}
+	}
+	return headers
+}
+
+// taskHeaders builds the headers of an enqueued task: the headers of TaskHeadersFrom,
+// plus the enqueue time and, for scheduled tasks, the time they become ready.
+func taskHeaders(ctx context.Context, enqueuedAt, processAt time.Time) map[string]string {
+	headers := TaskHeadersFrom(ctx)
+	headers[HeaderEnqueuedAt] = enqueuedAt.UTC().Format(time.RFC3339Nano)
+	if processAt.After(enqueuedAt) {
+		headers[HeaderProcessAt] = processAt.UTC().Format(time.RFC3339Nano)
+	}
+	return headers
+}
+
+// AggregatedTaskIDs returns the IDs of the tasks aggregated into a task, if any.
+func AggregatedTaskIDs(headers map[string]string) []string {
+	ids := headers[HeaderAggregatedTaskIDs]
+	if ids == "" {
+		return nil
+	}
+	return strings.Split(ids, ",")
+}
+
+// ReadyAt returns the time a task became ready to be processed: its process_at time
+// for scheduled tasks, its enqueue time otherwise. It returns false for tasks enqueued
+// without a scheduler.Client, e.g. by the periodic scheduler.
+func ReadyAt(headers map[string]string) (time.Time, bool) {
+	readyAt, err := time.Parse(time.RFC3339Nano, headers[HeaderEnqueuedAt])
+	if err != nil {
+		return time.Time{}, false
+	}
+	if processAt, err := time.Parse(time.RFC3339Nano, headers[HeaderProcessAt]); err == nil && processAt.After(readyAt) {
+		readyAt = processAt
+	}
+	return readyAt, true
+}
@@ -0,0 +1,55 @@
+//go:build integration
+
+package scheduler
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"boiler-go/internal/dbtest"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestClientIdempotentEnqueue(t *testing.T) {
+	pool := dbtest.Pool(t)
+	var runs atomic.Int32
+	client := NewClientWithBackend(NewInlineBackend(asynq.HandlerFunc(func(context.Context, *asynq.Task) error {
+		runs.Add(1)
+		return nil
+	}), InlineConfig{}), pool)
+	defer client.Close()
+	ctx := WithIdempotencyKey(context.Background(), "key-1")
+
+	// Retained, so the second enqueue finds the first task in its queue
+	first, err := client.EnqueueWithID(ctx, "test:idempotent", nil, asynq.Retention(time.Hour))
+	if err != nil {
+		t.Fatalf("EnqueueWithID() = %v", err)
+	}
+	if first != idempotentTaskID("test:idempotent", "key-1") {
+		t.Errorf("task ID = %s, want the one derived from the key", first)
+	}
+	second, err := client.EnqueueWithID(ctx, "test:idempotent", nil, asynq.Retention(time.Hour))
+	if err != nil || second != first {
+		t.Errorf("second EnqueueWithID() = %s, %v; want task %s", second, err, first)
+	}
+	if runs.Load() != 1 {
+		t.Errorf("runs = %d, want 1", runs.Load())
+	}
+
+	// Without a key the same enqueue makes a new task
+	other, err := client.EnqueueWithID(context.Background(), "test:idempotent", nil)
+	if err != nil || other == first {
+		t.Errorf("EnqueueWithID() without a key = %s, %v; want a new task", other, err)
+	}
+
+	var jobs int
+	if err := pool.QueryRow(context.Background(), `SELECT count(*) FROM jobs`).Scan(&jobs); err != nil {
+		t.Fatal(err)
+	}
+	if jobs != 2 {
+		t.Errorf("jobs = %d, want 2", jobs)
+	}
+}
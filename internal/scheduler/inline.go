@@ -0,0 +1,290 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+// ErrBackendClosed is returned when enqueueing on a closed InlineBackend.
+var ErrBackendClosed = errors.New("queue backend is closed")
+
+// InlineConfig configures an InlineBackend. Fields mirror asynq.Config.
+type InlineConfig struct {
+	// Concurrency is the number of tasks run at once on background goroutines.
+	// Zero runs each task on the goroutine calling Enqueue, before Enqueue returns.
+	Concurrency    int
+	RetryDelayFunc asynq.RetryDelayFunc
+	IsFailure      func(error) bool
+	ErrorHandler   asynq.ErrorHandler
+}
+
+// InlineBackend runs tasks in-process with an asynq.Handler instead of queueing them,
+// for development and tests without Redis or a worker process. Tasks are kept in memory:
+// outcomes, retries, timeouts and retention follow asynq, and the task state is
+// available through GetTaskInfo.
+//
+// Retries, and tasks enqueued with ProcessAt or ProcessIn, run on their own goroutine
+// once due, even when Concurrency is zero.
+type InlineBackend struct {
+	handler asynq.Handler
+	cfg     InlineConfig
+	// sem bounds the tasks running at once; nil when tasks run synchronously
+	sem chan struct{}
+
+	mu     sync.Mutex
+	tasks  map[string]*inlineTask
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// inlineTask is a task held by an InlineBackend. Its fields are guarded by the backend's mutex.
+type inlineTask struct {
+	task   *asynq.Task
+	info   asynq.TaskInfo
+	timer  *time.Timer
+	cancel context.CancelFunc
+}
+
+// NewInlineBackend returns a backend running tasks with handler, usually the
+// asynq.ServeMux the worker serves.
+func NewInlineBackend(handler asynq.Handler, cfg InlineConfig) *InlineBackend {
+	if cfg.RetryDelayFunc == nil {
+		cfg.RetryDelayFunc = asynq.DefaultRetryDelayFunc
+	}
+	if cfg.IsFailure == nil {
+		cfg.IsFailure = func(error) bool { return true }
+	}
+	b := &InlineBackend{
+		handler: handler,
+		cfg:     cfg,
+		tasks:   make(map[string]*inlineTask),
+	}
+	if cfg.Concurrency > 0 {
+		b.sem = make(chan struct{}, cfg.Concurrency)
+	}
+	return b
+}
+
+func (b *InlineBackend) Enqueue(_ context.Context, task *asynq.Task, opts ...asynq.Option) (*asynq.TaskInfo, error) {
+	now := time.Now()
+	o, err := parseOptions(opts, now)
+	if err != nil {
+		return nil, fmt.Errorf("inline queue mode: %w", err)
+	}
+
+	t := &inlineTask{
+		task: task,
+		info: asynq.TaskInfo{
+			ID:            o.taskID,
+			Queue:         o.queue,
+			Type:          task.Type(),
+			Payload:       task.Payload(),
+			Headers:       task.Headers(),
+			State:         asynq.TaskStatePending,
+			MaxRetry:      o.maxRetry,
+			Timeout:       o.timeout,
+			Deadline:      o.deadline,
+			NextProcessAt: o.processAt,
+			Retention:     o.retention,
+		},
+	}
+	delay := o.processAt.Sub(now)
+	if delay > 0 {
+		t.info.State = asynq.TaskStateScheduled
+	}
+
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil, ErrBackendClosed
+	}
+	if _, ok := b.tasks[o.taskID]; ok {
+		b.mu.Unlock()
+		return nil, asynq.ErrTaskIDConflict
+	}
+	b.tasks[o.taskID] = t
+	info := t.info
+	switch {
+	case delay > 0:
+		b.scheduleLocked(t, delay)
+	case b.sem != nil:
+		b.wg.Add(1)
+		go b.run(t)
+	default:
+		b.wg.Add(1)
+	}
+	b.mu.Unlock()
+
+	if delay <= 0 && b.sem == nil {
+		b.run(t)
+	}
+	return &info, nil
+}
+
+// scheduleLocked runs t on its own goroutine after delay.
+func (b *InlineBackend) scheduleLocked(t *inlineTask, delay time.Duration) {
+	t.timer = time.AfterFunc(delay, func() {
+		b.mu.Lock()
+		if b.closed || b.tasks[t.info.ID] != t {
+			b.mu.Unlock()
+			return
+		}
+		t.timer = nil
+		b.wg.Add(1)
+		b.mu.Unlock()
+		b.run(t)
+	})
+}
+
+// run processes one attempt of t and records its outcome like asynq does, except that
+// errors that are not failures are retried even on the last attempt.
+func (b *InlineBackend) run(t *inlineTask) {
+	defer b.wg.Done()
+	if b.sem != nil {
+		b.sem <- struct{}{}
+		defer func() { <-b.sem }()
+	}
+
+	b.mu.Lock()
+	// Deleted while it waited for a free goroutine
+	if b.tasks[t.info.ID] != t {
+		b.mu.Unlock()
+		return
+	}
+	t.info.State = asynq.TaskStateActive
+	t.info.NextProcessAt = time.Time{}
+	retried, maxRetry := t.info.Retried, t.info.MaxRetry
+	ctx, cancel := taskContext(&taskMetadata{
+		id:       t.info.ID,
+		queue:    t.info.Queue,
+		retried:  retried,
+		maxRetry: maxRetry,
+		writeResult: func(_ context.Context, data []byte) error {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			t.info.Result = append([]byte(nil), data...)
+			return nil
+		},
+	}, t.info.Timeout, t.info.Deadline)
+	t.cancel = cancel
+	b.mu.Unlock()
+
+	err := perform(ctx, b.handler, t.task)
+	// asynq fails a task whose context ends before its handler returns
+	if err == nil && ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	cancel()
+
+	if err != nil && b.cfg.ErrorHandler != nil {
+		b.cfg.ErrorHandler.HandleError(ctx, t.task, err)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	t.cancel = nil
+	now := time.Now()
+	switch {
+	case err == nil:
+		t.info.State = asynq.TaskStateCompleted
+		t.info.CompletedAt = now
+		// asynq deletes completed tasks right away unless they have a retention
+		if t.info.Retention <= 0 {
+			delete(b.tasks, t.info.ID)
+			return
+		}
+		t.timer = time.AfterFunc(t.info.Retention, func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			if b.tasks[t.info.ID] == t {
+				delete(b.tasks, t.info.ID)
+			}
+		})
+	case errors.Is(err, asynq.RevokeTask):
+		delete(b.tasks, t.info.ID)
+	case b.cfg.IsFailure(err) && (retried >= maxRetry || errors.Is(err, asynq.SkipRetry)):
+		t.info.State = asynq.TaskStateArchived
+		t.info.LastErr = err.Error()
+		t.info.LastFailedAt = now
+	default:
+		// Errors that are not failures, see IsFailure, are retried even on the last
+		// attempt, without counting against MaxRetry
+		if b.cfg.IsFailure(err) {
+			t.info.Retried++
+		}
+		delay := b.cfg.RetryDelayFunc(retried, err, t.task)
+		t.info.State = asynq.TaskStateRetry
+		t.info.LastErr = err.Error()
+		t.info.LastFailedAt = now
+		t.info.NextProcessAt = now.Add(delay)
+		if !b.closed {
+			b.scheduleLocked(t, delay)
+		}
+	}
+}
+
+func (b *InlineBackend) GetTaskInfo(_ context.Context, queueName, taskID string) (*asynq.TaskInfo, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	t, ok := b.tasks[taskID]
+	if !ok || t.info.Queue != queueName {
+		return nil, asynq.ErrTaskNotFound
+	}
+	info := t.info
+	return &info, nil
+}
+
+func (b *InlineBackend) DeleteTask(_ context.Context, queueName, taskID string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	t, ok := b.tasks[taskID]
+	if !ok || t.info.Queue != queueName {
+		return asynq.ErrTaskNotFound
+	}
+	if t.info.State == asynq.TaskStateActive {
+		return fmt.Errorf("cannot delete task %s: task is active", taskID)
+	}
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+	delete(b.tasks, taskID)
+	return nil
+}
+
+func (b *InlineBackend) CancelProcessing(_ context.Context, taskID string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	t, ok := b.tasks[taskID]
+	if !ok {
+		return asynq.ErrTaskNotFound
+	}
+	if t.cancel == nil {
+		return fmt.Errorf("cannot cancel task %s: task is not active", taskID)
+	}
+	t.cancel()
+	return nil
+}
+
+// Close drops the tasks that are not due yet, and waits for the running ones.
+func (b *InlineBackend) Close() error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	for _, t := range b.tasks {
+		if t.timer != nil {
+			t.timer.Stop()
+		}
+	}
+	b.mu.Unlock()
+
+	b.wg.Wait()
+	return nil
+}
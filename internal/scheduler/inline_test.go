@@ -0,0 +1,198 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+)
+
+// newTestInlineBackend returns a synchronous backend whose retries are due in an hour,
+// so they stay in the retry state during the test.
+func newTestInlineBackend(t *testing.T, handler asynq.HandlerFunc, cfg InlineConfig) *InlineBackend {
+	t.Helper()
+	if cfg.RetryDelayFunc == nil {
+		cfg.RetryDelayFunc = func(int, error, *asynq.Task) time.Duration { return time.Hour }
+	}
+	if cfg.IsFailure == nil {
+		cfg.IsFailure = func(err error) bool { return !errors.Is(err, errInlineNotFailure) }
+	}
+	b := NewInlineBackend(handler, cfg)
+	t.Cleanup(func() { b.Close() })
+	return b
+}
+
+var errInlineNotFailure = errors.New("not a failure")
+
+func TestInlineBackendOutcomes(t *testing.T) {
+	failed := errors.New("failed")
+	tests := []struct {
+		name        string
+		opts        []asynq.Option
+		err         error
+		wantState   asynq.TaskState // zero when the task is deleted
+		wantRetried int
+	}{
+		{name: "completed"},
+		{name: "completed with retention", opts: []asynq.Option{asynq.Retention(time.Hour)}, wantState: asynq.TaskStateCompleted},
+		{name: "failed with retries left", opts: []asynq.Option{asynq.MaxRetry(3)}, err: failed, wantState: asynq.TaskStateRetry, wantRetried: 1},
+		{name: "failed on last attempt", opts: []asynq.Option{asynq.MaxRetry(0)}, err: failed, wantState: asynq.TaskStateArchived},
+		{name: "skip retry", opts: []asynq.Option{asynq.MaxRetry(3)}, err: fmt.Errorf("bad payload: %w", asynq.SkipRetry), wantState: asynq.TaskStateArchived},
+		{name: "revoked", opts: []asynq.Option{asynq.MaxRetry(3)}, err: asynq.RevokeTask},
+		{name: "not a failure with retries left", opts: []asynq.Option{asynq.MaxRetry(3)}, err: errInlineNotFailure, wantState: asynq.TaskStateRetry},
+		{name: "not a failure on last attempt", opts: []asynq.Option{asynq.MaxRetry(0)}, err: errInlineNotFailure, wantState: asynq.TaskStateRetry},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var handled []error
+			b := newTestInlineBackend(t, func(context.Context, *asynq.Task) error { return tt.err }, InlineConfig{
+				ErrorHandler: asynq.ErrorHandlerFunc(func(_ context.Context, _ *asynq.Task, err error) {
+					handled = append(handled, err)
+				}),
+			})
+			ctx := context.Background()
+
+			taskID := uuid.NewString()
+			opts := append([]asynq.Option{asynq.TaskID(taskID)}, tt.opts...)
+			if _, err := b.Enqueue(ctx, asynq.NewTask("test:outcome", nil), opts...); err != nil {
+				t.Fatalf("Enqueue() = %v", err)
+			}
+			// Like asynq, every error goes to the ErrorHandler, RevokeTask included
+			if (tt.err != nil) != (len(handled) == 1) {
+				t.Errorf("ErrorHandler called with %v, want %v", handled, tt.err)
+			}
+
+			info, err := b.GetTaskInfo(ctx, "default", taskID)
+			if tt.wantState == 0 {
+				if !errors.Is(err, asynq.ErrTaskNotFound) {
+					t.Fatalf("GetTaskInfo() = %+v, %v; want the task deleted", info, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetTaskInfo() = %v", err)
+			}
+			if info.State != tt.wantState || info.Retried != tt.wantRetried {
+				t.Errorf("task = %s retried %d, want %s retried %d", info.State, info.Retried, tt.wantState, tt.wantRetried)
+			}
+			if tt.err != nil && info.LastErr == "" {
+				t.Error("last error not recorded")
+			}
+		})
+	}
+}
+
+func TestInlineBackendResult(t *testing.T) {
+	b := newTestInlineBackend(t, func(ctx context.Context, task *asynq.Task) error {
+		return WriteResult(ctx, task, []byte("done"))
+	}, InlineConfig{})
+	ctx := context.Background()
+
+	info, err := b.Enqueue(ctx, asynq.NewTask("test:result", nil), asynq.TaskID("a"), asynq.Retention(time.Hour))
+	if err != nil {
+		t.Fatalf("Enqueue() = %v", err)
+	}
+	if info.State != asynq.TaskStatePending {
+		t.Errorf("enqueued state = %s, want pending", info.State)
+	}
+	info, err = b.GetTaskInfo(ctx, "default", "a")
+	if err != nil || info.State != asynq.TaskStateCompleted || string(info.Result) != "done" {
+		t.Fatalf("GetTaskInfo() = %+v, %v; want completed with its result", info, err)
+	}
+
+	// A retained task keeps its ID
+	if _, err := b.Enqueue(ctx, asynq.NewTask("test:result", nil), asynq.TaskID("a")); !errors.Is(err, asynq.ErrTaskIDConflict) {
+		t.Errorf("Enqueue() with a retained ID = %v, want %v", err, asynq.ErrTaskIDConflict)
+	}
+	if _, err := b.GetTaskInfo(ctx, "critical", "a"); !errors.Is(err, asynq.ErrTaskNotFound) {
+		t.Errorf("GetTaskInfo() in another queue = %v, want %v", err, asynq.ErrTaskNotFound)
+	}
+}
+
+func TestInlineBackendScheduled(t *testing.T) {
+	var runs atomic.Int32
+	b := newTestInlineBackend(t, func(context.Context, *asynq.Task) error {
+		runs.Add(1)
+		return nil
+	}, InlineConfig{})
+	ctx := context.Background()
+
+	info, err := b.Enqueue(ctx, asynq.NewTask("test:scheduled", nil), asynq.TaskID("later"), asynq.ProcessIn(time.Hour))
+	if err != nil {
+		t.Fatalf("Enqueue() = %v", err)
+	}
+	if info.State != asynq.TaskStateScheduled || runs.Load() != 0 {
+		t.Fatalf("task = %s after %d runs, want scheduled and not run", info.State, runs.Load())
+	}
+	if err := b.DeleteTask(ctx, "default", "later"); err != nil {
+		t.Fatalf("DeleteTask() = %v", err)
+	}
+	if _, err := b.GetTaskInfo(ctx, "default", "later"); !errors.Is(err, asynq.ErrTaskNotFound) {
+		t.Errorf("GetTaskInfo() after delete = %v, want %v", err, asynq.ErrTaskNotFound)
+	}
+
+	// Due tasks run on their own goroutine
+	if _, err := b.Enqueue(ctx, asynq.NewTask("test:scheduled", nil), asynq.TaskID("soon"), asynq.ProcessIn(time.Millisecond)); err != nil {
+		t.Fatalf("Enqueue() = %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for runs.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if runs.Load() != 1 {
+		t.Errorf("runs = %d, want 1", runs.Load())
+	}
+}
+
+func TestInlineBackendCancelProcessing(t *testing.T) {
+	started := make(chan struct{})
+	b := newTestInlineBackend(t, func(ctx context.Context, _ *asynq.Task) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	}, InlineConfig{Concurrency: 1})
+	ctx := context.Background()
+
+	if _, err := b.Enqueue(ctx, asynq.NewTask("test:cancel", nil), asynq.TaskID("a"), asynq.MaxRetry(3)); err != nil {
+		t.Fatalf("Enqueue() = %v", err)
+	}
+	<-started
+	if err := b.DeleteTask(ctx, "default", "a"); err == nil {
+		t.Error("DeleteTask() of an active task succeeded")
+	}
+	if err := b.CancelProcessing(ctx, "a"); err != nil {
+		t.Fatalf("CancelProcessing() = %v", err)
+	}
+	b.Close()
+
+	// A canceled task is retried, like with asynq
+	info, err := b.GetTaskInfo(ctx, "default", "a")
+	if err != nil || info.State != asynq.TaskStateRetry || info.Retried != 1 {
+		t.Errorf("GetTaskInfo() = %+v, %v; want a retry", info, err)
+	}
+	if _, err := b.Enqueue(ctx, asynq.NewTask("test:cancel", nil)); !errors.Is(err, ErrBackendClosed) {
+		t.Errorf("Enqueue() after Close = %v, want %v", err, ErrBackendClosed)
+	}
+}
+
+func TestInlineBackendTimeout(t *testing.T) {
+	b := newTestInlineBackend(t, func(ctx context.Context, _ *asynq.Task) error {
+		<-ctx.Done()
+		return nil
+	}, InlineConfig{})
+	ctx := context.Background()
+
+	// A handler that returns nil after its context ended still fails the attempt
+	if _, err := b.Enqueue(ctx, asynq.NewTask("test:timeout", nil), asynq.TaskID("a"), asynq.Timeout(time.Millisecond), asynq.MaxRetry(0)); err != nil {
+		t.Fatalf("Enqueue() = %v", err)
+	}
+	info, err := b.GetTaskInfo(ctx, "default", "a")
+	if err != nil || info.State != asynq.TaskStateArchived || info.LastErr != context.DeadlineExceeded.Error() {
+		t.Errorf("GetTaskInfo() = %+v, %v; want archived after its timeout", info, err)
+	}
+}
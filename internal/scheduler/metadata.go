@@ -0,0 +1,78 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+
+	"github.com/hibiken/asynq"
+)
+
+// ErrNoResultWriter is returned by WriteResult when a task is not being processed by a backend server.
+var ErrNoResultWriter = errors.New("task has no result writer")
+
+// taskMetadata describes the task being processed by a PostgresServer, which cannot
+// use asynq's own context values.
+type taskMetadata struct {
+	id          string
+	queue       string
+	retried     int
+	maxRetry    int
+	writeResult func(context.Context, []byte) error
+}
+
+type metadataCtxKey struct{}
+
+func withTaskMetadata(ctx context.Context, md *taskMetadata) context.Context {
+	return context.WithValue(ctx, metadataCtxKey{}, md)
+}
+
+func metadataFrom(ctx context.Context) (*taskMetadata, bool) {
+	md, ok := ctx.Value(metadataCtxKey{}).(*taskMetadata)
+	return md, ok
+}
+
+// The accessors below work with every backend; use them instead of asynq.GetTaskID and friends.
+
+// TaskID returns the ID of the task being processed.
+func TaskID(ctx context.Context) (string, bool) {
+	if md, ok := metadataFrom(ctx); ok {
+		return md.id, true
+	}
+	return asynq.GetTaskID(ctx)
+}
+
+// QueueName returns the queue of the task being processed.
+func QueueName(ctx context.Context) (string, bool) {
+	if md, ok := metadataFrom(ctx); ok {
+		return md.queue, true
+	}
+	return asynq.GetQueueName(ctx)
+}
+
+// RetryCount returns how many times the task being processed has been retried.
+func RetryCount(ctx context.Context) (int, bool) {
+	if md, ok := metadataFrom(ctx); ok {
+		return md.retried, true
+	}
+	return asynq.GetRetryCount(ctx)
+}
+
+// MaxRetry returns how many times the task being processed can be retried.
+func MaxRetry(ctx context.Context) (int, bool) {
+	if md, ok := metadataFrom(ctx); ok {
+		return md.maxRetry, true
+	}
+	return asynq.GetMaxRetry(ctx)
+}
+
+// WriteResult stores data as the result of the task t being processed with ctx.
+func WriteResult(ctx context.Context, t *asynq.Task, data []byte) error {
+	if rw := t.ResultWriter(); rw != nil {
+		_, err := rw.Write(data)
+		return err
+	}
+	if md, ok := metadataFrom(ctx); ok && md.writeResult != nil {
+		return md.writeResult(ctx, data)
+	}
+	return ErrNoResultWriter
+}
@@ -0,0 +1,85 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"boiler-go/internal/queue"
+
+	"github.com/hibiken/asynq"
+	"github.com/robfig/cron/v3"
+)
+
+// PeriodicTask declares a task that is enqueued on a cron schedule.
+type PeriodicTask struct {
+	// Cronspec is a standard 5-field cron expression or a descriptor such as "@every 5m".
+	Cronspec string
+	// TaskType is the asynq task type to enqueue.
+	TaskType string
+	// Payload is JSON-encoded into the task payload. Nil means an empty payload.
+	Payload any
+	// Queue overrides any queue set in Opts. Empty keeps the Opts (or asynq default) queue.
+	Queue string
+	// Timezone is the IANA location the cronspec is evaluated in. Empty means UTC.
+	Timezone string
+	// Opts are additional asynq options, e.g. the registered task defaults.
+	Opts []asynq.Option
+}
+
+// Validate checks the cronspec, queue and timezone without registering the task.
+func (p PeriodicTask) Validate() error {
+	if p.TaskType == "" {
+		return fmt.Errorf("task type is required")
+	}
+	if p.Queue != "" && !queue.Valid(p.Queue) {
+		return fmt.Errorf("unknown queue %q", p.Queue)
+	}
+	if _, err := time.LoadLocation(p.timezone()); err != nil {
+		return fmt.Errorf("invalid timezone %q: %w", p.Timezone, err)
+	}
+	if _, err := cron.ParseStandard(p.spec()); err != nil {
+		return fmt.Errorf("invalid cronspec %q: %w", p.Cronspec, err)
+	}
+	return nil
+}
+
+// Config converts the periodic task into an asynq scheduler entry.
+func (p PeriodicTask) Config() (*asynq.PeriodicTaskConfig, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	var payload []byte
+	if p.Payload != nil {
+		data, err := json.Marshal(p.Payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal %s payload: %w", p.TaskType, err)
+		}
+		payload = data
+	}
+
+	opts := append([]asynq.Option{}, p.Opts...)
+	if p.Queue != "" {
+		opts = append(opts, asynq.Queue(p.Queue))
+	}
+
+	return &asynq.PeriodicTaskConfig{
+		Cronspec: p.spec(),
+		Task:     asynq.NewTask(p.TaskType, payload),
+		Opts:     opts,
+	}, nil
+}
+
+// spec prefixes the cronspec with CRON_TZ so each entry keeps its own timezone,
+// independent of the scheduler-wide location.
+func (p PeriodicTask) spec() string {
+	return "CRON_TZ=" + p.timezone() + " " + p.Cronspec
+}
+
+func (p PeriodicTask) timezone() string {
+	if p.Timezone == "" {
+		return "UTC"
+	}
+	return p.Timezone
+}
@@ -0,0 +1,250 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"boiler-go/internal/db"
+	"boiler-go/internal/queue"
+
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Defaults matching asynq's own.
+const (
+	defaultMaxRetry = 25
+	defaultTimeout  = 30 * time.Minute
+)
+
+// Task states stored in queue_tasks, named like asynq.TaskState values.
+const (
+	statePending   = "pending"
+	stateScheduled = "scheduled"
+	stateActive    = "active"
+	stateRetry     = "retry"
+	stateArchived  = "archived"
+	stateCompleted = "completed"
+)
+
+// PostgresBackend stores tasks in the queue_tasks table. Its tasks are processed by a PostgresServer.
+type PostgresBackend struct {
+	queries *db.Queries
+}
+
+// NewPostgresBackend returns a backend storing tasks in the queue_tasks table of pool.
+func NewPostgresBackend(pool *pgxpool.Pool) *PostgresBackend {
+	return &PostgresBackend{queries: db.New(pool)}
+}
+
+// taskOptions are the asynq options supported by the Postgres backend.
+type taskOptions struct {
+	taskID    string
+	queue     string
+	maxRetry  int
+	timeout   time.Duration
+	deadline  time.Time
+	processAt time.Time
+	retention time.Duration
+}
+
+// parseOptions resolves opts like asynq does: later options win, and tasks without
+// a timeout or deadline get asynq's default timeout.
+func parseOptions(opts []asynq.Option, now time.Time) (taskOptions, error) {
+	o := taskOptions{queue: queue.QueueDefault, maxRetry: defaultMaxRetry, processAt: now}
+	for _, opt := range opts {
+		switch opt.Type() {
+		case asynq.TaskIDOpt:
+			o.taskID, _ = opt.Value().(string)
+		case asynq.QueueOpt:
+			o.queue, _ = opt.Value().(string)
+		case asynq.MaxRetryOpt:
+			o.maxRetry, _ = opt.Value().(int)
+		case asynq.TimeoutOpt:
+			o.timeout, _ = opt.Value().(time.Duration)
+		case asynq.DeadlineOpt:
+			o.deadline, _ = opt.Value().(time.Time)
+		case asynq.ProcessAtOpt:
+			o.processAt, _ = opt.Value().(time.Time)
+		case asynq.ProcessInOpt:
+			d, _ := opt.Value().(time.Duration)
+			o.processAt = now.Add(d)
+		case asynq.RetentionOpt:
+			o.retention, _ = opt.Value().(time.Duration)
+		default:
+			return taskOptions{}, fmt.Errorf("asynq option %s: %w", opt.String(), ErrUnsupported)
+		}
+	}
+	o.maxRetry = max(o.maxRetry, 0)
+	if o.timeout == 0 && o.deadline.IsZero() {
+		o.timeout = defaultTimeout
+	}
+	return o, nil
+}
+
+func (b *PostgresBackend) Enqueue(ctx context.Context, task *asynq.Task, opts ...asynq.Option) (*asynq.TaskInfo, error) {
+	now := time.Now()
+	o, err := parseOptions(opts, now)
+	if err != nil {
+		return nil, fmt.Errorf("postgres queue backend: %w", err)
+	}
+	id, err := db.JobID(o.taskID)
+	if err != nil {
+		return nil, fmt.Errorf("task ID %q is not a valid UUID: %w", o.taskID, err)
+	}
+	headers, err := json.Marshal(task.Headers())
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode task headers: %w", err)
+	}
+
+	state := statePending
+	if o.processAt.After(now) {
+		state = stateScheduled
+	}
+	params := db.CreateQueueTaskParams{
+		ID:          id,
+		Queue:       o.queue,
+		TaskType:    task.Type(),
+		Payload:     task.Payload(),
+		Headers:     headers,
+		State:       state,
+		MaxRetry:    int32(o.maxRetry),
+		TimeoutMs:   o.timeout.Milliseconds(),
+		RetentionMs: o.retention.Milliseconds(),
+		RunAt:       pgtype.Timestamptz{Time: o.processAt, Valid: true},
+	}
+	if !o.deadline.IsZero() {
+		params.Deadline = pgtype.Timestamptz{Time: o.deadline, Valid: true}
+	}
+
+	rows, err := b.queries.CreateQueueTask(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store task: %w", err)
+	}
+	if rows == 0 {
+		return nil, asynq.ErrTaskIDConflict
+	}
+
+	return &asynq.TaskInfo{
+		ID:            o.taskID,
+		Queue:         o.queue,
+		Type:          task.Type(),
+		Payload:       task.Payload(),
+		Headers:       task.Headers(),
+		State:         taskState(state),
+		MaxRetry:      o.maxRetry,
+		Timeout:       o.timeout,
+		Deadline:      o.deadline,
+		NextProcessAt: o.processAt,
+		Retention:     o.retention,
+	}, nil
+}
+
+func (b *PostgresBackend) GetTaskInfo(ctx context.Context, queueName, taskID string) (*asynq.TaskInfo, error) {
+	id, err := db.JobID(taskID)
+	if err != nil {
+		return nil, asynq.ErrTaskNotFound
+	}
+	t, err := b.queries.GetQueueTask(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && t.Queue != queueName) {
+		return nil, asynq.ErrTaskNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load task: %w", err)
+	}
+	return taskInfo(t), nil
+}
+
+func (b *PostgresBackend) DeleteTask(ctx context.Context, queueName, taskID string) error {
+	info, err := b.GetTaskInfo(ctx, queueName, taskID)
+	if err != nil {
+		return err
+	}
+	if info.State == asynq.TaskStateActive {
+		return fmt.Errorf("cannot delete task %s: task is active", taskID)
+	}
+
+	id, _ := db.JobID(taskID)
+	rows, err := b.queries.DeleteInactiveQueueTask(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete task: %w", err)
+	}
+	if rows == 0 {
+		// Claimed since it was loaded
+		return fmt.Errorf("cannot delete task %s: task is active", taskID)
+	}
+	return nil
+}
+
+func (b *PostgresBackend) CancelProcessing(ctx context.Context, taskID string) error {
+	id, err := db.JobID(taskID)
+	if err != nil {
+		return asynq.ErrTaskNotFound
+	}
+	rows, err := b.queries.RequestQueueTaskCancel(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to request task cancellation: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("cannot cancel task %s: task is not active", taskID)
+	}
+	return nil
+}
+
+func (b *PostgresBackend) Close() error {
+	return nil
+}
+
+// taskInfo converts a queue_tasks row to the asynq representation used by the API.
+func taskInfo(t db.QueueTask) *asynq.TaskInfo {
+	var headers map[string]string
+	_ = json.Unmarshal(t.Headers, &headers)
+
+	info := &asynq.TaskInfo{
+		ID:           t.ID.String(),
+		Queue:        t.Queue,
+		Type:         t.TaskType,
+		Payload:      t.Payload,
+		Headers:      headers,
+		State:        taskState(t.State),
+		MaxRetry:     int(t.MaxRetry),
+		Retried:      int(t.Retried),
+		LastErr:      t.LastError.String,
+		LastFailedAt: t.LastFailedAt.Time,
+		Timeout:      time.Duration(t.TimeoutMs) * time.Millisecond,
+		Deadline:     t.Deadline.Time,
+		Retention:    time.Duration(t.RetentionMs) * time.Millisecond,
+		CompletedAt:  t.CompletedAt.Time,
+		Result:       t.Result,
+	}
+	switch t.State {
+	case statePending, stateScheduled, stateRetry:
+		info.NextProcessAt = t.RunAt.Time
+	}
+	if t.State == stateScheduled && !t.RunAt.Time.After(time.Now()) {
+		info.State = asynq.TaskStatePending
+	}
+	return info
+}
+
+func taskState(state string) asynq.TaskState {
+	switch state {
+	case stateScheduled:
+		return asynq.TaskStateScheduled
+	case stateActive:
+		return asynq.TaskStateActive
+	case stateRetry:
+		return asynq.TaskStateRetry
+	case stateArchived:
+		return asynq.TaskStateArchived
+	case stateCompleted:
+		return asynq.TaskStateCompleted
+	default:
+		return asynq.TaskStatePending
+	}
+}
@@ -0,0 +1,203 @@
+//go:build integration
+
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"boiler-go/internal/db"
+	"boiler-go/internal/dbtest"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
+)
+
+func TestPeriodicSchedulerEnqueuesToPostgres(t *testing.T) {
+	pool := dbtest.Pool(t)
+	client := NewClientWithBackend(NewPostgresBackend(pool), pool)
+	s := NewPeriodicScheduler(zerolog.Nop(), client, &staticProvider{}, 0)
+
+	s.enqueueFunc(periodicConfig("@hourly", `{"a":1}`))()
+
+	var id, queueName, payload, status string
+	err := pool.QueryRow(context.Background(),
+		`SELECT t.id::text, t.queue, convert_from(t.payload, 'UTF8'), j.status
+		FROM queue_tasks t JOIN jobs j ON j.id = t.id
+		WHERE t.task_type = 'test:periodic'`,
+	).Scan(&id, &queueName, &payload, &status)
+	if err != nil {
+		t.Fatalf("periodic task not queued: %v", err)
+	}
+	if queueName != "default" || payload != `{"a":1}` || status != "pending" {
+		t.Errorf("task %s = queue %q, payload %q, job %q; want default, {\"a\":1}, pending", id, queueName, payload, status)
+	}
+	info, err := client.TaskInfo(context.Background(), queueName, id)
+	if err != nil || info.State != asynq.TaskStatePending {
+		t.Errorf("TaskInfo() = %+v, %v; want a pending task", info, err)
+	}
+}
+
+// errNotFailure is an error the test servers do not count as a failure, like a rate limit.
+var errNotFailure = errors.New("not a failure")
+
+func newTestServer(pool *pgxpool.Pool) *PostgresServer {
+	return NewPostgresServer(pool, PostgresServerConfig{
+		Queues:            map[string]int{"default": 1},
+		VisibilityTimeout: time.Minute,
+		RetryDelayFunc:    func(int, error, *asynq.Task) time.Duration { return time.Hour },
+		IsFailure:         func(err error) bool { return !errors.Is(err, errNotFailure) },
+		Logger:            zerolog.Nop(),
+	})
+}
+
+// claim leases the next due task of the default queue, like a server goroutine does.
+func claim(t *testing.T, s *PostgresServer) db.QueueTask {
+	t.Helper()
+	task, err := s.queries.ClaimQueueTask(context.Background(), db.ClaimQueueTaskParams{
+		LeaseSeconds: s.cfg.VisibilityTimeout.Seconds(),
+		LeaseToken:   pgtype.UUID{Bytes: uuid.New(), Valid: true},
+		Queues:       []string{"default"},
+	})
+	if err != nil {
+		t.Fatalf("ClaimQueueTask() = %v", err)
+	}
+	return task
+}
+
+func TestPostgresServerOutcomes(t *testing.T) {
+	failed := errors.New("failed")
+	tests := []struct {
+		name        string
+		opts        []asynq.Option
+		err         error
+		wantState   string // empty when the task is deleted
+		wantRetried int32
+	}{
+		{name: "completed", wantState: ""},
+		{name: "completed with retention", opts: []asynq.Option{asynq.Retention(time.Hour)}, wantState: stateCompleted},
+		{name: "failed with retries left", opts: []asynq.Option{asynq.MaxRetry(3)}, err: failed, wantState: stateRetry, wantRetried: 1},
+		{name: "failed on last attempt", opts: []asynq.Option{asynq.MaxRetry(0)}, err: failed, wantState: stateArchived},
+		{name: "skip retry", opts: []asynq.Option{asynq.MaxRetry(3)}, err: fmt.Errorf("bad payload: %w", asynq.SkipRetry), wantState: stateArchived},
+		{name: "revoked", opts: []asynq.Option{asynq.MaxRetry(3)}, err: asynq.RevokeTask, wantState: ""},
+		{name: "not a failure with retries left", opts: []asynq.Option{asynq.MaxRetry(3)}, err: errNotFailure, wantState: stateRetry},
+		{name: "not a failure on last attempt", opts: []asynq.Option{asynq.MaxRetry(0)}, err: errNotFailure, wantState: stateRetry},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := dbtest.Pool(t)
+			backend := NewPostgresBackend(pool)
+			s := newTestServer(pool)
+			ctx := context.Background()
+
+			taskID := uuid.NewString()
+			opts := append([]asynq.Option{asynq.TaskID(taskID)}, tt.opts...)
+			if _, err := backend.Enqueue(ctx, asynq.NewTask("test:outcome", nil), opts...); err != nil {
+				t.Fatalf("Enqueue() = %v", err)
+			}
+
+			s.process(asynq.HandlerFunc(func(context.Context, *asynq.Task) error {
+				return tt.err
+			}), claim(t, s))
+
+			id, _ := db.JobID(taskID)
+			row, err := s.queries.GetQueueTask(ctx, id)
+			if tt.wantState == "" {
+				if !errors.Is(err, pgx.ErrNoRows) {
+					t.Fatalf("task = %s, %v; want it deleted", row.State, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetQueueTask() = %v", err)
+			}
+			if row.State != tt.wantState || row.Retried != tt.wantRetried {
+				t.Errorf("task = %s retried %d, want %s retried %d", row.State, row.Retried, tt.wantState, tt.wantRetried)
+			}
+			if tt.err != nil && tt.wantState != "" && row.LastError.String == "" {
+				t.Error("last error not recorded")
+			}
+			if row.LeaseToken.Valid {
+				t.Error("lease token not cleared")
+			}
+		})
+	}
+}
+
+func TestPostgresServerLostLease(t *testing.T) {
+	pool := dbtest.Pool(t)
+	backend := NewPostgresBackend(pool)
+	s := newTestServer(pool)
+	ctx := context.Background()
+
+	taskID := uuid.NewString()
+	if _, err := backend.Enqueue(ctx, asynq.NewTask("test:lease", nil), asynq.TaskID(taskID), asynq.MaxRetry(3)); err != nil {
+		t.Fatalf("Enqueue() = %v", err)
+	}
+	stale := claim(t, s)
+
+	// Another worker claims the task once the lease expired, while the first still runs it
+	newer := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	if _, err := pool.Exec(ctx, `UPDATE queue_tasks SET lease_token = $1, retried = retried + 1 WHERE id = $2`, newer, stale.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	var writeErr error
+	s.process(asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
+		writeErr = WriteResult(ctx, task, []byte("stale"))
+		return errors.New("failed")
+	}), stale)
+
+	if !errors.Is(writeErr, errLeaseLost) {
+		t.Errorf("WriteResult() = %v, want %v", writeErr, errLeaseLost)
+	}
+	row, err := s.queries.GetQueueTask(ctx, stale.ID)
+	if err != nil {
+		t.Fatalf("GetQueueTask() = %v", err)
+	}
+	// The stale outcome is dropped: the newer claim still holds the active task
+	if row.State != stateActive || row.LeaseToken != newer || row.Retried != 1 || row.Result != nil {
+		t.Errorf("task = %s token %v retried %d result %q, want it untouched by the stale worker", row.State, row.LeaseToken, row.Retried, row.Result)
+	}
+}
+
+func TestPostgresServerExpiredLeaseOnLastAttempt(t *testing.T) {
+	pool := dbtest.Pool(t)
+	backend := NewPostgresBackend(pool)
+	s := newTestServer(pool)
+	ctx := context.Background()
+
+	taskID := uuid.NewString()
+	if _, err := backend.Enqueue(ctx, asynq.NewTask("test:lease", nil), asynq.TaskID(taskID), asynq.MaxRetry(0)); err != nil {
+		t.Fatalf("Enqueue() = %v", err)
+	}
+	first := claim(t, s)
+	// The worker died: its lease expires and the task is claimed again
+	if _, err := pool.Exec(ctx, `UPDATE queue_tasks SET lease_expires_at = now() - interval '1 second' WHERE id = $1`, first.ID); err != nil {
+		t.Fatal(err)
+	}
+	second := claim(t, s)
+	if second.Retried != 1 {
+		t.Fatalf("reclaimed task retried = %d, want 1", second.Retried)
+	}
+
+	ran := false
+	s.process(asynq.HandlerFunc(func(context.Context, *asynq.Task) error {
+		ran = true
+		return nil
+	}), second)
+	if ran {
+		t.Error("handler ran although the lost attempt was the last one")
+	}
+	row, err := s.queries.GetQueueTask(ctx, second.ID)
+	if err != nil || row.State != stateArchived {
+		t.Errorf("task = %s, %v; want archived", row.State, err)
+	}
+}
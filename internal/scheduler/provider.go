@@ -0,0 +1,99 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"boiler-go/internal/db"
+
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
+)
+
+// providerQueryTimeout bounds each periodic_tasks read made during a sync.
+const providerQueryTimeout = 5 * time.Second
+
+// DefaultsFunc returns the registered default options for a task type,
+// and false if the task type is unknown.
+type DefaultsFunc func(taskType string) ([]asynq.Option, bool)
+
+// ConfigProvider implements asynq.PeriodicTaskConfigProvider.
+// It serves the code-defined entries plus every enabled row of the periodic_tasks table,
+// so an asynq.PeriodicTaskManager picks up schedule changes on its next sync.
+type ConfigProvider struct {
+	static   []PeriodicTask
+	queries  *db.Queries
+	defaults DefaultsFunc
+	logg     zerolog.Logger
+}
+
+// NewConfigProvider creates a provider. static entries are expected to be validated by the caller.
+func NewConfigProvider(logg zerolog.Logger, pool *pgxpool.Pool, static []PeriodicTask, defaults DefaultsFunc) *ConfigProvider {
+	return &ConfigProvider{
+		static:   static,
+		queries:  db.New(pool),
+		defaults: defaults,
+		logg:     logg,
+	}
+}
+
+// GetConfigs is called by the PeriodicTaskManager on every sync.
+// Invalid rows are skipped and logged so one bad row cannot stop every other schedule.
+// A database error is returned as-is; the manager then keeps its current entries.
+func (p *ConfigProvider) GetConfigs() ([]*asynq.PeriodicTaskConfig, error) {
+	configs := make([]*asynq.PeriodicTaskConfig, 0, len(p.static))
+	for _, entry := range p.static {
+		cfg, err := entry.Config()
+		if err != nil {
+			return nil, fmt.Errorf("periodic task %s (%s): %w", entry.TaskType, entry.Cronspec, err)
+		}
+		configs = append(configs, cfg)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), providerQueryTimeout)
+	defer cancel()
+
+	rows, err := p.queries.ListEnabledPeriodicTasks(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load periodic tasks: %w", err)
+	}
+
+	for _, row := range rows {
+		cfg, err := p.configFromRow(row)
+		if err != nil {
+			p.logg.Warn().
+				Err(err).
+				Str("periodic_task_id", row.ID.String()).
+				Str("name", row.Name).
+				Str("task_type", row.TaskType).
+				Msg("skipping invalid periodic task")
+			continue
+		}
+		configs = append(configs, cfg)
+	}
+
+	return configs, nil
+}
+
+// configFromRow converts a periodic_tasks row, applying the registered defaults of its task type.
+func (p *ConfigProvider) configFromRow(row db.PeriodicTask) (*asynq.PeriodicTaskConfig, error) {
+	opts, ok := p.defaults(row.TaskType)
+	if !ok {
+		return nil, fmt.Errorf("task type %q is not registered", row.TaskType)
+	}
+
+	entry := PeriodicTask{
+		Cronspec: row.Cronspec,
+		TaskType: row.TaskType,
+		Queue:    row.Queue,
+		Timezone: row.Timezone,
+		Opts:     opts,
+	}
+	if len(row.Payload) > 0 {
+		entry.Payload = json.RawMessage(row.Payload)
+	}
+	return entry.Config()
+}
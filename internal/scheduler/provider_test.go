@@ -0,0 +1,82 @@
+package scheduler
+
+import (
+	"testing"
+
+	"boiler-go/internal/db"
+	"boiler-go/internal/queue"
+
+	"github.com/hibiken/asynq"
+	"github.com/rs/zerolog"
+)
+
+func TestConfigFromRow(t *testing.T) {
+	defaults := func(taskType string) ([]asynq.Option, bool) {
+		if taskType != "test:periodic" {
+			return nil, false
+		}
+		return []asynq.Option{asynq.Queue(queue.QueueDefault), asynq.MaxRetry(3)}, true
+	}
+	p := NewConfigProvider(zerolog.Nop(), nil, nil, defaults)
+
+	tests := []struct {
+		name        string
+		row         db.PeriodicTask
+		wantPayload string
+		wantSpec    string
+		wantErr     bool
+	}{
+		{
+			name:     "without payload",
+			row:      db.PeriodicTask{Cronspec: "*/5 * * * *", TaskType: "test:periodic"},
+			wantSpec: "CRON_TZ=UTC */5 * * * *",
+		},
+		{
+			name:        "with payload",
+			row:         db.PeriodicTask{Cronspec: "@hourly", TaskType: "test:periodic", Payload: []byte(`{"a":1}`)},
+			wantPayload: `{"a":1}`,
+			wantSpec:    "CRON_TZ=UTC @hourly",
+		},
+		{
+			name:     "with timezone",
+			row:      db.PeriodicTask{Cronspec: "0 9 * * *", TaskType: "test:periodic", Timezone: "Europe/Paris"},
+			wantSpec: "CRON_TZ=Europe/Paris 0 9 * * *",
+		},
+		{
+			name:    "unregistered task type",
+			row:     db.PeriodicTask{Cronspec: "@hourly", TaskType: "test:unknown"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid cronspec",
+			row:     db.PeriodicTask{Cronspec: "every hour", TaskType: "test:periodic"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown queue",
+			row:     db.PeriodicTask{Cronspec: "@hourly", TaskType: "test:periodic", Queue: "unknown"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := p.configFromRow(tt.row)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("configFromRow() succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("configFromRow() = %v", err)
+			}
+			if cfg.Cronspec != tt.wantSpec {
+				t.Errorf("cronspec = %q, want %q", cfg.Cronspec, tt.wantSpec)
+			}
+			// An empty payload is handed to the handler as the zero value, see tasks.Definition.Handle
+			if got := string(cfg.Task.Payload()); got != tt.wantPayload {
+				t.Errorf("payload = %q, want %q", got, tt.wantPayload)
+			}
+		})
+	}
+}
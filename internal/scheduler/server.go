@@ -0,0 +1,461 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"boiler-go/internal/db"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// stateWriteTimeout bounds the update of a task row after its handler returns.
+	stateWriteTimeout = 5 * time.Second
+	// janitorInterval is how often completed tasks past their retention, and servers
+	// whose heartbeat expired, are deleted.
+	janitorInterval = time.Minute
+	// serverHeartbeatInterval is how often a server renews its queue_servers row, which
+	// expires after two intervals without a heartbeat, like asynq's server info.
+	serverHeartbeatInterval = 5 * time.Second
+)
+
+// errLeaseLost is returned when a task is written after its lease passed to another claim.
+var errLeaseLost = errors.New("task lease lost")
+
+// PostgresServerConfig configures a PostgresServer. Fields mirror asynq.Config.
+type PostgresServerConfig struct {
+	// Concurrency is the number of tasks processed at once.
+	Concurrency int
+	// Queues maps queue names to their weight, like asynq.Config.Queues:
+	// a queue with weight 6 is checked first twice as often as one with weight 3.
+	Queues map[string]int
+	// PollInterval is how long a worker goroutine sleeps when no task is due.
+	PollInterval time.Duration
+	// VisibilityTimeout is how long a task stays leased to a worker without a heartbeat.
+	// Leases are extended while the handler runs, so it only matters when a worker dies.
+	VisibilityTimeout time.Duration
+	RetryDelayFunc    asynq.RetryDelayFunc
+	IsFailure         func(error) bool
+	ErrorHandler      asynq.ErrorHandler
+	Logger            zerolog.Logger
+}
+
+// PostgresServer processes the tasks of a PostgresBackend with an asynq.Handler, so the
+// worker's ServeMux and middlewares run unchanged. Handlers read task metadata with
+// TaskID, QueueName, RetryCount and MaxRetry, and write results with WriteResult.
+// While it runs, the server heartbeats in the queue_servers table, which /health reads.
+type PostgresServer struct {
+	queries *db.Queries
+	cfg     PostgresServerConfig
+
+	id      pgtype.UUID
+	host    string
+	started time.Time
+	// active is the number of tasks being processed.
+	active atomic.Int32
+
+	fetchCtx  context.Context
+	stopFetch context.CancelFunc
+	wg        sync.WaitGroup
+	beatCtx   context.Context
+	stopBeat  context.CancelFunc
+	beatWG    sync.WaitGroup
+	done      chan struct{}
+	once      sync.Once
+}
+
+// NewPostgresServer returns a server processing the queue_tasks table of pool.
+func NewPostgresServer(pool *pgxpool.Pool, cfg PostgresServerConfig) *PostgresServer {
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = runtime.NumCPU()
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+	if cfg.VisibilityTimeout <= 0 {
+		cfg.VisibilityTimeout = 30 * time.Second
+	}
+	if cfg.IsFailure == nil {
+		cfg.IsFailure = func(error) bool { return true }
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown-host"
+	}
+	fetchCtx, stopFetch := context.WithCancel(context.Background())
+	beatCtx, stopBeat := context.WithCancel(context.Background())
+	return &PostgresServer{
+		queries:   db.New(pool),
+		cfg:       cfg,
+		id:        pgtype.UUID{Bytes: uuid.New(), Valid: true},
+		host:      host,
+		started:   time.Now(),
+		fetchCtx:  fetchCtx,
+		stopFetch: stopFetch,
+		beatCtx:   beatCtx,
+		stopBeat:  stopBeat,
+		done:      make(chan struct{}),
+	}
+}
+
+// Run starts processing tasks with handler and blocks until Shutdown.
+func (s *PostgresServer) Run(handler asynq.Handler) error {
+	if len(s.cfg.Queues) == 0 {
+		return errors.New("postgres server has no queues")
+	}
+	s.beatWG.Add(1)
+	go s.serverHeartbeat()
+	for range s.cfg.Concurrency {
+		s.wg.Add(1)
+		go s.fetch(handler)
+	}
+	s.wg.Add(1)
+	go s.janitor()
+
+	<-s.done
+	return nil
+}
+
+// Stop stops claiming new tasks. Tasks being processed run to completion.
+func (s *PostgresServer) Stop() {
+	s.stopFetch()
+}
+
+// Shutdown stops claiming new tasks and waits for the tasks being processed.
+// Tasks still running when the process exits are claimed again once their lease expires.
+func (s *PostgresServer) Shutdown() {
+	s.Stop()
+	s.wg.Wait()
+	s.once.Do(func() {
+		s.stopBeat()
+		s.beatWG.Wait()
+		// Deregister right away rather than waiting for the row to expire
+		ctx, cancel := context.WithTimeout(context.Background(), stateWriteTimeout)
+		defer cancel()
+		if err := s.queries.DeleteQueueServer(ctx, s.id); err != nil {
+			s.cfg.Logger.Warn().Err(err).Msg("failed to deregister queue server")
+		}
+		close(s.done)
+	})
+}
+
+// serverHeartbeat registers the server in queue_servers and renews its row until Shutdown.
+// The status turns from active to stopped once the server stops claiming tasks.
+func (s *PostgresServer) serverHeartbeat() {
+	defer s.beatWG.Done()
+	queues, _ := json.Marshal(s.cfg.Queues)
+	ticker := time.NewTicker(serverHeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		status := "active"
+		if s.fetchCtx.Err() != nil {
+			status = "stopped"
+		}
+		ctx, cancel := context.WithTimeout(s.beatCtx, stateWriteTimeout)
+		err := s.queries.HeartbeatQueueServer(ctx, db.HeartbeatQueueServerParams{
+			ID:            s.id,
+			Host:          s.host,
+			Pid:           int32(os.Getpid()),
+			Concurrency:   int32(s.cfg.Concurrency),
+			Queues:        queues,
+			Status:        status,
+			ActiveWorkers: s.active.Load(),
+			StartedAt:     pgtype.Timestamptz{Time: s.started, Valid: true},
+			TtlSeconds:    (2 * serverHeartbeatInterval).Seconds(),
+		})
+		cancel()
+		if err != nil && s.beatCtx.Err() == nil {
+			s.cfg.Logger.Warn().Err(err).Msg("failed to write queue server heartbeat")
+		}
+
+		select {
+		case <-s.beatCtx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *PostgresServer) fetch(handler asynq.Handler) {
+	defer s.wg.Done()
+	for s.fetchCtx.Err() == nil {
+		t, err := s.queries.ClaimQueueTask(s.fetchCtx, db.ClaimQueueTaskParams{
+			LeaseSeconds: s.cfg.VisibilityTimeout.Seconds(),
+			LeaseToken:   pgtype.UUID{Bytes: uuid.New(), Valid: true},
+			Queues:       s.queueOrder(),
+		})
+		if err != nil {
+			if !errors.Is(err, pgx.ErrNoRows) && s.fetchCtx.Err() == nil {
+				s.cfg.Logger.Error().Err(err).Msg("failed to claim task")
+			}
+			// Jitter spreads the polls of idle goroutines
+			s.sleep(s.cfg.PollInterval/2 + rand.N(s.cfg.PollInterval))
+			continue
+		}
+		s.process(handler, t)
+	}
+}
+
+func (s *PostgresServer) sleep(d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-s.fetchCtx.Done():
+	case <-timer.C:
+	}
+}
+
+// queueOrder returns the queues in the order to check them, randomized by weight
+// like asynq does when StrictPriority is off.
+func (s *PostgresServer) queueOrder() []string {
+	var weighted []string
+	for name, weight := range s.cfg.Queues {
+		for range max(weight, 1) {
+			weighted = append(weighted, name)
+		}
+	}
+	rand.Shuffle(len(weighted), func(i, j int) { weighted[i], weighted[j] = weighted[j], weighted[i] })
+
+	seen := make(map[string]bool, len(s.cfg.Queues))
+	order := make([]string, 0, len(s.cfg.Queues))
+	for _, name := range weighted {
+		if !seen[name] {
+			seen[name] = true
+			order = append(order, name)
+		}
+	}
+	return order
+}
+
+// process runs the handler on a claimed task and records the outcome like asynq does,
+// except that errors that are not failures are retried even on the last attempt.
+func (s *PostgresServer) process(handler asynq.Handler, t db.QueueTask) {
+	s.active.Add(1)
+	defer s.active.Add(-1)
+
+	taskID := t.ID.String()
+	log := s.cfg.Logger.With().Str("task_id", taskID).Str("task_type", t.TaskType).Logger()
+
+	// A lease that expired on the last attempt leaves no retry
+	if t.Retried > t.MaxRetry {
+		s.archive(log, t, errors.New("task lease expired"))
+		return
+	}
+
+	var headers map[string]string
+	_ = json.Unmarshal(t.Headers, &headers)
+	task := asynq.NewTaskWithHeaders(t.TaskType, t.Payload, headers)
+
+	ctx, cancel := taskContext(&taskMetadata{
+		id:       taskID,
+		queue:    t.Queue,
+		retried:  int(t.Retried),
+		maxRetry: int(t.MaxRetry),
+		writeResult: func(ctx context.Context, data []byte) error {
+			n, err := s.queries.WriteQueueTaskResult(ctx, db.WriteQueueTaskResultParams{
+				ID:         t.ID,
+				Result:     data,
+				LeaseToken: t.LeaseToken,
+			})
+			if err == nil && n == 0 {
+				err = errLeaseLost
+			}
+			return err
+		},
+	}, time.Duration(t.TimeoutMs)*time.Millisecond, t.Deadline.Time)
+	defer cancel()
+
+	stopHeartbeat := s.heartbeat(ctx, log, t, cancel)
+	err := perform(ctx, handler, task)
+	// asynq fails a task whose context ends before its handler returns
+	if err == nil && ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	stopHeartbeat()
+
+	if err == nil {
+		s.complete(log, t)
+		return
+	}
+
+	if s.cfg.ErrorHandler != nil {
+		s.cfg.ErrorHandler.HandleError(ctx, task, err)
+	}
+	switch {
+	case errors.Is(err, asynq.RevokeTask):
+		s.write(log, "revoke", func(ctx context.Context) (int64, error) {
+			return s.queries.DeleteLeasedQueueTask(ctx, db.DeleteLeasedQueueTaskParams{ID: t.ID, LeaseToken: t.LeaseToken})
+		})
+	case !s.cfg.IsFailure(err):
+		// Errors that are not failures, such as rate limits, are retried even on the last
+		// attempt, without counting against MaxRetry
+		s.retry(log, t, task, err, t.Retried)
+	case t.Retried >= t.MaxRetry || errors.Is(err, asynq.SkipRetry):
+		s.archive(log, t, err)
+	default:
+		s.retry(log, t, task, err, t.Retried+1)
+	}
+}
+
+// heartbeat extends the lease of a task while its handler runs, and cancels the task
+// when cancellation is requested through CancelProcessing or the lease is lost to another
+// claim. It returns a stop func.
+func (s *PostgresServer) heartbeat(ctx context.Context, log zerolog.Logger, t db.QueueTask, cancel context.CancelFunc) func() {
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(s.cfg.VisibilityTimeout / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+
+			writeCtx, writeCancel := context.WithTimeout(context.WithoutCancel(ctx), stateWriteTimeout)
+			cancelRequested, err := s.queries.ExtendQueueTaskLease(writeCtx, db.ExtendQueueTaskLeaseParams{
+				LeaseSeconds: s.cfg.VisibilityTimeout.Seconds(),
+				ID:           t.ID,
+				LeaseToken:   t.LeaseToken,
+			})
+			writeCancel()
+			if errors.Is(err, pgx.ErrNoRows) {
+				// Another worker claimed the task, so this attempt's outcome would be dropped
+				log.Warn().Msg("task lease lost, cancelling task")
+				cancel()
+				return
+			}
+			if err != nil {
+				log.Warn().Err(err).Msg("failed to extend task lease")
+				continue
+			}
+			if cancelRequested {
+				cancel()
+			}
+		}
+	}()
+	return func() {
+		close(stop)
+		<-stopped
+	}
+}
+
+// taskContext returns the context a task is processed with. It carries md, and ends at
+// the task's timeout or deadline, whichever comes first; a zero value means none.
+func taskContext(md *taskMetadata, timeout time.Duration, deadline time.Time) (context.Context, context.CancelFunc) {
+	ctx := withTaskMetadata(context.Background(), md)
+	if timeout > 0 {
+		if end := time.Now().Add(timeout); deadline.IsZero() || end.Before(deadline) {
+			deadline = end
+		}
+	}
+	if deadline.IsZero() {
+		return context.WithCancel(ctx)
+	}
+	return context.WithDeadline(ctx, deadline)
+}
+
+// perform calls the handler, turning a panic into an error as asynq does.
+func perform(ctx context.Context, handler asynq.Handler, task *asynq.Task) (err error) {
+	defer func() {
+		if x := recover(); x != nil {
+			if _, file, line, ok := runtime.Caller(2); ok {
+				err = fmt.Errorf("panic [%s:%d]: %v", file, line, x)
+			} else {
+				err = fmt.Errorf("panic: %v", x)
+			}
+		}
+	}()
+	return handler.ProcessTask(ctx, task)
+}
+
+func (s *PostgresServer) complete(log zerolog.Logger, t db.QueueTask) {
+	s.write(log, "complete", func(ctx context.Context) (int64, error) {
+		// asynq deletes completed tasks right away unless they have a retention
+		if t.RetentionMs == 0 {
+			return s.queries.DeleteLeasedQueueTask(ctx, db.DeleteLeasedQueueTaskParams{ID: t.ID, LeaseToken: t.LeaseToken})
+		}
+		return s.queries.CompleteQueueTask(ctx, db.CompleteQueueTaskParams{ID: t.ID, LeaseToken: t.LeaseToken})
+	})
+}
+
+func (s *PostgresServer) archive(log zerolog.Logger, t db.QueueTask, err error) {
+	s.write(log, "archive", func(ctx context.Context) (int64, error) {
+		return s.queries.ArchiveQueueTask(ctx, db.ArchiveQueueTaskParams{
+			ID:         t.ID,
+			LastError:  pgtype.Text{String: err.Error(), Valid: true},
+			LeaseToken: t.LeaseToken,
+		})
+	})
+}
+
+// retry schedules the next attempt, recording retried as the task's retry count.
+func (s *PostgresServer) retry(log zerolog.Logger, t db.QueueTask, task *asynq.Task, err error, retried int32) {
+	delay := asynq.DefaultRetryDelayFunc(int(t.Retried), err, task)
+	if s.cfg.RetryDelayFunc != nil {
+		delay = s.cfg.RetryDelayFunc(int(t.Retried), err, task)
+	}
+
+	s.write(log, "retry", func(ctx context.Context) (int64, error) {
+		return s.queries.RetryQueueTask(ctx, db.RetryQueueTaskParams{
+			ID:         t.ID,
+			Retried:    retried,
+			RunAt:      pgtype.Timestamptz{Time: time.Now().Add(delay), Valid: true},
+			LastError:  pgtype.Text{String: err.Error(), Valid: true},
+			LeaseToken: t.LeaseToken,
+		})
+	})
+}
+
+// write records the outcome of a task. A failed write leaves the task active, so it is
+// processed again once its lease expires. fn matches the row only while this worker holds
+// the lease; when no row matches, another worker claimed the task and the outcome is dropped.
+func (s *PostgresServer) write(log zerolog.Logger, action string, fn func(context.Context) (int64, error)) {
+	ctx, cancel := context.WithTimeout(context.Background(), stateWriteTimeout)
+	defer cancel()
+	n, err := fn(ctx)
+	if err != nil {
+		log.Error().Err(err).Str("action", action).Msg("failed to record task outcome")
+		return
+	}
+	if n == 0 {
+		log.Warn().Err(errLeaseLost).Str("action", action).Msg("task outcome dropped")
+	}
+}
+
+// janitor deletes completed tasks whose retention has expired, and the rows of servers
+// that stopped heartbeating, e.g. after a crash.
+func (s *PostgresServer) janitor() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(janitorInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.fetchCtx.Done():
+			return
+		case <-ticker.C:
+		}
+		if _, err := s.queries.DeleteExpiredQueueTasks(s.fetchCtx); err != nil && s.fetchCtx.Err() == nil {
+			s.cfg.Logger.Error().Err(err).Msg("failed to delete expired tasks")
+		}
+		if _, err := s.queries.DeleteExpiredQueueServers(s.fetchCtx); err != nil && s.fetchCtx.Err() == nil {
+			s.cfg.Logger.Error().Err(err).Msg("failed to delete expired queue servers")
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package tasks
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+// PermanentError marks a handler error as not worth retrying.
+// It wraps asynq.SkipRetry, so asynq archives the task immediately.
+type PermanentError struct {
+	Err error
+}
+
+// Permanent marks err as permanent. Use it for failures a retry cannot fix,
+// such as invalid payloads or a rejected request.
+func Permanent(err error) error {
+	return &PermanentError{Err: err}
+}
+
+func (e *PermanentError) Error() string {
+	return "permanent: " + e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() []error {
+	return []error{e.Err, asynq.SkipRetry}
+}
+
+// RetryAfterError asks for the task to be retried after a specific delay
+// instead of the delay computed by its RetryPolicy, e.g. to honor an
+// upstream Retry-After header. The retry still counts against MaxRetry.
+type RetryAfterError struct {
+	Err   error
+	After time.Duration
+}
+
+// RetryAfter returns err wrapped so the task is retried after d.
+func RetryAfter(d time.Duration, err error) error {
+	return &RetryAfterError{Err: err, After: d}
+}
+
+func (e *RetryAfterError) Error() string {
+	return fmt.Sprintf("%v (retry after %v)", e.Err, e.After)
+}
+
+func (e *RetryAfterError) Unwrap() error {
+	return e.Err
+}
+
+// RateLimitedError reports that a task was not run because its task type is over its
+// rate limit. asynq retries it after RetryIn without counting it against MaxRetry,
+// see IsFailure.
+type RateLimitedError struct {
+	TaskType string
+	RetryIn  time.Duration
+}
+
+func (e *RateLimitedError) Error() string {
+	return fmt.Sprintf("%s is rate limited (retry in %v)", e.TaskType, e.RetryIn)
+}
+
+// IsFailure implements asynq.Config.IsFailure. Rate limited tasks are not failures,
+// so they are retried without using up their retries.
+func IsFailure(err error) bool {
+	var rateLimited *RateLimitedError
+	return !errors.As(err, &rateLimited)
+}
@@ -0,0 +1,66 @@
+package tasks
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestPermanent(t *testing.T) {
+	cause := errors.New("invalid payload")
+	err := fmt.Errorf("handle: %w", Permanent(cause))
+
+	// asynq archives on SkipRetry, and handlers can still match the cause
+	if !errors.Is(err, asynq.SkipRetry) {
+		t.Errorf("errors.Is(%v, SkipRetry) = false, want true", err)
+	}
+	if !errors.Is(err, cause) {
+		t.Errorf("errors.Is(%v, cause) = false, want true", err)
+	}
+	var permanent *PermanentError
+	if !errors.As(err, &permanent) || permanent.Err != cause {
+		t.Errorf("errors.As(%v) = %v, want the permanent error", err, permanent)
+	}
+	if got, want := err.Error(), "handle: permanent: invalid payload"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	cause := errors.New("upstream busy")
+	err := RetryAfter(30*time.Second, cause)
+
+	if !errors.Is(err, cause) {
+		t.Errorf("errors.Is(%v, cause) = false, want true", err)
+	}
+	// The task is retried, so it must not skip retries
+	if errors.Is(err, asynq.SkipRetry) {
+		t.Errorf("errors.Is(%v, SkipRetry) = true, want false", err)
+	}
+	if got, want := err.Error(), "upstream busy (retry after 30s)"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
+
+func TestIsFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "error", err: errors.New("failed"), want: true},
+		{name: "permanent", err: Permanent(errors.New("failed")), want: true},
+		{name: "rate limited", err: &RateLimitedError{TaskType: TypeWorkerPing, RetryIn: time.Second}},
+		{name: "wrapped rate limited", err: fmt.Errorf("ping: %w", &RateLimitedError{TaskType: TypeWorkerPing})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsFailure(tt.err); got != tt.want {
+				t.Errorf("IsFailure(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
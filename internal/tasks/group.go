@@ -0,0 +1,69 @@
+package tasks
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"boiler-go/internal/scheduler"
+
+	"github.com/hibiken/asynq"
+)
+
+// Aggregation combines a group of tasks of one type into a single task of another,
+// see scheduler.Client.EnqueueToGroup.
+type Aggregation struct {
+	// From is the type of the grouped tasks.
+	From string
+	// Into is the type of the aggregated task.
+	Into string
+
+	aggregate func(group string, tasks []*asynq.Task) (*asynq.Task, error)
+}
+
+// Aggregate returns the aggregation of groups of from tasks into one into task.
+// fn combines the decoded payloads of a group into the aggregated payload. The aggregated
+// task gets into's default options, except its queue, which is the queue of the group.
+func Aggregate[P, B any](from Definition[P], into Definition[B], fn func(group string, payloads []P) (B, error)) Aggregation {
+	return Aggregation{
+		From: from.Type,
+		Into: into.Type,
+		aggregate: func(group string, tasks []*asynq.Task) (*asynq.Task, error) {
+			payloads := make([]P, 0, len(tasks))
+			ids := make([]string, 0, len(tasks))
+			for _, t := range tasks {
+				var payload P
+				if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+					return nil, fmt.Errorf("failed to decode %s payload: %w", from.Type, err)
+				}
+				payloads = append(payloads, payload)
+				if id := t.Headers()[scheduler.HeaderTaskID]; id != "" {
+					ids = append(ids, id)
+				}
+			}
+
+			aggregated, err := fn(group, payloads)
+			if err != nil {
+				return nil, err
+			}
+			data, err := json.Marshal(aggregated)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal %s payload: %w", into.Type, err)
+			}
+
+			headers := map[string]string{
+				scheduler.HeaderEnqueuedAt: time.Now().UTC().Format(time.RFC3339Nano),
+			}
+			if len(ids) > 0 {
+				headers[scheduler.HeaderAggregatedTaskIDs] = strings.Join(ids, ",")
+			}
+			return asynq.NewTaskWithHeaders(into.Type, data, headers, into.Options()...), nil
+		},
+	}
+}
+
+// Aggregate combines tasks, the tasks of group, into a single task.
+func (a Aggregation) Aggregate(group string, tasks []*asynq.Task) (*asynq.Task, error) {
+	return a.aggregate(group, tasks)
+}
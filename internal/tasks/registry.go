@@ -0,0 +1,171 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"boiler-go/internal/claimcheck"
+	"boiler-go/internal/db"
+	"boiler-go/internal/outbox"
+	"boiler-go/internal/scheduler"
+
+	"github.com/hibiken/asynq"
+)
+
+// Spec describes the static options shared by every task of a given type.
+type Spec struct {
+	// Type is the asynq task type name, e.g. "worker:ping".
+	Type string
+	// Queue is the default queue the task is enqueued to.
+	Queue string
+	// MaxRetry is the default number of retries before the task is archived.
+	MaxRetry int
+	// Timeout is the default processing timeout for a single attempt.
+	Timeout time.Duration
+	// Retention keeps completed tasks, and their results, in Redis for this long.
+	// Zero deletes a task as soon as it completes, so its result cannot be read.
+	Retention time.Duration
+	// Retry controls the backoff between retries. The zero value uses DefaultRetryPolicy.
+	Retry RetryPolicy
+	// API allows clients to enqueue the task type through the HTTP API, by name, in
+	// batches, periodic tasks and workflows. Internal types, such as webhook deliveries,
+	// are enqueued by the application only and leave it false.
+	API bool
+}
+
+// Options returns the asynq options derived from the spec.
+// Options passed at enqueue time are appended after these, so they take precedence.
+func (s Spec) Options() []asynq.Option {
+	opts := []asynq.Option{
+		asynq.Queue(s.Queue),
+		asynq.MaxRetry(s.MaxRetry),
+	}
+	if s.Timeout > 0 {
+		opts = append(opts, asynq.Timeout(s.Timeout))
+	}
+	if s.Retention > 0 {
+		opts = append(opts, asynq.Retention(s.Retention))
+	}
+	return opts
+}
+
+// Definition binds a Spec to its payload type P.
+// It is the single place a task type is declared; both the API (enqueueing)
+// and the worker (processing) go through it.
+type Definition[P any] struct {
+	Spec
+}
+
+// HandlerFunc processes a task whose payload has already been decoded.
+type HandlerFunc[P any] func(ctx context.Context, t *asynq.Task, payload P) error
+
+var (
+	registry   = map[string]Spec{}
+	registryMu sync.RWMutex
+)
+
+// define registers spec and returns a typed definition for it.
+// It panics on duplicate registration since that is always a programming error.
+func define[P any](spec Spec) Definition[P] {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, exists := registry[spec.Type]; exists {
+		panic(fmt.Sprintf("tasks: duplicate task type %q", spec.Type))
+	}
+	registry[spec.Type] = spec
+
+	return Definition[P]{Spec: spec}
+}
+
+// Lookup returns the spec registered for taskType.
+func Lookup(taskType string) (Spec, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	spec, ok := registry[taskType]
+	return spec, ok
+}
+
+// DefaultOptions returns the registered options for taskType, and false if it is unknown.
+func DefaultOptions(taskType string) ([]asynq.Option, bool) {
+	spec, ok := Lookup(taskType)
+	if !ok {
+		return nil, false
+	}
+	return spec.Options(), true
+}
+
+// All returns every registered spec sorted by type name.
+func All() []Spec {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	specs := make([]Spec, 0, len(registry))
+	for _, spec := range registry {
+		specs = append(specs, spec)
+	}
+	sort.Slice(specs, func(i, j int) bool { return specs[i].Type < specs[j].Type })
+	return specs
+}
+
+// Enqueue encodes payload and enqueues it with the definition's default options.
+// Any opts given override the defaults.
+func (d Definition[P]) Enqueue(ctx context.Context, client *scheduler.Client, payload P, opts ...asynq.Option) (string, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal %s payload: %w", d.Type, err)
+	}
+	return client.EnqueueWithID(ctx, d.Type, data, append(d.Options(), opts...)...)
+}
+
+// EnqueueToGroup encodes payload and enqueues it to group with the definition's default
+// options, to be aggregated with the other tasks of the group. Any opts given override the defaults.
+func (d Definition[P]) EnqueueToGroup(ctx context.Context, client *scheduler.Client, group string, payload P, opts ...asynq.Option) (string, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal %s payload: %w", d.Type, err)
+	}
+	return client.EnqueueToGroup(ctx, d.Type, group, data, append(d.Options(), opts...)...)
+}
+
+// EnqueueTx encodes payload and writes it to the outbox through q, with the definition's
+// default options. Bind q to a transaction with Queries.WithTx so the task is enqueued
+// if and only if the transaction commits. Any opts given override the defaults.
+func (d Definition[P]) EnqueueTx(ctx context.Context, q *db.Queries, payload P, opts ...asynq.Option) (string, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal %s payload: %w", d.Type, err)
+	}
+	return outbox.Enqueue(ctx, q, d.Type, data, append(d.Options(), opts...)...)
+}
+
+// Handle registers fn on mux for the definition's task type.
+// Offloaded payloads are loaded from the claimcheck store in ctx first. An empty payload,
+// such as a task enqueued through the API without one, decodes to the zero value of P.
+// Payloads that cannot be found or decoded are permanent failures since retrying cannot fix them.
+func (d Definition[P]) Handle(mux *asynq.ServeMux, fn HandlerFunc[P]) {
+	mux.HandleFunc(d.Type, func(ctx context.Context, t *asynq.Task) error {
+		data, err := claimcheck.Payload(ctx, t)
+		if errors.Is(err, claimcheck.ErrNotFound) {
+			return Permanent(err)
+		}
+		if err != nil {
+			return err
+		}
+
+		var payload P
+		if len(data) == 0 {
+			return fn(ctx, t, payload)
+		}
+		if err := json.Unmarshal(data, &payload); err != nil {
+			return Permanent(fmt.Errorf("failed to decode %s payload: %w", d.Type, err))
+		}
+		return fn(ctx, t, payload)
+	})
+}
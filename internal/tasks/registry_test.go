@@ -0,0 +1,81 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hibiken/asynq"
+)
+
+type testPayload struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func TestDefinitionHandle(t *testing.T) {
+	def := Definition[testPayload]{Spec: Spec{Type: "test:handle"}}
+	tests := []struct {
+		name          string
+		payload       []byte
+		want          testPayload
+		wantPermanent bool
+	}{
+		{
+			name:    "decodes payload",
+			payload: []byte(`{"name":"a","count":2}`),
+			want:    testPayload{Name: "a", Count: 2},
+		},
+		{
+			name:    "nil payload is the zero value",
+			payload: nil,
+		},
+		{
+			name:    "empty payload is the zero value",
+			payload: []byte{},
+		},
+		{
+			name:          "invalid payload is permanent",
+			payload:       []byte(`{"name":`),
+			wantPermanent: true,
+		},
+		{
+			name:          "mismatched payload is permanent",
+			payload:       []byte(`{"count":"two"}`),
+			wantPermanent: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := asynq.NewServeMux()
+			var got testPayload
+			called := false
+			def.Handle(mux, func(_ context.Context, _ *asynq.Task, payload testPayload) error {
+				called = true
+				got = payload
+				return nil
+			})
+
+			err := mux.ProcessTask(context.Background(), asynq.NewTask(def.Type, tt.payload))
+			if tt.wantPermanent {
+				var permanent *PermanentError
+				if !errors.As(err, &permanent) || !errors.Is(err, asynq.SkipRetry) {
+					t.Fatalf("ProcessTask() = %v, want a permanent error", err)
+				}
+				if called {
+					t.Error("handler called for an undecodable payload")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ProcessTask() = %v", err)
+			}
+			if !called {
+				t.Fatal("handler not called")
+			}
+			if got != tt.want {
+				t.Errorf("payload = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"boiler-go/internal/scheduler"
+
+	"github.com/hibiken/asynq"
+)
+
+// ErrNoResultWriter is returned when a task is not being processed by a backend server.
+var ErrNoResultWriter = scheduler.ErrNoResultWriter
+
+// WriteResult stores v as the JSON result of t, processed with ctx.
+// The result is readable through the API until the task's Retention expires,
+// so task types that produce results should set Spec.Retention.
+func WriteResult(ctx context.Context, t *asynq.Task, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s result: %w", t.Type(), err)
+	}
+	if err := scheduler.WriteResult(ctx, t, data); err != nil {
+		return fmt.Errorf("failed to write %s result: %w", t.Type(), err)
+	}
+	return nil
+}
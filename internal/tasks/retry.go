@@ -0,0 +1,71 @@
+package tasks
+
+import (
+	"errors"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+// RetryPolicy computes the delay before a failed task is retried:
+// Base * Multiplier^n, randomized by ±Jitter and capped at Max.
+type RetryPolicy struct {
+	// Base is the delay before the first retry.
+	Base time.Duration
+	// Max caps the delay. Zero means no cap.
+	Max time.Duration
+	// Multiplier grows the delay after each retry. Values below 1 are treated as 1.
+	Multiplier float64
+	// Jitter is the fraction of the delay, between 0 and 1, that is randomized
+	// so tasks failing together do not retry together.
+	Jitter float64
+}
+
+// DefaultRetryPolicy applies to task types that do not declare their own policy.
+var DefaultRetryPolicy = RetryPolicy{
+	Base:       time.Second,
+	Max:        10 * time.Minute,
+	Multiplier: 2,
+	Jitter:     0.2,
+}
+
+// Delay returns the delay before retry number n+1, where n is the number of retries so far.
+func (p RetryPolicy) Delay(n int) time.Duration {
+	base := p.Base
+	if base <= 0 {
+		base = DefaultRetryPolicy.Base
+	}
+	multiplier := math.Max(p.Multiplier, 1)
+
+	delay := float64(base) * math.Pow(multiplier, float64(n))
+	if p.Jitter > 0 {
+		jitter := math.Min(p.Jitter, 1)
+		delay += delay * jitter * (2*rand.Float64() - 1)
+	}
+	if p.Max > 0 && delay > float64(p.Max) {
+		delay = float64(p.Max)
+	}
+	return time.Duration(delay)
+}
+
+// RetryDelay implements asynq.RetryDelayFunc.
+// A RateLimitedError or RetryAfterError returned by the handler wins; otherwise the
+// task type's registered RetryPolicy is used, falling back to DefaultRetryPolicy.
+func RetryDelay(n int, err error, t *asynq.Task) time.Duration {
+	var rateLimited *RateLimitedError
+	if errors.As(err, &rateLimited) {
+		return rateLimited.RetryIn
+	}
+	var retryAfter *RetryAfterError
+	if errors.As(err, &retryAfter) {
+		return retryAfter.After
+	}
+
+	policy := DefaultRetryPolicy
+	if spec, ok := Lookup(t.Type()); ok && spec.Retry != (RetryPolicy{}) {
+		policy = spec.Retry
+	}
+	return policy.Delay(n)
+}
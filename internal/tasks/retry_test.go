@@ -0,0 +1,169 @@
+package tasks
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy RetryPolicy
+		n      int
+		want   time.Duration
+	}{
+		{
+			name:   "first retry uses base",
+			policy: RetryPolicy{Base: time.Second, Multiplier: 2},
+			n:      0,
+			want:   time.Second,
+		},
+		{
+			name:   "grows by multiplier",
+			policy: RetryPolicy{Base: time.Second, Multiplier: 2},
+			n:      3,
+			want:   8 * time.Second,
+		},
+		{
+			name:   "capped at max",
+			policy: RetryPolicy{Base: time.Second, Max: 5 * time.Second, Multiplier: 2},
+			n:      3,
+			want:   5 * time.Second,
+		},
+		{
+			name:   "zero max means no cap",
+			policy: RetryPolicy{Base: time.Second, Multiplier: 2},
+			n:      10,
+			want:   1024 * time.Second,
+		},
+		{
+			name:   "zero base falls back to default base",
+			policy: RetryPolicy{Multiplier: 3},
+			n:      1,
+			want:   3 * DefaultRetryPolicy.Base,
+		},
+		{
+			name:   "multiplier below 1 treated as 1",
+			policy: RetryPolicy{Base: 2 * time.Second, Multiplier: 0.5},
+			n:      5,
+			want:   2 * time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Delay(tt.n); got != tt.want {
+				t.Errorf("Delay(%d) = %v, want %v", tt.n, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyDelayJitter(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   RetryPolicy
+		n        int
+		min, max time.Duration
+	}{
+		{
+			name:   "within jitter fraction",
+			policy: RetryPolicy{Base: 10 * time.Second, Multiplier: 2, Jitter: 0.2},
+			n:      0,
+			min:    8 * time.Second,
+			max:    12 * time.Second,
+		},
+		{
+			name:   "jitter above 1 clamped",
+			policy: RetryPolicy{Base: 10 * time.Second, Multiplier: 2, Jitter: 5},
+			n:      0,
+			min:    0,
+			max:    20 * time.Second,
+		},
+		{
+			name:   "jittered delay still capped",
+			policy: RetryPolicy{Base: 10 * time.Second, Max: 10 * time.Second, Multiplier: 2, Jitter: 0.5},
+			n:      0,
+			min:    5 * time.Second,
+			max:    10 * time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for range 1000 {
+				got := tt.policy.Delay(tt.n)
+				if got < tt.min || got > tt.max {
+					t.Fatalf("Delay(%d) = %v, want within [%v, %v]", tt.n, got, tt.min, tt.max)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	failed := errors.New("failed")
+	tests := []struct {
+		name     string
+		err      error
+		taskType string
+		n        int
+		want     time.Duration
+	}{
+		{
+			name:     "rate limited error wins",
+			err:      &RateLimitedError{TaskType: TypeWorkerPing, RetryIn: 3 * time.Second},
+			taskType: TypeWorkerPing,
+			want:     3 * time.Second,
+		},
+		{
+			name:     "retry after error wins",
+			err:      RetryAfter(42*time.Second, failed),
+			taskType: TypeWorkerPing,
+			want:     42 * time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RetryDelay(tt.n, tt.err, asynq.NewTask(tt.taskType, nil))
+			if got != tt.want {
+				t.Errorf("RetryDelay() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryDelayPolicy(t *testing.T) {
+	failed := errors.New("failed")
+	tests := []struct {
+		name     string
+		taskType string
+		policy   RetryPolicy
+	}{
+		{
+			name:     "registered policy",
+			taskType: TypeWorkerPing,
+			policy:   WorkerPing.Spec.Retry,
+		},
+		{
+			name:     "registered type without policy uses default",
+			taskType: TypeUserUpdated,
+			policy:   DefaultRetryPolicy,
+		},
+		{
+			name:     "unknown type uses default",
+			taskType: "unknown:type",
+			policy:   DefaultRetryPolicy,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The cap is reached whatever the jitter, so the delay is exact
+			got := RetryDelay(30, failed, asynq.NewTask(tt.taskType, nil))
+			if got != tt.policy.Max {
+				t.Errorf("RetryDelay() = %v, want %v", got, tt.policy.Max)
+			}
+		})
+	}
+}
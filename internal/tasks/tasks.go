@@ -1,8 +1,121 @@
-// Package tasks defines shared task type constants used across the codebase.
-// This ensures consistency between task enqueueing (handlers) and task processing (worker).
+// Package tasks is the registry of every background task type.
+// Each task type is declared once with its payload struct and default options,
+// which keeps task enqueueing (handlers) and task processing (worker) consistent.
 package tasks
 
+import (
+	"time"
+
+	"boiler-go/internal/queue"
+)
+
 const (
 	// TypeWorkerPing is used to verify the worker is alive and processing tasks.
 	TypeWorkerPing = "worker:ping"
+	// TypeWebhookDeliver delivers a task completion notification to a callback URL.
+	TypeWebhookDeliver = "webhook:deliver"
+	// TypeUserUpdated records that a user changed. It is enqueued to a group and
+	// aggregated into TypeUserSync tasks.
+	TypeUserUpdated = "user:updated"
+	// TypeUserSync sends a batch of changed users downstream in one call.
+	TypeUserSync = "user:sync"
 )
+
+// PingPayload is the payload for the worker ping task.
+// The request ID travels in the task headers, see scheduler.Client.
+type PingPayload struct {
+	Message  string    `json:"message"`
+	QueuedAt time.Time `json:"queued_at"`
+}
+
+// PingResult is the result written by the worker ping handler.
+type PingResult struct {
+	Message     string    `json:"message"`
+	Hostname    string    `json:"hostname"`
+	ProcessedAt time.Time `json:"processed_at"`
+}
+
+// WorkerPing verifies the worker is alive and processing tasks.
+var WorkerPing = define[PingPayload](Spec{
+	Type:      TypeWorkerPing,
+	API:       true,
+	Queue:     queue.QueueDefault,
+	MaxRetry:  3,
+	Timeout:   30 * time.Second,
+	Retention: 24 * time.Hour,
+	Retry: RetryPolicy{
+		Base:       time.Second,
+		Max:        30 * time.Second,
+		Multiplier: 2,
+		Jitter:     0.1,
+	},
+})
+
+// WebhookPayload is the payload of the webhook delivery task.
+type WebhookPayload struct {
+	DeliveryID string `json:"delivery_id"`
+}
+
+// WebhookDeliver POSTs a completion notification to a task's callback URL, see package webhook.
+// Retries back off up to an hour so a receiver can be down for a while without losing notifications.
+var WebhookDeliver = define[WebhookPayload](Spec{
+	Type:     TypeWebhookDeliver,
+	Queue:    queue.QueueDefault,
+	MaxRetry: 10,
+	Timeout:  time.Minute,
+	Retry: RetryPolicy{
+		Base:       10 * time.Second,
+		Max:        time.Hour,
+		Multiplier: 2,
+		Jitter:     0.2,
+	},
+})
+
+// UserUpdatedPayload is the payload of the user updated task.
+type UserUpdatedPayload struct {
+	UserID string `json:"user_id"`
+}
+
+// UserUpdated records that a user changed. Enqueue it with EnqueueToGroup so the changes
+// are synced in batches; a task enqueued on its own is synced alone.
+var UserUpdated = define[UserUpdatedPayload](Spec{
+	Type:     TypeUserUpdated,
+	Queue:    queue.QueueDefault,
+	MaxRetry: 3,
+	Timeout:  time.Minute,
+})
+
+// UserSyncPayload is the payload of the user sync task.
+type UserSyncPayload struct {
+	// Group is the group the changes were aggregated from, empty for a single change.
+	Group   string   `json:"group,omitempty"`
+	UserIDs []string `json:"user_ids"`
+}
+
+// UserSync sends a batch of changed users downstream in one call.
+var UserSync = define[UserSyncPayload](Spec{
+	Type:     TypeUserSync,
+	Queue:    queue.QueueDefault,
+	MaxRetry: 5,
+	Timeout:  5 * time.Minute,
+	Retry: RetryPolicy{
+		Base:       5 * time.Second,
+		Max:        5 * time.Minute,
+		Multiplier: 2,
+		Jitter:     0.2,
+	},
+})
+
+// UserSyncAggregation aggregates the user updated tasks of a group into one user sync task,
+// dropping duplicate user IDs.
+var UserSyncAggregation = Aggregate(UserUpdated, UserSync, func(group string, payloads []UserUpdatedPayload) (UserSyncPayload, error) {
+	batch := UserSyncPayload{Group: group, UserIDs: make([]string, 0, len(payloads))}
+	seen := make(map[string]bool, len(payloads))
+	for _, p := range payloads {
+		if !seen[p.UserID] {
+			seen[p.UserID] = true
+			batch.UserIDs = append(batch.UserIDs, p.UserID)
+		}
+	}
+	return batch, nil
+})
@@ -0,0 +1,30 @@
+package tracing
+
+import "context"
+
+// TaskHeaders returns the task headers that carry ctx's request ID and span to the worker.
+// It returns nil when ctx carries neither.
+func TaskHeaders(ctx context.Context) map[string]string {
+	var headers map[string]string
+	if requestID, ok := RequestIDFrom(ctx); ok {
+		headers = map[string]string{TaskHeaderRequestID: requestID}
+	}
+	if span, ok := SpanFrom(ctx); ok {
+		if headers == nil {
+			headers = make(map[string]string, 1)
+		}
+		headers[HeaderTraceparent] = span.Traceparent()
+	}
+	return headers
+}
+
+// FromTaskHeaders restores the request ID from task headers and starts the span
+// for processing the task: a child of the enqueuing span, or a new root span for
+// tasks enqueued without trace context (e.g. by the periodic scheduler).
+func FromTaskHeaders(headers map[string]string) (requestID string, span Span) {
+	requestID = headers[TaskHeaderRequestID]
+	if parent, err := ParseTraceparent(headers[HeaderTraceparent]); err == nil {
+		return requestID, parent.Child()
+	}
+	return requestID, NewRoot()
+}
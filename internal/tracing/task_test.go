@@ -0,0 +1,37 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+)
+
+func TestTaskHeadersRoundTrip(t *testing.T) {
+	parent := NewRoot()
+	ctx := WithSpan(WithRequestID(context.Background(), "req-1"), parent)
+
+	headers := TaskHeaders(ctx)
+	if headers[TaskHeaderRequestID] != "req-1" || headers[HeaderTraceparent] != parent.Traceparent() {
+		t.Fatalf("TaskHeaders() = %v, want the request ID and span", headers)
+	}
+
+	requestID, span := FromTaskHeaders(headers)
+	if requestID != "req-1" {
+		t.Errorf("request ID = %q, want req-1", requestID)
+	}
+	// The task runs in a child span of the enqueuing one
+	if span.TraceID != parent.TraceID || span.ParentID != parent.SpanID || span.SpanID == parent.SpanID {
+		t.Errorf("span = %+v, want a child of %+v", span, parent)
+	}
+}
+
+func TestTaskHeadersWithoutCorrelation(t *testing.T) {
+	if headers := TaskHeaders(context.Background()); headers != nil {
+		t.Errorf("TaskHeaders() = %v, want nil", headers)
+	}
+
+	// Tasks enqueued without trace context, e.g. by the scheduler, start a new trace
+	requestID, span := FromTaskHeaders(map[string]string{HeaderTraceparent: "garbage"})
+	if requestID != "" || span.ParentID != "" || len(span.TraceID) != 32 || len(span.SpanID) != 16 {
+		t.Errorf("FromTaskHeaders() = %q, %+v; want a new root span", requestID, span)
+	}
+}
@@ -0,0 +1,137 @@
+// Package tracing carries correlation data - the request ID and a W3C trace context -
+// from API requests into background tasks, so one request can be followed through
+// every API and worker log line it causes.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+const (
+	// HeaderTraceparent is the W3C Trace Context header name, used on HTTP requests and task headers alike.
+	HeaderTraceparent = "traceparent"
+	// TaskHeaderRequestID is the task header carrying the ID of the request that enqueued the task.
+	TaskHeaderRequestID = "request_id"
+)
+
+// Span identifies one unit of work within a trace.
+// IDs are lowercase hex as they appear in a traceparent header.
+type Span struct {
+	TraceID string
+	SpanID  string
+	// ParentID is the span this one was started from. Empty for a root span.
+	ParentID string
+	Sampled  bool
+}
+
+// NewRoot starts a new trace.
+func NewRoot() Span {
+	return Span{
+		TraceID: randomHex(16),
+		SpanID:  randomHex(8),
+		Sampled: true,
+	}
+}
+
+// Child starts a span within the same trace, with s as its parent.
+func (s Span) Child() Span {
+	return Span{
+		TraceID:  s.TraceID,
+		SpanID:   randomHex(8),
+		ParentID: s.SpanID,
+		Sampled:  s.Sampled,
+	}
+}
+
+// Traceparent formats s as a version 00 traceparent header value.
+func (s Span) Traceparent() string {
+	flags := "00"
+	if s.Sampled {
+		flags = "01"
+	}
+	return "00-" + s.TraceID + "-" + s.SpanID + "-" + flags
+}
+
+// ParseTraceparent parses a traceparent header value.
+// The returned span is the remote caller's span; call Child to continue the trace.
+func ParseTraceparent(value string) (Span, error) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 {
+		return Span{}, errors.New("traceparent must have 4 fields")
+	}
+	version, traceID, spanID, flags := parts[0], parts[1], parts[2], parts[3]
+
+	// Future versions may append fields, version 00 may not
+	if !isHex(version, 2) || version == "ff" || (version == "00" && len(parts) != 4) {
+		return Span{}, errors.New("unsupported traceparent version")
+	}
+	if !isHex(traceID, 32) || traceID == strings.Repeat("0", 32) {
+		return Span{}, errors.New("invalid trace ID")
+	}
+	if !isHex(spanID, 16) || spanID == strings.Repeat("0", 16) {
+		return Span{}, errors.New("invalid parent span ID")
+	}
+	if !isHex(flags, 2) {
+		return Span{}, errors.New("invalid trace flags")
+	}
+
+	flagBits, _ := hex.DecodeString(flags)
+	return Span{
+		TraceID: traceID,
+		SpanID:  spanID,
+		Sampled: flagBits[0]&0x01 == 0x01,
+	}, nil
+}
+
+// isHex reports whether s is exactly n lowercase hex characters.
+func isHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for _, r := range s {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	// crypto/rand.Read never returns an error
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+type ctxKey int
+
+const (
+	requestIDCtxKey ctxKey = iota
+	spanCtxKey
+)
+
+// WithRequestID returns a context carrying the request ID.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDCtxKey, requestID)
+}
+
+// RequestIDFrom returns the request ID stored in ctx, if any.
+func RequestIDFrom(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDCtxKey).(string)
+	return id, ok && id != ""
+}
+
+// WithSpan returns a context carrying the current span.
+func WithSpan(ctx context.Context, span Span) context.Context {
+	return context.WithValue(ctx, spanCtxKey, span)
+}
+
+// SpanFrom returns the current span stored in ctx, if any.
+func SpanFrom(ctx context.Context) (Span, bool) {
+	span, ok := ctx.Value(spanCtxKey).(Span)
+	return span, ok
+}
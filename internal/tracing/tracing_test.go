@@ -0,0 +1,90 @@
+package tracing
+
+import "testing"
+
+func TestParseTraceparent(t *testing.T) {
+	const (
+		traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+		spanID  = "00f067aa0ba902b7"
+	)
+	tests := []struct {
+		name    string
+		value   string
+		want    Span
+		wantErr bool
+	}{
+		{
+			name:  "sampled",
+			value: "00-" + traceID + "-" + spanID + "-01",
+			want:  Span{TraceID: traceID, SpanID: spanID, Sampled: true},
+		},
+		{
+			name:  "not sampled",
+			value: "00-" + traceID + "-" + spanID + "-00",
+			want:  Span{TraceID: traceID, SpanID: spanID},
+		},
+		{
+			name:  "surrounding whitespace",
+			value: "  00-" + traceID + "-" + spanID + "-01 ",
+			want:  Span{TraceID: traceID, SpanID: spanID, Sampled: true},
+		},
+		{
+			name:  "future version with extra fields",
+			value: "01-" + traceID + "-" + spanID + "-01-extra",
+			want:  Span{TraceID: traceID, SpanID: spanID, Sampled: true},
+		},
+		{name: "empty", value: "", wantErr: true},
+		{name: "too few fields", value: "00-" + traceID + "-" + spanID, wantErr: true},
+		{name: "version 00 with extra fields", value: "00-" + traceID + "-" + spanID + "-01-extra", wantErr: true},
+		{name: "invalid version ff", value: "ff-" + traceID + "-" + spanID + "-01", wantErr: true},
+		{name: "non-hex version", value: "0x-" + traceID + "-" + spanID + "-01", wantErr: true},
+		{name: "uppercase trace ID", value: "00-4BF92F3577B34DA6A3CE929D0E0E4736-" + spanID + "-01", wantErr: true},
+		{name: "short trace ID", value: "00-4bf92f35-" + spanID + "-01", wantErr: true},
+		{name: "all-zero trace ID", value: "00-00000000000000000000000000000000-" + spanID + "-01", wantErr: true},
+		{name: "short span ID", value: "00-" + traceID + "-00f067aa-01", wantErr: true},
+		{name: "all-zero span ID", value: "00-" + traceID + "-0000000000000000-01", wantErr: true},
+		{name: "invalid flags", value: "00-" + traceID + "-" + spanID + "-zz", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTraceparent(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseTraceparent(%q) = %+v, want error", tt.value, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseTraceparent(%q) error: %v", tt.value, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseTraceparent(%q) = %+v, want %+v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTraceparentRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		span Span
+	}{
+		{name: "root", span: NewRoot()},
+		{name: "child", span: NewRoot().Child()},
+		{name: "not sampled", span: Span{TraceID: randomHex(16), SpanID: randomHex(8)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTraceparent(tt.span.Traceparent())
+			if err != nil {
+				t.Fatalf("ParseTraceparent(%q) error: %v", tt.span.Traceparent(), err)
+			}
+			// The parent span is not part of a traceparent
+			want := tt.span
+			want.ParentID = ""
+			if got != want {
+				t.Errorf("round trip = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"boiler-go/internal/db"
+	"boiler-go/internal/scheduler"
+	"boiler-go/internal/tasks"
+	"boiler-go/pkg/logger"
+
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	userAgent = "boiler-go-webhook/1.0"
+	// maxResponseDrain caps how much of a response body is read before closing it.
+	maxResponseDrain = 64 << 10
+	// recordTimeout bounds the update of a delivery row after an attempt.
+	recordTimeout = 5 * time.Second
+)
+
+// Deliverer sends notifications and records each attempt. It is the handler of
+// the webhook:deliver task, whose retry policy drives redelivery.
+type Deliverer struct {
+	queries *db.Queries
+	secret  string
+	client  *http.Client
+}
+
+// NewDeliverer returns a deliverer signing notifications with secret.
+// Each request is bounded by timeout. Callback URLs resolving to loopback, private
+// or link-local addresses are refused unless allowPrivate is set.
+func NewDeliverer(queries *db.Queries, secret string, timeout time.Duration, allowPrivate bool) *Deliverer {
+	return &Deliverer{
+		queries: queries,
+		secret:  secret,
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: newTransport(allowPrivate),
+			// Redirects would turn the POST into a GET, so they count as failures
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// Deliver makes one delivery attempt and records it in the delivery's history.
+func (d *Deliverer) Deliver(ctx context.Context, t *asynq.Task, p tasks.WebhookPayload) error {
+	log := logger.FromContext(ctx)
+
+	id, err := db.ParseUUID(p.DeliveryID)
+	if err != nil {
+		return tasks.Permanent(fmt.Errorf("invalid delivery ID %q: %w", p.DeliveryID, err))
+	}
+	delivery, err := d.queries.GetWebhookDelivery(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return tasks.Permanent(fmt.Errorf("webhook delivery %s not found", p.DeliveryID))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load webhook delivery: %w", err)
+	}
+	// A previous attempt succeeded but its task was not marked done
+	if delivery.Status != StatusPending {
+		return nil
+	}
+
+	started := time.Now()
+	statusCode, sendErr := d.send(ctx, delivery)
+	attempt := Attempt{
+		Attempt:    delivery.Attempts + 1,
+		At:         started.UTC(),
+		StatusCode: statusCode,
+		DurationMs: time.Since(started).Milliseconds(),
+	}
+
+	status := StatusDelivered
+	if sendErr != nil {
+		attempt.Error = sendErr.Error()
+		status = StatusPending
+		if lastAttempt(ctx, sendErr) {
+			status = StatusFailed
+		}
+	}
+
+	logEvent := log.Info()
+	if sendErr != nil {
+		logEvent = log.Warn().Err(sendErr)
+	}
+	logEvent.
+		Str("delivery_id", p.DeliveryID).
+		Str("callback_url", delivery.CallbackUrl).
+		Int("status_code", statusCode).
+		Str("status", status).
+		Msg("webhook delivery attempted")
+
+	// The task context may already be canceled or past its deadline
+	recordCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), recordTimeout)
+	defer cancel()
+	if err := d.record(recordCtx, delivery.ID, status, attempt); err != nil {
+		log.Error().Err(err).Str("delivery_id", p.DeliveryID).Msg("failed to record webhook delivery attempt")
+	}
+
+	return sendErr
+}
+
+// send POSTs the notification and classifies the response. Timeouts, 408, 429 and 5xx
+// responses are retried, honoring Retry-After; other responses are permanent failures.
+func (d *Deliverer) send(ctx context.Context, delivery db.WebhookDelivery) (int, error) {
+	if d.secret == "" {
+		return 0, tasks.Permanent(errors.New("WEBHOOK_SECRET is not set"))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.CallbackUrl, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, tasks.Permanent(fmt.Errorf("invalid callback request: %w", err))
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set(HeaderDeliveryID, delivery.ID.String())
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(d.secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	// The callback host resolves to a forbidden address, which retrying will not change
+	if errors.Is(err, ErrForbiddenAddress) {
+		return 0, tasks.Permanent(fmt.Errorf("callback request failed: %w", err))
+	}
+	if err != nil {
+		return 0, fmt.Errorf("callback request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseDrain))
+
+	code := resp.StatusCode
+	switch {
+	case code >= 200 && code < 300:
+		return code, nil
+	case code == http.StatusRequestTimeout || code == http.StatusTooManyRequests || code >= 500:
+		err := fmt.Errorf("callback responded %s", resp.Status)
+		if seconds, convErr := strconv.Atoi(resp.Header.Get("Retry-After")); convErr == nil && seconds > 0 {
+			return code, tasks.RetryAfter(time.Duration(seconds)*time.Second, err)
+		}
+		return code, err
+	default:
+		return code, tasks.Permanent(fmt.Errorf("callback responded %s", resp.Status))
+	}
+}
+
+// record appends attempt to the history of the delivery and sets its status.
+func (d *Deliverer) record(ctx context.Context, id pgtype.UUID, status string, attempt Attempt) error {
+	// history is a JSON array, so the attempt is appended as a one-element array
+	entry, err := json.Marshal([]Attempt{attempt})
+	if err != nil {
+		return err
+	}
+
+	params := db.RecordWebhookAttemptParams{
+		ID:      id,
+		Status:  status,
+		Attempt: entry,
+	}
+	if attempt.StatusCode != 0 {
+		params.LastStatusCode = pgtype.Int4{Int32: int32(attempt.StatusCode), Valid: true}
+	}
+	if attempt.Error != "" {
+		params.LastError = pgtype.Text{String: attempt.Error, Valid: true}
+	}
+	return d.queries.RecordWebhookAttempt(ctx, params)
+}
+
+// lastAttempt reports whether asynq will archive the delivery task instead of retrying it.
+func lastAttempt(ctx context.Context, err error) bool {
+	retried, _ := scheduler.RetryCount(ctx)
+	maxRetry, _ := scheduler.MaxRetry(ctx)
+	return retried >= maxRetry || errors.Is(err, asynq.SkipRetry)
+}
@@ -0,0 +1,138 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"boiler-go/internal/db"
+	"boiler-go/internal/tasks"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func testDelivery(url string) db.WebhookDelivery {
+	return db.WebhookDelivery{
+		ID:          pgtype.UUID{Bytes: uuid.New(), Valid: true},
+		CallbackUrl: url,
+		Payload:     []byte(`{"task_id":"a","status":"completed"}`),
+	}
+}
+
+func TestDelivererSend(t *testing.T) {
+	tests := []struct {
+		name           string
+		status         int
+		retryAfter     string
+		wantErr        bool
+		wantPermanent  bool
+		wantRetryAfter time.Duration
+	}{
+		{name: "ok", status: http.StatusOK},
+		{name: "accepted", status: http.StatusAccepted},
+		{name: "server error", status: http.StatusInternalServerError, wantErr: true},
+		{name: "unavailable with Retry-After", status: http.StatusServiceUnavailable, retryAfter: "30", wantErr: true, wantRetryAfter: 30 * time.Second},
+		{name: "Retry-After as a date", status: http.StatusServiceUnavailable, retryAfter: "Wed, 21 Oct 2015 07:28:00 GMT", wantErr: true},
+		{name: "too many requests", status: http.StatusTooManyRequests, retryAfter: "5", wantErr: true, wantRetryAfter: 5 * time.Second},
+		{name: "request timeout", status: http.StatusRequestTimeout, wantErr: true},
+		{name: "bad request", status: http.StatusBadRequest, wantErr: true, wantPermanent: true},
+		{name: "not found", status: http.StatusNotFound, wantErr: true, wantPermanent: true},
+		{name: "redirect", status: http.StatusFound, wantErr: true, wantPermanent: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delivery := testDelivery("")
+			var received bool
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				received = r.Method == http.MethodPost &&
+					r.Header.Get(HeaderDeliveryID) == delivery.ID.String() &&
+					Verify("secret", r.Header.Get(HeaderTimestamp), body, r.Header.Get(HeaderSignature))
+				if tt.retryAfter != "" {
+					w.Header().Set("Retry-After", tt.retryAfter)
+				}
+				if tt.status == http.StatusFound {
+					w.Header().Set("Location", "/elsewhere")
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+			delivery.CallbackUrl = srv.URL
+
+			d := NewDeliverer(nil, "secret", time.Second, true)
+			code, err := d.send(context.Background(), delivery)
+			if !received {
+				t.Fatal("callback did not receive a signed POST of the delivery")
+			}
+			if code != tt.status {
+				t.Errorf("status code = %d, want %d", code, tt.status)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("send() = %v, want error %v", err, tt.wantErr)
+			}
+			if errors.Is(err, asynq.SkipRetry) != tt.wantPermanent {
+				t.Errorf("send() = %v, want permanent %v", err, tt.wantPermanent)
+			}
+			var retryAfter *tasks.RetryAfterError
+			if errors.As(err, &retryAfter) != (tt.wantRetryAfter != 0) || (retryAfter != nil && retryAfter.After != tt.wantRetryAfter) {
+				t.Errorf("send() = %v, want retry after %v", err, tt.wantRetryAfter)
+			}
+		})
+	}
+}
+
+func TestDelivererSendPermanentFailures(t *testing.T) {
+	var received bool
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		received = true
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name      string
+		deliverer *Deliverer
+		want      error
+	}{
+		{name: "no secret", deliverer: NewDeliverer(nil, "", time.Second, true)},
+		{name: "loopback callback", deliverer: NewDeliverer(nil, "secret", time.Second, false), want: ErrForbiddenAddress},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			received = false
+			_, err := tt.deliverer.send(context.Background(), testDelivery(srv.URL))
+			if !errors.Is(err, asynq.SkipRetry) {
+				t.Errorf("send() = %v, want a permanent error", err)
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("send() = %v, want %v", err, tt.want)
+			}
+			if received {
+				t.Error("callback received the notification")
+			}
+		})
+	}
+}
+
+func TestDelivererSendTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	d := NewDeliverer(nil, "secret", 10*time.Millisecond, true)
+	code, err := d.send(context.Background(), testDelivery(srv.URL))
+	if err == nil || code != 0 {
+		t.Fatalf("send() = %d, %v; want a timeout", code, err)
+	}
+	if errors.Is(err, asynq.SkipRetry) {
+		t.Errorf("send() = %v, want a timeout to be retried", err)
+	}
+}
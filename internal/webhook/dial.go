@@ -0,0 +1,88 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenAddress is returned when a callback URL resolves to an address that is not
+// globally reachable: loopback, private, link-local, shared (CGNAT), reserved, multicast
+// or unspecified.
+var ErrForbiddenAddress = errors.New("callback address is not publicly routable")
+
+// nonGlobalPrefixes are the special-purpose ranges of the IANA IPv4 and IPv6 registries
+// that are not globally reachable, beyond those the netip.Addr methods cover.
+var nonGlobalPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // "this network"
+	netip.MustParsePrefix("100.64.0.0/10"),   // shared address space (CGNAT)
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("192.0.2.0/24"),    // documentation (TEST-NET-1)
+	netip.MustParsePrefix("192.88.99.0/24"),  // deprecated 6to4 relay anycast
+	netip.MustParsePrefix("198.18.0.0/15"),   // benchmarking
+	netip.MustParsePrefix("198.51.100.0/24"), // documentation (TEST-NET-2)
+	netip.MustParsePrefix("203.0.113.0/24"),  // documentation (TEST-NET-3)
+	netip.MustParsePrefix("240.0.0.0/4"),     // reserved, including broadcast
+	netip.MustParsePrefix("64:ff9b:1::/48"),  // local-use IPv4/IPv6 translation
+	netip.MustParsePrefix("100::/64"),        // discard-only
+	netip.MustParsePrefix("2001::/23"),       // IETF protocol assignments
+	netip.MustParsePrefix("2001:db8::/32"),   // documentation
+	netip.MustParsePrefix("2002::/16"),       // 6to4
+	netip.MustParsePrefix("3fff::/20"),       // documentation
+	netip.MustParsePrefix("5f00::/16"),       // segment routing (SRv6) SIDs
+}
+
+// newTransport returns the transport of the deliverer. Unless allowPrivate is set, every
+// connection is checked at dial time, after DNS resolution, so a callback host cannot
+// reach internal services or cloud metadata endpoints, even by rebinding its DNS record.
+func newTransport(allowPrivate bool) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if allowPrivate {
+		return transport
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   checkDialAddress,
+	}
+	transport.DialContext = dialer.DialContext
+	// Requests go straight to the callback host, so its address is the one checked
+	transport.Proxy = nil
+	return transport
+}
+
+// checkDialAddress implements net.Dialer.Control, rejecting forbidden addresses.
+func checkDialAddress(_, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("invalid callback address %q: %w", address, err)
+	}
+	if forbiddenAddr(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, addrPort.Addr())
+	}
+	return nil
+}
+
+// forbiddenAddr reports whether addr is not globally reachable, so it may belong to the
+// worker's own network.
+func forbiddenAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if addr.IsLoopback() ||
+		addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() ||
+		addr.IsMulticast() ||
+		addr.IsUnspecified() {
+		return true
+	}
+	for _, prefix := range nonGlobalPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
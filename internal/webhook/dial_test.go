@@ -0,0 +1,54 @@
+package webhook
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckDialAddress(t *testing.T) {
+	tests := []struct {
+		name      string
+		address   string
+		forbidden bool
+	}{
+		{name: "public IPv4", address: "93.184.216.34:443"},
+		{name: "public IPv6", address: "[2606:2800:220:1:248:1893:25c8:1946]:443"},
+		{name: "public IPv4 next to CGNAT", address: "100.128.0.1:443"},
+		{name: "shared address space", address: "100.64.0.1:80", forbidden: true},
+		{name: "shared address space end", address: "100.127.255.254:80", forbidden: true},
+		{name: "IETF protocol assignments", address: "192.0.0.8:80", forbidden: true},
+		{name: "benchmarking", address: "198.19.0.1:80", forbidden: true},
+		{name: "documentation", address: "203.0.113.7:80", forbidden: true},
+		{name: "this network", address: "0.1.2.3:80", forbidden: true},
+		{name: "reserved", address: "240.0.0.1:80", forbidden: true},
+		{name: "broadcast", address: "255.255.255.255:80", forbidden: true},
+		{name: "multicast", address: "224.0.0.251:80", forbidden: true},
+		{name: "IPv4-mapped CGNAT", address: "[::ffff:100.64.0.1]:80", forbidden: true},
+		{name: "documentation IPv6", address: "[2001:db8::1]:80", forbidden: true},
+		{name: "6to4", address: "[2002:a00:1::1]:80", forbidden: true},
+		{name: "local-use translation", address: "[64:ff9b:1::a00:1]:80", forbidden: true},
+		{name: "discard-only", address: "[100::1]:80", forbidden: true},
+		{name: "multicast IPv6", address: "[ff02::1]:80", forbidden: true},
+		{name: "loopback IPv4", address: "127.0.0.1:80", forbidden: true},
+		{name: "loopback IPv6", address: "[::1]:80", forbidden: true},
+		{name: "private 10/8", address: "10.1.2.3:80", forbidden: true},
+		{name: "private 172.16/12", address: "172.20.0.1:80", forbidden: true},
+		{name: "private 192.168/16", address: "192.168.1.1:80", forbidden: true},
+		{name: "unique local IPv6", address: "[fd00::1]:80", forbidden: true},
+		{name: "cloud metadata", address: "169.254.169.254:80", forbidden: true},
+		{name: "link-local IPv6", address: "[fe80::1]:80", forbidden: true},
+		{name: "unspecified", address: "0.0.0.0:80", forbidden: true},
+		{name: "IPv4-mapped loopback", address: "[::ffff:127.0.0.1]:80", forbidden: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkDialAddress("tcp", tt.address, nil)
+			if tt.forbidden != errors.Is(err, ErrForbiddenAddress) {
+				t.Errorf("checkDialAddress(%q) = %v, want forbidden %v", tt.address, err, tt.forbidden)
+			}
+			if !tt.forbidden && err != nil {
+				t.Errorf("checkDialAddress(%q) = %v, want nil", tt.address, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"boiler-go/internal/db"
+	"boiler-go/internal/scheduler"
+	"boiler-go/internal/tasks"
+
+	"github.com/hibiken/asynq"
+)
+
+// Notifier records notifications for finished tasks and enqueues their delivery.
+type Notifier struct {
+	queries *db.Queries
+	client  *scheduler.Client
+}
+
+// NewNotifier returns a notifier that enqueues deliveries and reads task results through client.
+func NewNotifier(queries *db.Queries, client *scheduler.Client) *Notifier {
+	return &Notifier{
+		queries: queries,
+		client:  client,
+	}
+}
+
+// Notify records a notification that task finished and enqueues its delivery to the
+// task's callback URL. ctx must derive from the task's context. event is one of the
+// Event constants and taskErr the error the task finished with, if any.
+// It returns the delivery ID, which is empty for tasks without a callback URL.
+func (n *Notifier) Notify(ctx context.Context, task *asynq.Task, event string, taskErr error) (string, error) {
+	callbackURL := task.Headers()[HeaderCallbackURL]
+	if callbackURL == "" {
+		return "", nil
+	}
+	taskID, _ := scheduler.TaskID(ctx)
+	queueName, _ := scheduler.QueueName(ctx)
+
+	jobID, err := db.JobID(taskID)
+	if err != nil {
+		return "", fmt.Errorf("task ID %q is not a valid job ID: %w", taskID, err)
+	}
+
+	notification := Notification{
+		TaskID:     taskID,
+		TaskType:   task.Type(),
+		Status:     event,
+		FinishedAt: time.Now().UTC(),
+	}
+	if taskErr != nil {
+		notification.Error = taskErr.Error()
+	}
+	// The result is written through the task's ResultWriter while the handler runs,
+	// so it is already stored even though the task is not marked completed yet
+	if event == EventCompleted {
+		info, err := n.client.TaskInfo(ctx, queueName, taskID)
+		if err != nil && !errors.Is(err, asynq.ErrTaskNotFound) {
+			return "", fmt.Errorf("failed to read task result: %w", err)
+		}
+		if info != nil {
+			notification.Result = resultValue(info.Result)
+		}
+	}
+
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal notification: %w", err)
+	}
+
+	delivery, err := n.queries.CreateWebhookDelivery(ctx, db.CreateWebhookDeliveryParams{
+		TaskID:      jobID,
+		TaskType:    task.Type(),
+		Event:       event,
+		CallbackUrl: callbackURL,
+		Payload:     body,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to record webhook delivery: %w", err)
+	}
+
+	deliveryID := delivery.ID.String()
+	// The delivery ID doubles as the task ID, so a delivery is never enqueued twice
+	_, err = tasks.WebhookDeliver.Enqueue(ctx, n.client, tasks.WebhookPayload{DeliveryID: deliveryID}, asynq.TaskID(deliveryID))
+	if err != nil {
+		return deliveryID, fmt.Errorf("failed to enqueue webhook delivery: %w", err)
+	}
+	return deliveryID, nil
+}
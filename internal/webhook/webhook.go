@@ -0,0 +1,102 @@
+// Package webhook notifies clients when their tasks finish. A task enqueued with a
+// callback URL gets a webhook_deliveries row once it reaches a terminal state, and a
+// webhook:deliver task POSTs an HMAC-signed JSON notification to the URL with its own retries.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"time"
+)
+
+// HeaderCallbackURL is the task header carrying the URL notified when the task finishes.
+const HeaderCallbackURL = "callback_url"
+
+// HTTP headers of a notification request.
+const (
+	// HeaderDeliveryID identifies the delivery; it is the same on every retry.
+	HeaderDeliveryID = "X-Webhook-Id"
+	// HeaderTimestamp is the Unix time the request was signed at.
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	// HeaderSignature is "sha256=" followed by the hex HMAC-SHA256 of "<timestamp>.<body>".
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Notification events, matching the outcome of the task.
+const (
+	EventCompleted = "completed"
+	EventFailed    = "failed"
+	EventCancelled = "cancelled"
+)
+
+// Delivery statuses.
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusFailed    = "failed"
+)
+
+// Notification is the JSON body POSTed to a callback URL.
+type Notification struct {
+	TaskID     string    `json:"task_id"`
+	TaskType   string    `json:"task_type"`
+	Status     string    `json:"status"`
+	Result     any       `json:"result,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+// Attempt is one delivery attempt, as recorded in the delivery's history.
+type Attempt struct {
+	Attempt    int32     `json:"attempt"`
+	At         time.Time `json:"at"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+}
+
+// ValidateURL checks that rawURL can be used as a callback URL.
+func ValidateURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return errors.New("callback_url must be a valid URL")
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.New("callback_url must be an http or https URL")
+	}
+	if u.Host == "" {
+		return errors.New("callback_url must contain a host")
+	}
+	return nil
+}
+
+// Sign returns the signature of a notification body sent at timestamp,
+// in the format of the X-Webhook-Signature header.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is valid for body and timestamp, for use by receivers.
+// Receivers should also reject timestamps too far in the past to prevent replays.
+func Verify(secret, timestamp string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// resultValue returns a task result as raw JSON when possible, or as a string otherwise.
+func resultValue(result []byte) any {
+	if len(result) == 0 {
+		return nil
+	}
+	if json.Valid(result) {
+		return json.RawMessage(result)
+	}
+	return string(result)
+}
@@ -0,0 +1,84 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+)
+
+func TestValidateURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		wantErr bool
+	}{
+		{url: "https://example.com/hooks"},
+		{url: "http://example.com:8080/hooks?task=1"},
+		{url: "ftp://example.com/hooks", wantErr: true},
+		{url: "example.com/hooks", wantErr: true},
+		{url: "https:///hooks", wantErr: true},
+		{url: "://bad", wantErr: true},
+		{url: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			if err := ValidateURL(tt.url); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateURL(%q) = %v, want error %v", tt.url, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+	body := []byte(`{"task_id":"a"}`)
+	signature := Sign("secret", "1700000000", body)
+
+	// Receivers in other languages rely on the documented format
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte(`1700000000.{"task_id":"a"}`))
+	if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); signature != want {
+		t.Fatalf("Sign() = %q, want %q", signature, want)
+	}
+	if !Verify("secret", "1700000000", body, signature) {
+		t.Error("Verify() rejected its own signature")
+	}
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		body      []byte
+	}{
+		{name: "other secret", secret: "other", timestamp: "1700000000", body: body},
+		{name: "other timestamp", secret: "secret", timestamp: "1700000001", body: body},
+		{name: "other body", secret: "secret", timestamp: "1700000000", body: []byte(`{"task_id":"b"}`)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if Verify(tt.secret, tt.timestamp, tt.body, signature) {
+				t.Error("Verify() accepted a signature of another notification")
+			}
+		})
+	}
+}
+
+func TestResultValue(t *testing.T) {
+	tests := []struct {
+		name   string
+		result []byte
+		want   string
+	}{
+		{name: "none", result: nil, want: `null`},
+		{name: "JSON", result: []byte(`{"sent":3}`), want: `{"sent":3}`},
+		{name: "text", result: []byte("done"), want: `"done"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(resultValue(tt.result))
+			if err != nil || string(got) != tt.want {
+				t.Errorf("resultValue(%q) = %s, %v; want %s", tt.result, got, err, tt.want)
+			}
+		})
+	}
+}
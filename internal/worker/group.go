@@ -0,0 +1,110 @@
+package worker
+
+import (
+	"context"
+	"errors"
+
+	"boiler-go/internal/db"
+	"boiler-go/internal/metrics"
+	"boiler-go/internal/scheduler"
+	"boiler-go/internal/tasks"
+	"boiler-go/pkg/logger"
+
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog"
+)
+
+// aggregations are the task types whose groups the worker aggregates.
+var aggregations = []tasks.Aggregation{
+	tasks.UserSyncAggregation,
+}
+
+// Aggregator returns the asynq.GroupAggregator of the worker. It combines each group
+// with the aggregation registered for the type of its tasks, and logs and counts every
+// aggregated group. A group that cannot be aggregated is logged and left in its queue,
+// and asynq tries again on its next aggregation check.
+func Aggregator(logg zerolog.Logger, m *metrics.TaskMetrics) asynq.GroupAggregator {
+	byType := make(map[string]tasks.Aggregation, len(aggregations))
+	for _, a := range aggregations {
+		byType[a.From] = a
+	}
+
+	return asynq.GroupAggregatorFunc(func(group string, grouped []*asynq.Task) *asynq.Task {
+		taskType := grouped[0].Type()
+		log := logg.With().
+			Str("group", group).
+			Str("task_type", taskType).
+			Int("size", len(grouped)).
+			Logger()
+
+		a, ok := byType[taskType]
+		if !ok {
+			log.Error().Msg("no aggregation registered for task type, group left in queue")
+			return nil
+		}
+		for _, t := range grouped[1:] {
+			if t.Type() != taskType {
+				log.Error().Str("other_type", t.Type()).Msg("group mixes task types, group left in queue")
+				return nil
+			}
+		}
+
+		task, err := a.Aggregate(group, grouped)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to aggregate group, group left in queue")
+			return nil
+		}
+		m.Aggregated(taskType, len(grouped))
+		log.Info().Str("aggregated_type", task.Type()).Msg("task group aggregated")
+		return task
+	})
+}
+
+// groupMiddleware gives the jobs rows of the tasks aggregated into a task the final
+// status of that task: completed, archived or cancelled. Until then the rows stay
+// pending, since the grouped tasks never run on their own. Tracking failures are
+// logged and never fail the task itself.
+func groupMiddleware(queries *db.Queries) asynq.MiddlewareFunc {
+	return func(next asynq.Handler) asynq.Handler {
+		return asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
+			taskIDs := scheduler.AggregatedTaskIDs(task.Headers())
+			if len(taskIDs) == 0 {
+				return next.ProcessTask(ctx, task)
+			}
+
+			taskErr := next.ProcessTask(ctx, task)
+
+			params := db.MarkJobsFinishedParams{Ids: make([]pgtype.UUID, 0, len(taskIDs))}
+			switch {
+			case taskErr == nil:
+				params.Status = "completed"
+			case errors.Is(taskErr, asynq.RevokeTask):
+				params.Status = "cancelled"
+			case finalFailure(ctx, taskErr):
+				params.Status = "archived"
+				params.LastError = pgtype.Text{String: taskErr.Error(), Valid: true}
+			default:
+				return taskErr
+			}
+			for _, id := range taskIDs {
+				if jobID, err := db.JobID(id); err == nil {
+					params.Ids = append(params.Ids, jobID)
+				}
+			}
+
+			// The task context may already be canceled or past its deadline
+			writeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), jobWriteTimeout)
+			defer cancel()
+			if err := queries.MarkJobsFinished(writeCtx, params); err != nil {
+				log := logger.FromContext(ctx)
+				log.Error().
+					Err(err).
+					Str("status", params.Status).
+					Int("aggregated_tasks", len(params.Ids)).
+					Msg("failed to update aggregated job statuses")
+			}
+			return taskErr
+		})
+	}
+}
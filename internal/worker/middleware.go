@@ -0,0 +1,422 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"boiler-go/internal/claimcheck"
+	"boiler-go/internal/db"
+	"boiler-go/internal/metrics"
+	"boiler-go/internal/progress"
+	"boiler-go/internal/ratelimit"
+	"boiler-go/internal/scheduler"
+	"boiler-go/internal/tasks"
+	"boiler-go/internal/tracing"
+	"boiler-go/internal/webhook"
+	"boiler-go/internal/workflow"
+	"boiler-go/pkg/logger"
+
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog"
+)
+
+// loggingMiddleware logs task execution duration and success/failure.
+// It rebuilds the correlation data from the task headers: the request ID of the API
+// request that enqueued the task, and a span continuing its trace. Both are attached to
+// a task-scoped logger stored in the context, and re-propagated by any task enqueued
+// with that context.
+func loggingMiddleware(logg zerolog.Logger) asynq.MiddlewareFunc {
+	return func(next asynq.Handler) asynq.Handler {
+		return asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
+			start := time.Now()
+
+			taskID, ok := scheduler.TaskID(ctx)
+			if !ok {
+				taskID = "unknown"
+			}
+
+			requestID, span := tracing.FromTaskHeaders(task.Headers())
+
+			logCtx := logg.With().
+				Str("task_type", task.Type()).
+				Str("task_id", taskID).
+				Str("trace_id", span.TraceID).
+				Str("span_id", span.SpanID)
+			if span.ParentID != "" {
+				logCtx = logCtx.Str("parent_span_id", span.ParentID)
+			}
+			if requestID != "" {
+				logCtx = logCtx.Str("request_id", requestID)
+				ctx = tracing.WithRequestID(ctx, requestID)
+			}
+			taskLog := logCtx.Logger()
+			ctx = logger.WithContext(tracing.WithSpan(ctx, span), taskLog)
+
+			taskLog.Info().Msg("task started")
+
+			err := next.ProcessTask(ctx, task)
+			duration := time.Since(start)
+
+			// The context is canceled by a deadline or timeout, or by Inspector.CancelProcessing
+			if ctx.Err() != nil {
+				taskLog.Warn().
+					AnErr("cause", context.Cause(ctx)).
+					Dur("duration", duration).
+					Msg("task context canceled")
+			}
+
+			switch {
+			case err == nil:
+				taskLog.Info().
+					Dur("duration", duration).
+					Msg("task completed")
+			case !tasks.IsFailure(err):
+				taskLog.Info().
+					Err(err).
+					Dur("duration", duration).
+					Msg("task deferred")
+			default:
+				taskLog.Error().
+					Err(err).
+					Dur("duration", duration).
+					Msg("task failed")
+			}
+
+			return err
+		})
+	}
+}
+
+// jobsMiddleware keeps the jobs row of each task in sync with its lifecycle:
+// active when started, then completed, retry or archived once the handler returns.
+// Tasks whose job was cancelled through the API are revoked without running the handler,
+// and an attempt canceled through the API while active is revoked too, so it is neither
+// retried nor archived on its last attempt.
+// Tracking failures are logged and never fail the task itself.
+func jobsMiddleware(queries *db.Queries) asynq.MiddlewareFunc {
+	return func(next asynq.Handler) asynq.Handler {
+		return asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
+			log := logger.FromContext(ctx)
+
+			taskID, _ := scheduler.TaskID(ctx)
+			jobID, err := db.JobID(taskID)
+			if err != nil {
+				log.Warn().Msg("task ID is not a UUID, skipping job tracking")
+				return next.ProcessTask(ctx, task)
+			}
+
+			retried, _ := scheduler.RetryCount(ctx)
+			queueName, _ := scheduler.QueueName(ctx)
+
+			logFailure := func(err error, status string) {
+				log.Error().
+					Err(err).
+					Str("status", status).
+					Msg("failed to update job status")
+			}
+
+			// Upsert so tasks enqueued outside scheduler.Client are tracked too
+			writeCtx, cancel := context.WithTimeout(ctx, jobWriteTimeout)
+			rows, err := queries.MarkJobActive(writeCtx, db.MarkJobActiveParams{
+				ID:       jobID,
+				TaskType: task.Type(),
+				Queue:    queueName,
+				Payload:  db.JobPayload(task.Payload()),
+				Attempts: int32(retried + 1),
+			})
+			cancel()
+			switch {
+			case err != nil:
+				logFailure(err, "active")
+			case rows == 0:
+				// Cancelled through the API, e.g. while active: asynq retries a task whose
+				// context was canceled, so the cancellation takes effect on the next attempt.
+				log.Info().Msg("task was cancelled, revoking")
+				return asynq.RevokeTask
+			}
+
+			taskErr := next.ProcessTask(ctx, task)
+
+			// The task context may already be canceled or past its deadline
+			writeCtx, cancel = context.WithTimeout(context.WithoutCancel(ctx), jobWriteTimeout)
+			defer cancel()
+
+			// Tell a cancellation through the API apart from other context cancellations.
+			// asynq's own processor does not wait for the handler once the context is
+			// canceled and fails the attempt with the context error; the Postgres and
+			// inline backends act on the RevokeTask.
+			if errors.Is(ctx.Err(), context.Canceled) {
+				if job, err := queries.GetJob(writeCtx, jobID); err == nil && job.Status == "cancelled" {
+					log.Warn().Msg("task cancelled through the API while active, revoking")
+					taskErr = asynq.RevokeTask
+				}
+			}
+
+			switch {
+			case errors.Is(taskErr, asynq.RevokeTask):
+				if _, err := queries.MarkJobCancelled(writeCtx, jobID); err != nil {
+					logFailure(err, "cancelled")
+				}
+			case taskErr == nil:
+				if err := queries.MarkJobCompleted(writeCtx, jobID); err != nil {
+					logFailure(err, "completed")
+				}
+			case finalFailure(ctx, taskErr):
+				if err := queries.MarkJobArchived(writeCtx, db.MarkJobArchivedParams{
+					ID:        jobID,
+					LastError: pgtype.Text{String: taskErr.Error(), Valid: true},
+				}); err != nil {
+					logFailure(err, "archived")
+				}
+			default:
+				if err := queries.MarkJobRetry(writeCtx, db.MarkJobRetryParams{
+					ID:        jobID,
+					LastError: pgtype.Text{String: taskErr.Error(), Valid: true},
+				}); err != nil {
+					logFailure(err, "retry")
+				}
+			}
+
+			return taskErr
+		})
+	}
+}
+
+// workflowMiddleware reports the outcome of workflow step tasks to the workflow engine
+// once it is final: on success, or on a failure with no retries left. The engine then
+// enqueues the dependent steps or fails the workflow. Engine errors are logged and never
+// fail the task itself, since its handler has already run.
+func workflowMiddleware(engine *workflow.Engine) asynq.MiddlewareFunc {
+	return func(next asynq.Handler) asynq.Handler {
+		return asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
+			workflowID, step, ok := workflow.StepFromHeaders(task.Headers())
+			if !ok {
+				return next.ProcessTask(ctx, task)
+			}
+
+			taskErr := next.ProcessTask(ctx, task)
+			if taskErr != nil && !finalFailure(ctx, taskErr) {
+				return taskErr
+			}
+
+			log := logger.FromContext(ctx).With().
+				Str("workflow_id", workflowID).
+				Str("workflow_step", step).
+				Logger()
+
+			// The task context may already be canceled or past its deadline
+			advanceCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), jobWriteTimeout)
+			defer cancel()
+
+			if err := engine.StepFinished(advanceCtx, workflowID, step, taskErr); err != nil {
+				log.Error().Err(err).Msg("failed to advance workflow")
+			} else {
+				log.Info().Bool("succeeded", taskErr == nil).Msg("workflow step finished")
+			}
+
+			return taskErr
+		})
+	}
+}
+
+// webhookMiddleware records a completion notification and enqueues its delivery once a
+// task with a callback URL has finished for good: on success, on a failure with no retries
+// left, or when revoked after a cancellation. Notifier errors are logged and never fail
+// the task itself, since its handler has already run.
+func webhookMiddleware(notifier *webhook.Notifier) asynq.MiddlewareFunc {
+	return func(next asynq.Handler) asynq.Handler {
+		return asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
+			if task.Headers()[webhook.HeaderCallbackURL] == "" {
+				return next.ProcessTask(ctx, task)
+			}
+
+			taskErr := next.ProcessTask(ctx, task)
+
+			var event string
+			switch {
+			case taskErr == nil:
+				event = webhook.EventCompleted
+			case errors.Is(taskErr, asynq.RevokeTask):
+				event = webhook.EventCancelled
+			case finalFailure(ctx, taskErr):
+				event = webhook.EventFailed
+			default:
+				return taskErr
+			}
+
+			log := logger.FromContext(ctx)
+
+			// The task context may already be canceled or past its deadline
+			notifyCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), jobWriteTimeout)
+			defer cancel()
+
+			deliveryID, err := notifier.Notify(notifyCtx, task, event, taskErr)
+			if err != nil {
+				log.Error().Err(err).Str("event", event).Msg("failed to queue task completion webhook")
+			} else {
+				log.Info().Str("event", event).Str("delivery_id", deliveryID).Msg("task completion webhook queued")
+			}
+
+			return taskErr
+		})
+	}
+}
+
+// payloadMiddleware lets task handlers load offloaded payloads from store, and deletes the
+// payload of a task once it completes or is revoked after a cancellation. Payloads of tasks
+// that fail for good are kept, since archived tasks can be re-run.
+func payloadMiddleware(store claimcheck.Store) asynq.MiddlewareFunc {
+	return func(next asynq.Handler) asynq.Handler {
+		return asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
+			ref, offloaded := claimcheck.Ref(task)
+			if !offloaded {
+				return next.ProcessTask(ctx, task)
+			}
+
+			taskErr := next.ProcessTask(claimcheck.WithStore(ctx, store), task)
+			if taskErr != nil && !errors.Is(taskErr, asynq.RevokeTask) {
+				return taskErr
+			}
+
+			// The task context may already be canceled or past its deadline
+			deleteCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), jobWriteTimeout)
+			defer cancel()
+			if err := store.Delete(deleteCtx, ref); err != nil {
+				log := logger.FromContext(ctx)
+				log.Error().Err(err).Str("payload_ref", ref).Msg("failed to delete offloaded payload")
+			}
+			return taskErr
+		})
+	}
+}
+
+// finalFailure reports whether the task will be archived or revoked instead of retried.
+// Errors that are not failures are always retried by the Postgres and inline backends.
+// asynq archives them on the last attempt, which rateLimitMiddleware avoids by waiting
+// for a token instead.
+func finalFailure(ctx context.Context, err error) bool {
+	if errors.Is(err, asynq.RevokeTask) {
+		return true
+	}
+	if !tasks.IsFailure(err) {
+		return false
+	}
+	retried, _ := scheduler.RetryCount(ctx)
+	maxRetry, _ := scheduler.MaxRetry(ctx)
+	return retried >= maxRetry || errors.Is(err, asynq.SkipRetry)
+}
+
+// rateLimitMiddleware takes a token from the rate limit bucket of the task's type, shared by
+// every worker process, before running the task. Over the limit the task is pushed back with
+// a RateLimitedError, which asynq retries once a token is due without counting a failure.
+// Limiter errors are logged and let the task run rather than stall the task type.
+func rateLimitMiddleware(limiter *ratelimit.Limiter, rules map[string]ratelimit.Rule, m *metrics.TaskMetrics) asynq.MiddlewareFunc {
+	return func(next asynq.Handler) asynq.Handler {
+		return asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
+			rule, ok := rules[task.Type()]
+			if !ok {
+				return next.ProcessTask(ctx, task)
+			}
+			log := logger.FromContext(ctx)
+
+			// asynq archives a task that errors on its last attempt even when the error is not
+			// a failure, so a task with no retries left waits for a token instead
+			retried, _ := scheduler.RetryCount(ctx)
+			maxRetry, _ := scheduler.MaxRetry(ctx)
+			if retried >= maxRetry {
+				if err := limiter.Wait(ctx, task.Type(), rule); err != nil {
+					if ctx.Err() != nil {
+						return ctx.Err()
+					}
+					log.Error().Err(err).Msg("rate limiter unavailable, running task")
+				}
+				return next.ProcessTask(ctx, task)
+			}
+
+			allowed, wait, err := limiter.Allow(ctx, task.Type(), rule)
+			if err != nil {
+				log.Error().Err(err).Msg("rate limiter unavailable, running task")
+				return next.ProcessTask(ctx, task)
+			}
+			if !allowed {
+				queueName, _ := scheduler.QueueName(ctx)
+				m.RateLimited(task.Type(), queueName)
+				log.Info().
+					Stringer("limit", rule).
+					Dur("retry_in", wait).
+					Msg("task rate limited")
+				return &tasks.RateLimitedError{TaskType: task.Type(), RetryIn: wait}
+			}
+
+			return next.ProcessTask(ctx, task)
+		})
+	}
+}
+
+// progressMiddleware lets handlers report progress with progress.Report, and publishes
+// the outcome of each attempt: completed, failed once no retries are left, cancelled,
+// or retrying. Publish errors are logged and never fail the task itself.
+func progressMiddleware(store *progress.Store) asynq.MiddlewareFunc {
+	return func(next asynq.Handler) asynq.Handler {
+		return asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
+			taskID, ok := scheduler.TaskID(ctx)
+			if !ok {
+				return next.ProcessTask(ctx, task)
+			}
+
+			taskErr := next.ProcessTask(progress.WithReporter(ctx, store, taskID), task)
+
+			event := progress.Event{TaskID: taskID}
+			switch {
+			case taskErr == nil:
+				event.Type = progress.EventCompleted
+				event.Percent = 100
+			case errors.Is(taskErr, asynq.RevokeTask):
+				event.Type = progress.EventCancelled
+			case finalFailure(ctx, taskErr):
+				event.Type = progress.EventFailed
+				event.Error = taskErr.Error()
+			case errors.Is(ctx.Err(), context.Canceled):
+				// Canceled through the API, which has published the cancelled event
+				return taskErr
+			default:
+				event.Type = progress.EventRetrying
+				event.Error = taskErr.Error()
+			}
+
+			// The task context may already be canceled or past its deadline
+			publishCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), jobWriteTimeout)
+			defer cancel()
+			if err := store.Publish(publishCtx, event); err != nil {
+				log := logger.FromContext(ctx)
+				log.Warn().Err(err).Str("event", event.Type).Msg("failed to publish task progress")
+			}
+
+			return taskErr
+		})
+	}
+}
+
+// metricsMiddleware records task attempts, failures, retries and handler duration,
+// plus the time the task waited in its queue before its first attempt.
+func metricsMiddleware(m *metrics.TaskMetrics) asynq.MiddlewareFunc {
+	return func(next asynq.Handler) asynq.Handler {
+		return asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
+			start := time.Now()
+			queueName, _ := scheduler.QueueName(ctx)
+			retried, _ := scheduler.RetryCount(ctx)
+
+			m.Started(task.Type(), queueName, retried)
+			// Retries wait out their backoff, which is not queue wait
+			if readyAt, ok := scheduler.ReadyAt(task.Headers()); ok && retried == 0 {
+				m.ObserveQueueWait(task.Type(), queueName, start.Sub(readyAt))
+			}
+
+			err := next.ProcessTask(ctx, task)
+			m.Finished(task.Type(), queueName, time.Since(start), err)
+			return err
+		})
+	}
+}
@@ -0,0 +1,366 @@
+//go:build integration
+
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"boiler-go/internal/claimcheck"
+	"boiler-go/internal/db"
+	"boiler-go/internal/dbtest"
+	"boiler-go/internal/scheduler"
+	"boiler-go/internal/tasks"
+	"boiler-go/internal/webhook"
+	"boiler-go/internal/workflow"
+
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// newTestClient returns a client whose tasks run through mux on an inline backend, with
+// retries due in an hour so they stay in the retry state during the test.
+func newTestClient(t *testing.T, pool *pgxpool.Pool, mux *asynq.ServeMux, concurrency int) *scheduler.Client {
+	t.Helper()
+	backend := scheduler.NewInlineBackend(mux, scheduler.InlineConfig{
+		Concurrency:    concurrency,
+		RetryDelayFunc: func(int, error, *asynq.Task) time.Duration { return time.Hour },
+		IsFailure:      tasks.IsFailure,
+	})
+	client := scheduler.NewClientWithBackend(backend, pool)
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestJobsMiddlewareOutcomes(t *testing.T) {
+	failed := errors.New("failed")
+	tests := []struct {
+		name       string
+		opts       []asynq.Option
+		err        error
+		wantStatus string
+		wantError  bool
+	}{
+		{name: "completed", wantStatus: "completed"},
+		{name: "failed with retries left", opts: []asynq.Option{asynq.MaxRetry(3)}, err: failed, wantStatus: "retry", wantError: true},
+		{name: "failed on last attempt", opts: []asynq.Option{asynq.MaxRetry(0)}, err: failed, wantStatus: "archived", wantError: true},
+		{name: "permanent failure", opts: []asynq.Option{asynq.MaxRetry(3)}, err: tasks.Permanent(failed), wantStatus: "archived", wantError: true},
+		{name: "revoked", opts: []asynq.Option{asynq.MaxRetry(3)}, err: fmt.Errorf("stop: %w", asynq.RevokeTask), wantStatus: "cancelled"},
+		{name: "rate limited on last attempt", opts: []asynq.Option{asynq.MaxRetry(0)}, err: &tasks.RateLimitedError{TaskType: "test:outcome", RetryIn: time.Second}, wantStatus: "retry", wantError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := dbtest.Pool(t)
+			queries := db.New(pool)
+			ctx := context.Background()
+
+			mux := asynq.NewServeMux()
+			mux.Use(jobsMiddleware(queries))
+			mux.HandleFunc("test:outcome", func(context.Context, *asynq.Task) error { return tt.err })
+			client := newTestClient(t, pool, mux, 0)
+
+			taskID, err := client.EnqueueWithID(ctx, "test:outcome", []byte(`{"n":1}`), tt.opts...)
+			if err != nil {
+				t.Fatalf("EnqueueWithID() = %v", err)
+			}
+
+			jobID, _ := db.JobID(taskID)
+			job, err := queries.GetJob(ctx, jobID)
+			if err != nil {
+				t.Fatalf("GetJob() = %v", err)
+			}
+			if job.Status != tt.wantStatus || job.Attempts != 1 {
+				t.Errorf("job = %s after %d attempts, want %s after 1", job.Status, job.Attempts, tt.wantStatus)
+			}
+			if job.LastError.Valid != tt.wantError {
+				t.Errorf("job last error = %q, want recorded %v", job.LastError.String, tt.wantError)
+			}
+			if finished := tt.wantStatus != "retry"; job.CompletedAt.Valid != finished {
+				t.Errorf("job completed_at set = %v, want %v", job.CompletedAt.Valid, finished)
+			}
+		})
+	}
+}
+
+func TestJobsMiddlewareRevokesCancelledJob(t *testing.T) {
+	pool := dbtest.Pool(t)
+	queries := db.New(pool)
+	ctx := context.Background()
+
+	release := make(chan struct{})
+	ran := make(map[string]bool)
+	mux := asynq.NewServeMux()
+	mux.Use(jobsMiddleware(queries))
+	mux.HandleFunc("test:block", func(context.Context, *asynq.Task) error {
+		<-release
+		return nil
+	})
+	mux.HandleFunc("test:cancelled", func(ctx context.Context, _ *asynq.Task) error {
+		taskID, _ := scheduler.TaskID(ctx)
+		ran[taskID] = true
+		return nil
+	})
+	// A single goroutine, so the second task waits while its job is cancelled
+	client := newTestClient(t, pool, mux, 1)
+
+	if _, err := client.EnqueueWithID(ctx, "test:block", nil); err != nil {
+		t.Fatalf("EnqueueWithID() = %v", err)
+	}
+	taskID, err := client.EnqueueWithID(ctx, "test:cancelled", nil, asynq.MaxRetry(3))
+	if err != nil {
+		t.Fatalf("EnqueueWithID() = %v", err)
+	}
+	jobID, _ := db.JobID(taskID)
+	if _, err := queries.MarkJobCancelled(ctx, jobID); err != nil {
+		t.Fatal(err)
+	}
+	close(release)
+	client.Close()
+
+	if ran[taskID] {
+		t.Error("handler ran for a cancelled job")
+	}
+	if info, err := client.TaskInfo(ctx, "default", taskID); !errors.Is(err, asynq.ErrTaskNotFound) {
+		t.Errorf("TaskInfo() = %+v, %v; want the task revoked", info, err)
+	}
+	if job, err := queries.GetJob(ctx, jobID); err != nil || job.Status != "cancelled" || job.Attempts != 0 {
+		t.Errorf("job = %s after %d attempts, %v; want cancelled without attempts", job.Status, job.Attempts, err)
+	}
+}
+
+func TestJobsMiddlewareCancelActiveOnLastAttempt(t *testing.T) {
+	pool := dbtest.Pool(t)
+	queries := db.New(pool)
+	ctx := context.Background()
+
+	started := make(chan struct{})
+	mux := asynq.NewServeMux()
+	mux.Use(jobsMiddleware(queries))
+	mux.HandleFunc("test:cancel", func(ctx context.Context, _ *asynq.Task) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	client := scheduler.NewClientWithBackend(scheduler.NewInlineBackend(mux, scheduler.InlineConfig{Concurrency: 1}), pool)
+
+	taskID, err := client.EnqueueWithID(ctx, "test:cancel", nil, asynq.MaxRetry(0))
+	if err != nil {
+		t.Fatalf("EnqueueWithID() = %v", err)
+	}
+	<-started
+
+	// Cancel like the API does: mark the job, then cancel the running handler
+	jobID, _ := db.JobID(taskID)
+	if _, err := queries.MarkJobCancelled(ctx, jobID); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.CancelProcessing(ctx, taskID); err != nil {
+		t.Fatalf("CancelProcessing() = %v", err)
+	}
+	client.Close()
+
+	// Revoked rather than archived, although it was the last attempt
+	if info, err := client.TaskInfo(ctx, "default", taskID); !errors.Is(err, asynq.ErrTaskNotFound) {
+		t.Errorf("TaskInfo() = %+v, %v; want the task revoked", info, err)
+	}
+	job, err := queries.GetJob(ctx, jobID)
+	if err != nil || job.Status != "cancelled" {
+		t.Errorf("job = %s, %v; want cancelled", job.Status, err)
+	}
+}
+
+func TestWorkflowMiddlewareReportsFinalOutcome(t *testing.T) {
+	tests := []struct {
+		name       string
+		message    string // makes step a retry or fail for good, see the handler below
+		wantStatus string
+		wantSteps  map[string]string
+	}{
+		{name: "completed", wantStatus: workflow.StatusCompleted, wantSteps: map[string]string{"a": "completed", "b": "completed"}},
+		{name: "failed with retries left", message: "retry", wantStatus: workflow.StatusRunning, wantSteps: map[string]string{"a": "enqueued", "b": "waiting"}},
+		{name: "failed for good", message: "fail", wantStatus: workflow.StatusFailed, wantSteps: map[string]string{"a": "failed", "b": "cancelled"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := dbtest.Pool(t)
+			queries := db.New(pool)
+			ctx := context.Background()
+
+			mux := asynq.NewServeMux()
+			tasks.WorkerPing.Handle(mux, func(_ context.Context, _ *asynq.Task, p tasks.PingPayload) error {
+				switch p.Message {
+				case "retry":
+					return errors.New("step failed")
+				case "fail":
+					return tasks.Permanent(errors.New("step failed"))
+				}
+				return nil
+			})
+			engine := workflow.NewEngine(pool, newTestClient(t, pool, mux, 0))
+			mux.Use(workflowMiddleware(engine))
+
+			payload, _ := json.Marshal(tasks.PingPayload{Message: tt.message})
+			id, err := engine.Start(ctx, workflow.Definition{
+				Name: "steps",
+				Steps: []workflow.Step{
+					{Name: "a", TaskType: tasks.TypeWorkerPing, Payload: payload},
+					{Name: "b", TaskType: tasks.TypeWorkerPing, DependsOn: []string{"a"}},
+				},
+			})
+			if err != nil {
+				t.Fatalf("Start() = %v", err)
+			}
+
+			uid, _ := db.ParseUUID(id)
+			wf, err := queries.GetWorkflow(ctx, uid)
+			if err != nil {
+				t.Fatalf("GetWorkflow() = %v", err)
+			}
+			if wf.Status != tt.wantStatus {
+				t.Errorf("workflow status = %q, want %q", wf.Status, tt.wantStatus)
+			}
+			steps, err := queries.ListWorkflowSteps(ctx, uid)
+			if err != nil {
+				t.Fatalf("ListWorkflowSteps() = %v", err)
+			}
+			for _, step := range steps {
+				if step.Status != tt.wantSteps[step.Name] {
+					t.Errorf("step %s status = %q, want %q", step.Name, step.Status, tt.wantSteps[step.Name])
+				}
+			}
+		})
+	}
+}
+
+func TestWebhookMiddlewareNotifiesFinishedTasks(t *testing.T) {
+	failed := errors.New("failed")
+	tests := []struct {
+		name      string
+		opts      []asynq.Option
+		err       error
+		wantEvent string // empty when no notification is due
+	}{
+		{name: "completed", wantEvent: webhook.EventCompleted},
+		{name: "failed with retries left", opts: []asynq.Option{asynq.MaxRetry(3)}, err: failed},
+		{name: "failed on last attempt", opts: []asynq.Option{asynq.MaxRetry(0)}, err: failed, wantEvent: webhook.EventFailed},
+		{name: "revoked", opts: []asynq.Option{asynq.MaxRetry(3)}, err: asynq.RevokeTask, wantEvent: webhook.EventCancelled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := dbtest.Pool(t)
+			queries := db.New(pool)
+			ctx := context.Background()
+
+			var deliveries []string
+			mux := asynq.NewServeMux()
+			mux.HandleFunc("test:notify", func(ctx context.Context, task *asynq.Task) error {
+				if tt.err != nil {
+					return tt.err
+				}
+				return scheduler.WriteResult(ctx, task, []byte(`{"sent":3}`))
+			})
+			tasks.WebhookDeliver.Handle(mux, func(_ context.Context, _ *asynq.Task, p tasks.WebhookPayload) error {
+				deliveries = append(deliveries, p.DeliveryID)
+				return nil
+			})
+			client := newTestClient(t, pool, mux, 0)
+			mux.Use(webhookMiddleware(webhook.NewNotifier(queries, client)))
+
+			headerCtx := scheduler.WithTaskHeaders(ctx, map[string]string{webhook.HeaderCallbackURL: "https://example.com/hooks"})
+			taskID, err := client.EnqueueWithID(headerCtx, "test:notify", nil, tt.opts...)
+			if err != nil {
+				t.Fatalf("EnqueueWithID() = %v", err)
+			}
+
+			jobID, _ := db.JobID(taskID)
+			rows, err := queries.ListWebhookDeliveriesByTask(ctx, jobID)
+			if err != nil {
+				t.Fatalf("ListWebhookDeliveriesByTask() = %v", err)
+			}
+			if tt.wantEvent == "" {
+				if len(rows) != 0 || len(deliveries) != 0 {
+					t.Errorf("deliveries = %d recorded, %d enqueued; want none", len(rows), len(deliveries))
+				}
+				return
+			}
+			if len(rows) != 1 || len(deliveries) != 1 || deliveries[0] != rows[0].ID.String() {
+				t.Fatalf("deliveries = %d recorded, %v enqueued; want one of each", len(rows), deliveries)
+			}
+			d := rows[0]
+			var n webhook.Notification
+			if err := json.Unmarshal(d.Payload, &n); err != nil {
+				t.Fatal(err)
+			}
+			if d.Event != tt.wantEvent || d.CallbackUrl != "https://example.com/hooks" || n.TaskID != taskID || n.Status != tt.wantEvent {
+				t.Errorf("delivery = %s to %s with %+v, want %s for task %s", d.Event, d.CallbackUrl, n, tt.wantEvent, taskID)
+			}
+			if tt.wantEvent == webhook.EventCompleted {
+				if result, _ := json.Marshal(n.Result); string(result) != `{"sent":3}` {
+					t.Errorf("notification result = %s, want the task result", result)
+				}
+			} else if n.Error == "" {
+				t.Error("notification error not set")
+			}
+		})
+	}
+}
+
+func TestPayloadMiddleware(t *testing.T) {
+	failed := errors.New("failed")
+	tests := []struct {
+		name     string
+		opts     []asynq.Option
+		err      error
+		wantKept bool
+	}{
+		{name: "completed"},
+		{name: "failed with retries left", opts: []asynq.Option{asynq.MaxRetry(3)}, err: failed, wantKept: true},
+		// Archived tasks can be re-run, so their payload is kept
+		{name: "failed on last attempt", opts: []asynq.Option{asynq.MaxRetry(0)}, err: failed, wantKept: true},
+		{name: "revoked", opts: []asynq.Option{asynq.MaxRetry(3)}, err: asynq.RevokeTask},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := dbtest.Pool(t)
+			ctx := context.Background()
+			store, err := claimcheck.NewDiskStore(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var loaded []byte
+			mux := asynq.NewServeMux()
+			mux.Use(payloadMiddleware(store))
+			mux.HandleFunc("test:large", func(ctx context.Context, task *asynq.Task) error {
+				if len(task.Payload()) != 0 {
+					t.Errorf("task payload = %q, want it offloaded", task.Payload())
+				}
+				var err error
+				if loaded, err = claimcheck.Payload(ctx, task); err != nil {
+					t.Errorf("claimcheck.Payload() = %v", err)
+				}
+				return tt.err
+			})
+			client := newTestClient(t, pool, mux, 0)
+			client.OffloadPayloads(store, 4)
+
+			payload := []byte(`{"users":[1,2,3]}`)
+			taskID, err := client.EnqueueWithID(ctx, "test:large", payload, tt.opts...)
+			if err != nil {
+				t.Fatalf("EnqueueWithID() = %v", err)
+			}
+			if string(loaded) != string(payload) {
+				t.Errorf("handler loaded %q, want %q", loaded, payload)
+			}
+
+			_, err = store.Get(ctx, taskID)
+			if kept := err == nil; kept != tt.wantKept {
+				t.Errorf("payload kept = %v (%v), want %v", kept, err, tt.wantKept)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"boiler-go/internal/progress"
+	"boiler-go/internal/scheduler"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestProgressMiddleware(t *testing.T) {
+	failed := errors.New("failed")
+	tests := []struct {
+		name      string
+		opts      []asynq.Option
+		err       error
+		wantEvent string
+	}{
+		{name: "completed", wantEvent: progress.EventCompleted},
+		{name: "failed with retries left", opts: []asynq.Option{asynq.MaxRetry(3)}, err: failed, wantEvent: progress.EventRetrying},
+		{name: "failed on last attempt", opts: []asynq.Option{asynq.MaxRetry(0)}, err: failed, wantEvent: progress.EventFailed},
+		{name: "revoked", opts: []asynq.Option{asynq.MaxRetry(3)}, err: asynq.RevokeTask, wantEvent: progress.EventCancelled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mr := miniredis.RunT(t)
+			rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+			t.Cleanup(func() { rdb.Close() })
+			store := progress.NewStore(rdb, time.Hour)
+			ctx := context.Background()
+
+			mux := asynq.NewServeMux()
+			mux.Use(progressMiddleware(store))
+			mux.HandleFunc("test:progress", func(ctx context.Context, _ *asynq.Task) error {
+				if err := progress.Report(ctx, progress.Update{Percent: 50, Stage: "halfway"}); err != nil {
+					t.Errorf("Report() = %v", err)
+				}
+				return tt.err
+			})
+			b := scheduler.NewInlineBackend(mux, scheduler.InlineConfig{
+				RetryDelayFunc: func(int, error, *asynq.Task) time.Duration { return time.Hour },
+			})
+			t.Cleanup(func() { b.Close() })
+
+			sub, err := store.Subscribe(ctx, "a")
+			if err != nil {
+				t.Fatalf("Subscribe() = %v", err)
+			}
+			defer sub.Close()
+
+			opts := append([]asynq.Option{asynq.TaskID("a")}, tt.opts...)
+			if _, err := b.Enqueue(ctx, asynq.NewTask("test:progress", nil), opts...); err != nil {
+				t.Fatalf("Enqueue() = %v", err)
+			}
+
+			// The handler's report, then the outcome of the attempt
+			for _, want := range []string{progress.EventProgress, tt.wantEvent} {
+				select {
+				case e := <-sub.Events():
+					if e.Type != want {
+						t.Fatalf("event = %+v, want %s", e, want)
+					}
+					if e.Type == progress.EventCompleted && e.Percent != 100 {
+						t.Errorf("completed at %d%%, want 100%%", e.Percent)
+					}
+					if (e.Type == progress.EventFailed || e.Type == progress.EventRetrying) && e.Error != failed.Error() {
+						t.Errorf("event error = %q, want %q", e.Error, failed)
+					}
+				case <-time.After(5 * time.Second):
+					t.Fatalf("no %s event received", want)
+				}
+			}
+		})
+	}
+}
+
+func TestProgressMiddlewareCanceled(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	store := progress.NewStore(rdb, time.Hour)
+	ctx := context.Background()
+
+	started := make(chan struct{})
+	mux := asynq.NewServeMux()
+	mux.Use(progressMiddleware(store))
+	mux.HandleFunc("test:progress", func(ctx context.Context, _ *asynq.Task) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	b := scheduler.NewInlineBackend(mux, scheduler.InlineConfig{
+		Concurrency:    1,
+		RetryDelayFunc: func(int, error, *asynq.Task) time.Duration { return time.Hour },
+	})
+
+	if _, err := b.Enqueue(ctx, asynq.NewTask("test:progress", nil), asynq.TaskID("a"), asynq.MaxRetry(3)); err != nil {
+		t.Fatalf("Enqueue() = %v", err)
+	}
+	<-started
+	if err := b.CancelProcessing(ctx, "a"); err != nil {
+		t.Fatalf("CancelProcessing() = %v", err)
+	}
+	b.Close()
+
+	// The API publishes the cancelled event, so the worker does not report a retry
+	if e, ok, err := store.Latest(ctx, "a"); ok || err != nil {
+		t.Errorf("Latest() = %+v, %v, %v; want no event", e, ok, err)
+	}
+}
@@ -0,0 +1,124 @@
+package worker
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"boiler-go/internal/metrics"
+	"boiler-go/internal/ratelimit"
+	"boiler-go/internal/scheduler"
+	"boiler-go/internal/tasks"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/hibiken/asynq"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/redis/go-redis/v9"
+)
+
+// newLimitedBackend returns an inline backend running every task type through
+// rateLimitMiddleware, counting the runs of each type in runs.
+func newLimitedBackend(t *testing.T, rdb *redis.Client, rules map[string]ratelimit.Rule, reg prometheus.Registerer, runs map[string]*atomic.Int32) *scheduler.InlineBackend {
+	t.Helper()
+	mux := asynq.NewServeMux()
+	mux.Use(rateLimitMiddleware(ratelimit.NewLimiter(rdb), rules, metrics.NewTaskMetrics(reg)))
+	for taskType := range runs {
+		mux.HandleFunc(taskType, func(context.Context, *asynq.Task) error {
+			runs[taskType].Add(1)
+			return nil
+		})
+	}
+	b := scheduler.NewInlineBackend(mux, scheduler.InlineConfig{
+		RetryDelayFunc: tasks.RetryDelay,
+		IsFailure:      tasks.IsFailure,
+	})
+	t.Cleanup(func() { b.Close() })
+	return b
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	reg := prometheus.NewRegistry()
+	runs := map[string]*atomic.Int32{"test:limited": {}, "test:free": {}}
+	b := newLimitedBackend(t, rdb, map[string]ratelimit.Rule{"test:limited": {Limit: 1, Period: time.Hour}}, reg, runs)
+	ctx := context.Background()
+
+	for _, id := range []string{"a", "b"} {
+		if _, err := b.Enqueue(ctx, asynq.NewTask("test:limited", nil), asynq.TaskID(id), asynq.MaxRetry(3)); err != nil {
+			t.Fatalf("Enqueue() = %v", err)
+		}
+	}
+	if _, err := b.Enqueue(ctx, asynq.NewTask("test:free", nil), asynq.TaskID("c")); err != nil {
+		t.Fatalf("Enqueue() = %v", err)
+	}
+	if runs["test:limited"].Load() != 1 || runs["test:free"].Load() != 1 {
+		t.Fatalf("runs = %d limited, %d free; want 1 each", runs["test:limited"].Load(), runs["test:free"].Load())
+	}
+
+	// The task over the limit is pushed back until a token is due, without using a retry
+	info, err := b.GetTaskInfo(ctx, "default", "b")
+	if err != nil {
+		t.Fatalf("GetTaskInfo() = %v", err)
+	}
+	if info.State != asynq.TaskStateRetry || info.Retried != 0 || time.Until(info.NextProcessAt) < 50*time.Minute {
+		t.Errorf("task = %s retried %d next at %v, want a retry in about an hour that is not counted", info.State, info.Retried, info.NextProcessAt)
+	}
+	want := `
+# HELP boiler_task_rate_limited_total Task attempts pushed back because their task type was over its rate limit.
+# TYPE boiler_task_rate_limited_total counter
+boiler_task_rate_limited_total{queue="default",task_type="test:limited"} 1
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want), "boiler_task_rate_limited_total"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRateLimitMiddlewareLastAttempt(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	runs := map[string]*atomic.Int32{"test:limited": {}}
+	rule := ratelimit.Rule{Limit: 1, Period: 50 * time.Millisecond}
+	b := newLimitedBackend(t, rdb, map[string]ratelimit.Rule{"test:limited": rule}, prometheus.NewRegistry(), runs)
+	ctx := context.Background()
+
+	// asynq would archive a rate limited task with no retries left, so it waits for a token
+	start := time.Now()
+	for _, id := range []string{"a", "b"} {
+		if _, err := b.Enqueue(ctx, asynq.NewTask("test:limited", nil), asynq.TaskID(id), asynq.MaxRetry(0)); err != nil {
+			t.Fatalf("Enqueue() = %v", err)
+		}
+	}
+	if runs["test:limited"].Load() != 2 {
+		t.Errorf("runs = %d, want both tasks run", runs["test:limited"].Load())
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("tasks ran within %v, want the second to wait about 50ms for a token", elapsed)
+	}
+}
+
+func TestRateLimitMiddlewareRedisDown(t *testing.T) {
+	mr := miniredis.RunT(t)
+	// One dial attempt, so the stopped server fails fast
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr(), DialerRetries: 1, MaxRetries: -1})
+	t.Cleanup(func() { rdb.Close() })
+	runs := map[string]*atomic.Int32{"test:limited": {}}
+	b := newLimitedBackend(t, rdb, map[string]ratelimit.Rule{"test:limited": {Limit: 1, Period: time.Hour}}, prometheus.NewRegistry(), runs)
+	mr.Close()
+	ctx := context.Background()
+
+	// An unavailable limiter lets tasks run rather than stall their task type
+	for _, opt := range []asynq.Option{asynq.MaxRetry(3), asynq.MaxRetry(0)} {
+		if _, err := b.Enqueue(ctx, asynq.NewTask("test:limited", nil), opt); err != nil {
+			t.Fatalf("Enqueue() = %v", err)
+		}
+	}
+	if runs["test:limited"].Load() != 2 {
+		t.Errorf("runs = %d, want both tasks run", runs["test:limited"].Load())
+	}
+}
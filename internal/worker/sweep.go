@@ -0,0 +1,40 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"boiler-go/internal/scheduler"
+	"boiler-go/pkg/logger"
+)
+
+// payloadSweepAge is how old an offloaded payload must be before it is swept, so the
+// payload of an enqueue still in flight is never taken for an orphan.
+const payloadSweepAge = time.Hour
+
+// SweepPayloads deletes the offloaded payloads whose task is gone every interval until
+// ctx is done, see scheduler.Client.SweepPayloads. A zero interval disables it.
+func SweepPayloads(ctx context.Context, client *scheduler.Client, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	log := logger.FromContext(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		n, err := client.SweepPayloads(ctx, time.Now().Add(-payloadSweepAge))
+		if err != nil && ctx.Err() == nil {
+			log.Error().Err(err).Msg("failed to sweep offloaded payloads")
+		}
+		if n > 0 {
+			log.Info().Int("deleted", n).Msg("orphaned offloaded payloads deleted")
+		}
+	}
+}
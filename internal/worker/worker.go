@@ -0,0 +1,141 @@
+// Package worker holds the task middlewares and handlers run by cmd/worker, and by any
+// process that runs tasks in-process with QUEUE_MODE=inline.
+package worker
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"boiler-go/internal/claimcheck"
+	"boiler-go/internal/db"
+	"boiler-go/internal/metrics"
+	"boiler-go/internal/progress"
+	"boiler-go/internal/ratelimit"
+	"boiler-go/internal/scheduler"
+	"boiler-go/internal/tasks"
+	"boiler-go/internal/tracing"
+	"boiler-go/internal/webhook"
+	"boiler-go/internal/workflow"
+	"boiler-go/pkg/logger"
+
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+)
+
+// jobWriteTimeout bounds each jobs table update made by jobsMiddleware.
+const jobWriteTimeout = 5 * time.Second
+
+// Deps are the services used by the task middlewares and handlers.
+type Deps struct {
+	Logger zerolog.Logger
+	Pool   *pgxpool.Pool
+	// Client enqueues the next steps of workflows and webhook deliveries
+	Client *scheduler.Client
+	// Payloads is the store of offloaded payloads, shared with the API
+	Payloads claimcheck.Store
+	// Redis holds the rate limit buckets and progress events. When nil, rate limits
+	// are not enforced and progress is not published.
+	Redis       *redis.Client
+	RateLimits  map[string]ratelimit.Rule
+	ProgressTTL time.Duration
+	Metrics     *metrics.TaskMetrics
+
+	WebhookSecret  string
+	WebhookTimeout time.Duration
+	// WebhookAllowPrivate lets webhooks reach loopback, private and link-local addresses
+	WebhookAllowPrivate bool
+}
+
+// Register adds the task middlewares and handlers to mux.
+func Register(mux *asynq.ServeMux, d Deps) {
+	workflows := workflow.NewEngine(d.Pool, d.Client)
+	notifier := webhook.NewNotifier(db.New(d.Pool), d.Client)
+
+	// Add logging middleware, which also puts the task-scoped logger and span into the context
+	mux.Use(loggingMiddleware(d.Logger))
+	// Advance workflows once a step task has finished for good
+	mux.Use(workflowMiddleware(workflows))
+	// Notify the callback URL of tasks that have finished for good
+	mux.Use(webhookMiddleware(notifier))
+	// Load offloaded payloads on demand, and delete them once their task has finished for good
+	mux.Use(payloadMiddleware(d.Payloads))
+	if d.Redis != nil {
+		// Push back tasks whose type is over its rate limit, before they are marked active
+		mux.Use(rateLimitMiddleware(ratelimit.NewLimiter(d.Redis), d.RateLimits, d.Metrics))
+		// Let handlers report progress, and publish the outcome of each attempt to live subscribers
+		mux.Use(progressMiddleware(progress.NewStore(d.Redis, d.ProgressTTL)))
+	}
+	// Finish the jobs rows of the tasks aggregated into a task, once it is finished itself
+	mux.Use(groupMiddleware(db.New(d.Pool)))
+	// Record task lifecycle in the jobs table
+	mux.Use(jobsMiddleware(db.New(d.Pool)))
+	// Record per-task-type counts, handler duration and queue wait time
+	mux.Use(metricsMiddleware(d.Metrics))
+
+	// worker ping handler - used by API to verify worker is alive
+	tasks.WorkerPing.Handle(mux, func(ctx context.Context, t *asynq.Task, payload tasks.PingPayload) error {
+		log := logger.FromContext(ctx)
+		if err := progress.Report(ctx, progress.Update{Percent: 50, Stage: "processing"}); err != nil {
+			log.Warn().Err(err).Msg("failed to report progress")
+		}
+
+		log.Info().
+			Str("payload", payload.Message).
+			Msg("worker ping task processed - worker is alive!")
+
+		hostname, _ := os.Hostname()
+		return tasks.WriteResult(ctx, t, tasks.PingResult{
+			Message:     payload.Message,
+			Hostname:    hostname,
+			ProcessedAt: time.Now().UTC(),
+		})
+	})
+
+	// user change handlers - changes are normally grouped and synced in batches, see Aggregator
+	tasks.UserUpdated.Handle(mux, func(ctx context.Context, _ *asynq.Task, payload tasks.UserUpdatedPayload) error {
+		return syncUsers(ctx, tasks.UserSyncPayload{UserIDs: []string{payload.UserID}})
+	})
+	tasks.UserSync.Handle(mux, func(ctx context.Context, _ *asynq.Task, payload tasks.UserSyncPayload) error {
+		return syncUsers(ctx, payload)
+	})
+
+	// webhook delivery handler - sends completion notifications queued by webhookMiddleware
+	deliverer := webhook.NewDeliverer(db.New(d.Pool), d.WebhookSecret, d.WebhookTimeout, d.WebhookAllowPrivate)
+	tasks.WebhookDeliver.Handle(mux, deliverer.Deliver)
+}
+
+// ErrorHandler logs the tasks that failed, with their request ID.
+func ErrorHandler(logg zerolog.Logger) asynq.ErrorHandler {
+	return asynq.ErrorHandlerFunc(func(ctx context.Context, task *asynq.Task, err error) {
+		// Already logged by rateLimitMiddleware
+		if !tasks.IsFailure(err) {
+			return
+		}
+		taskID, ok := scheduler.TaskID(ctx)
+		if !ok {
+			taskID = "unknown"
+		}
+		logEvent := logg.Error().
+			Err(err).
+			Str("task_type", task.Type()).
+			Str("task_id", taskID)
+		if requestID := task.Headers()[tracing.TaskHeaderRequestID]; requestID != "" {
+			logEvent.Str("request_id", requestID)
+		}
+		logEvent.Msg("task processing failed")
+	})
+}
+
+// syncUsers sends a batch of changed users downstream. It is the place for the single
+// downstream call the batch is made for; here it only logs the batch.
+func syncUsers(ctx context.Context, batch tasks.UserSyncPayload) error {
+	log := logger.FromContext(ctx)
+	log.Info().
+		Str("group", batch.Group).
+		Int("users", len(batch.UserIDs)).
+		Msg("user changes synced")
+	return nil
+}
@@ -0,0 +1,317 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"boiler-go/internal/db"
+	"boiler-go/internal/scheduler"
+	"boiler-go/internal/tasks"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// HeaderWorkflowID is the task header carrying the workflow a step task belongs to.
+	HeaderWorkflowID = "workflow_id"
+	// HeaderWorkflowStep is the task header carrying the step name of a step task.
+	HeaderWorkflowStep = "workflow_step"
+)
+
+// Workflow status values.
+const (
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+// Step status values, see the workflow_steps queries.
+const (
+	stepWaiting   = "waiting"
+	stepCompleted = "completed"
+)
+
+// Engine persists workflows and enqueues their steps.
+// Transitions of a workflow are serialized by locking its row, so parallel
+// steps finishing at the same time enqueue each dependent step exactly once.
+type Engine struct {
+	pool    *pgxpool.Pool
+	queries *db.Queries
+	client  *scheduler.Client
+}
+
+// NewEngine creates a workflow engine that enqueues steps with client.
+func NewEngine(pool *pgxpool.Pool, client *scheduler.Client) *Engine {
+	return &Engine{
+		pool:    pool,
+		queries: db.New(pool),
+		client:  client,
+	}
+}
+
+// plannedTask is a step or compensation task claimed in the database and waiting to be enqueued.
+type plannedTask struct {
+	step         string
+	taskType     string
+	payload      []byte
+	taskID       string
+	compensation bool
+}
+
+// stepResult is the final outcome of a step task.
+type stepResult struct {
+	step string
+	err  error
+}
+
+// StepFromHeaders returns the workflow and step a task runs, if it is a workflow step.
+func StepFromHeaders(headers map[string]string) (workflowID, step string, ok bool) {
+	workflowID, step = headers[HeaderWorkflowID], headers[HeaderWorkflowStep]
+	return workflowID, step, workflowID != "" && step != ""
+}
+
+// Start persists the workflow and enqueues the steps without dependencies.
+// If a root step cannot be enqueued the workflow fails, and its ID is returned with the error.
+func (e *Engine) Start(ctx context.Context, def Definition) (string, error) {
+	if err := def.Validate(); err != nil {
+		return "", err
+	}
+
+	tx, err := e.pool.Begin(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(context.WithoutCancel(ctx))
+	q := e.queries.WithTx(tx)
+
+	wf, err := q.CreateWorkflow(ctx, db.CreateWorkflowParams{
+		Name:      def.Name,
+		OnFailure: string(def.policy()),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create workflow: %w", err)
+	}
+
+	for i, step := range def.Steps {
+		params := db.CreateWorkflowStepParams{
+			WorkflowID: wf.ID,
+			Name:       step.Name,
+			Position:   int32(i),
+			TaskType:   step.TaskType,
+			Payload:    db.JobPayload(step.Payload),
+			DependsOn:  append([]string{}, step.DependsOn...),
+		}
+		if c := step.Compensate; c != nil {
+			params.CompensateTaskType = pgtype.Text{String: c.TaskType, Valid: true}
+			params.CompensatePayload = db.JobPayload(c.Payload)
+		}
+		if err := q.CreateWorkflowStep(ctx, params); err != nil {
+			return "", fmt.Errorf("failed to create step %q: %w", step.Name, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", fmt.Errorf("failed to commit workflow: %w", err)
+	}
+
+	return wf.ID.String(), e.advance(ctx, wf.ID, nil)
+}
+
+// StepFinished records the final outcome of a step task and advances the workflow.
+// Call it when the task succeeded (taskErr nil) or failed with no retries left.
+// Calling it again for the same outcome is harmless, so at-least-once delivery is fine.
+func (e *Engine) StepFinished(ctx context.Context, workflowID, step string, taskErr error) error {
+	id, err := db.ParseUUID(workflowID)
+	if err != nil {
+		return fmt.Errorf("invalid workflow ID %q: %w", workflowID, err)
+	}
+	return e.advance(ctx, id, &stepResult{step: step, err: taskErr})
+}
+
+// advance applies finished, if any, then enqueues everything the transition planned.
+// A step that cannot be enqueued fails the workflow like a failed task would.
+func (e *Engine) advance(ctx context.Context, id pgtype.UUID, finished *stepResult) error {
+	planned, err := e.transition(ctx, id, finished)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, p := range planned {
+		err := e.enqueue(ctx, id, p)
+		if err == nil {
+			continue
+		}
+		if p.compensation {
+			errs = append(errs, fmt.Errorf("failed to enqueue compensation of step %q: %w", p.step, err))
+			continue
+		}
+		err = fmt.Errorf("failed to enqueue step %q: %w", p.step, err)
+		errs = append(errs, err)
+		if failErr := e.advance(ctx, id, &stepResult{step: p.step, err: err}); failErr != nil {
+			errs = append(errs, failErr)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// transition updates the workflow under its row lock and claims the tasks to enqueue next.
+func (e *Engine) transition(ctx context.Context, id pgtype.UUID, finished *stepResult) ([]plannedTask, error) {
+	tx, err := e.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(context.WithoutCancel(ctx))
+	q := e.queries.WithTx(tx)
+
+	wf, err := q.LockWorkflow(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock workflow: %w", err)
+	}
+	steps, err := q.ListWorkflowSteps(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load workflow steps: %w", err)
+	}
+	byName := make(map[string]*db.WorkflowStep, len(steps))
+	for i := range steps {
+		byName[steps[i].Name] = &steps[i]
+	}
+
+	compensate := wf.OnFailure == string(FailureCompensate)
+	var planned []plannedTask
+
+	if finished != nil {
+		step, ok := byName[finished.step]
+		if !ok {
+			return nil, fmt.Errorf("workflow has no step %q", finished.step)
+		}
+		key := db.MarkWorkflowStepCompletedParams{WorkflowID: id, Name: step.Name}
+
+		if finished.err == nil {
+			if err := q.MarkWorkflowStepCompleted(ctx, key); err != nil {
+				return nil, fmt.Errorf("failed to complete step: %w", err)
+			}
+			step.Status = stepCompleted
+			// A step still running when the workflow failed is compensated as soon as it completes
+			if wf.Status == StatusFailed && compensate && step.CompensateTaskType.Valid {
+				planned = append(planned, compensation(step))
+			}
+		} else {
+			if err := q.MarkWorkflowStepFailed(ctx, db.MarkWorkflowStepFailedParams(key)); err != nil {
+				return nil, fmt.Errorf("failed to fail step: %w", err)
+			}
+			if wf.Status == StatusRunning {
+				if err := q.MarkWorkflowFailed(ctx, db.MarkWorkflowFailedParams{
+					ID:        id,
+					LastError: pgtype.Text{String: fmt.Sprintf("step %q failed: %v", step.Name, finished.err), Valid: true},
+				}); err != nil {
+					return nil, fmt.Errorf("failed to fail workflow: %w", err)
+				}
+				if err := q.CancelWaitingWorkflowSteps(ctx, id); err != nil {
+					return nil, fmt.Errorf("failed to cancel waiting steps: %w", err)
+				}
+				wf.Status = StatusFailed
+				if compensate {
+					for i := range steps {
+						if steps[i].Status == stepCompleted && steps[i].CompensateTaskType.Valid {
+							planned = append(planned, compensation(&steps[i]))
+						}
+					}
+				}
+			}
+		}
+	}
+
+	if wf.Status == StatusRunning {
+		done := true
+		for i := range steps {
+			step := &steps[i]
+			if step.Status != stepCompleted {
+				done = false
+			}
+			if step.Status == stepWaiting && dependenciesCompleted(step, byName) {
+				planned = append(planned, plannedTask{
+					step:     step.Name,
+					taskType: step.TaskType,
+					payload:  step.Payload,
+				})
+			}
+		}
+		if done {
+			if err := q.MarkWorkflowCompleted(ctx, id); err != nil {
+				return nil, fmt.Errorf("failed to complete workflow: %w", err)
+			}
+		}
+	}
+
+	// Claim each task before committing, so a concurrent transition never plans it again
+	for i := range planned {
+		p := &planned[i]
+		p.taskID = uuid.NewString()
+		jobID, _ := db.JobID(p.taskID)
+		if p.compensation {
+			err = q.MarkWorkflowStepCompensating(ctx, db.MarkWorkflowStepCompensatingParams{
+				WorkflowID:        id,
+				Name:              p.step,
+				CompensationJobID: jobID,
+			})
+		} else {
+			err = q.MarkWorkflowStepEnqueued(ctx, db.MarkWorkflowStepEnqueuedParams{
+				WorkflowID: id,
+				Name:       p.step,
+				JobID:      jobID,
+			})
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to claim step %q: %w", p.step, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit workflow transition: %w", err)
+	}
+	return planned, nil
+}
+
+// enqueue hands a claimed task to the scheduler with the task type's registered defaults.
+// Step tasks are tagged with workflow headers so the worker reports their outcome;
+// compensation tasks are not, since their outcome does not advance the workflow.
+func (e *Engine) enqueue(ctx context.Context, id pgtype.UUID, p plannedTask) error {
+	opts, ok := tasks.DefaultOptions(p.taskType)
+	if !ok {
+		return fmt.Errorf("task type %q is not registered", p.taskType)
+	}
+	opts = append(opts, asynq.TaskID(p.taskID))
+
+	if !p.compensation {
+		ctx = scheduler.WithTaskHeaders(ctx, map[string]string{
+			HeaderWorkflowID:   id.String(),
+			HeaderWorkflowStep: p.step,
+		})
+	}
+
+	_, err := e.client.EnqueueWithID(ctx, p.taskType, p.payload, opts...)
+	return err
+}
+
+func compensation(step *db.WorkflowStep) plannedTask {
+	return plannedTask{
+		step:         step.Name,
+		taskType:     step.CompensateTaskType.String,
+		payload:      step.CompensatePayload,
+		compensation: true,
+	}
+}
+
+func dependenciesCompleted(step *db.WorkflowStep, byName map[string]*db.WorkflowStep) bool {
+	for _, dep := range step.DependsOn {
+		if parent, ok := byName[dep]; !ok || parent.Status != stepCompleted {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,180 @@
+//go:build integration
+
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+
+	"boiler-go/internal/db"
+	"boiler-go/internal/dbtest"
+	"boiler-go/internal/scheduler"
+	"boiler-go/internal/tasks"
+
+	"github.com/hibiken/asynq"
+)
+
+// stepRunner runs worker:ping tasks in-process: it records the step, or the message of
+// compensation tasks, and fails the steps listed in fail for good.
+type stepRunner struct {
+	fail map[string]bool
+
+	mu  sync.Mutex
+	ran []string
+}
+
+func (r *stepRunner) handle(_ context.Context, t *asynq.Task, payload tasks.PingPayload) error {
+	_, step, ok := StepFromHeaders(t.Headers())
+	if !ok {
+		step = "compensate:" + payload.Message
+	}
+	r.mu.Lock()
+	r.ran = append(r.ran, step)
+	r.mu.Unlock()
+	if r.fail[step] {
+		return tasks.Permanent(errors.New("step failed"))
+	}
+	return nil
+}
+
+// newTestEngine returns an engine whose steps run inline with r, reporting their outcome
+// like the worker's workflowMiddleware does.
+func newTestEngine(t *testing.T, r *stepRunner) (*Engine, *db.Queries) {
+	t.Helper()
+	pool := dbtest.Pool(t)
+
+	var engine *Engine
+	mux := asynq.NewServeMux()
+	mux.Use(func(next asynq.Handler) asynq.Handler {
+		return asynq.HandlerFunc(func(ctx context.Context, task *asynq.Task) error {
+			err := next.ProcessTask(ctx, task)
+			if workflowID, step, ok := StepFromHeaders(task.Headers()); ok {
+				if advanceErr := engine.StepFinished(ctx, workflowID, step, err); advanceErr != nil {
+					t.Errorf("StepFinished(%s) = %v", step, advanceErr)
+				}
+			}
+			return err
+		})
+	})
+	tasks.WorkerPing.Handle(mux, r.handle)
+
+	client := scheduler.NewClientWithBackend(scheduler.NewInlineBackend(mux, scheduler.InlineConfig{}), pool)
+	t.Cleanup(func() { client.Close() })
+	engine = NewEngine(pool, client)
+	return engine, db.New(pool)
+}
+
+func workflowStatus(t *testing.T, queries *db.Queries, id string) (db.Workflow, map[string]string) {
+	t.Helper()
+	uid, err := db.ParseUUID(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	wf, err := queries.GetWorkflow(ctx, uid)
+	if err != nil {
+		t.Fatalf("GetWorkflow() = %v", err)
+	}
+	steps, err := queries.ListWorkflowSteps(ctx, uid)
+	if err != nil {
+		t.Fatalf("ListWorkflowSteps() = %v", err)
+	}
+	status := make(map[string]string, len(steps))
+	for _, step := range steps {
+		status[step.Name] = step.Status
+	}
+	return wf, status
+}
+
+func TestEngineRunsDiamond(t *testing.T) {
+	r := &stepRunner{}
+	engine, queries := newTestEngine(t, r)
+	ping := tasks.TypeWorkerPing
+
+	// Steps without a payload run with the zero payload
+	id, err := engine.Start(context.Background(), Definition{
+		Name: "diamond",
+		Steps: []Step{
+			{Name: "a", TaskType: ping},
+			{Name: "b", TaskType: ping, DependsOn: []string{"a"}, Payload: json.RawMessage(`{"message":"b"}`)},
+			{Name: "c", TaskType: ping, DependsOn: []string{"a"}},
+			{Name: "d", TaskType: ping, DependsOn: []string{"b", "c"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Start() = %v", err)
+	}
+
+	if len(r.ran) != 4 || r.ran[0] != "a" || r.ran[3] != "d" {
+		t.Errorf("ran %v, want a first, d last and each step once", r.ran)
+	}
+	wf, steps := workflowStatus(t, queries, id)
+	if wf.Status != StatusCompleted {
+		t.Errorf("workflow status = %q, want %q (last error %q)", wf.Status, StatusCompleted, wf.LastError.String)
+	}
+	for name, status := range steps {
+		if status != stepCompleted {
+			t.Errorf("step %s status = %q, want %q", name, status, stepCompleted)
+		}
+	}
+}
+
+func TestEngineCompensatesOnFailure(t *testing.T) {
+	r := &stepRunner{fail: map[string]bool{"b": true}}
+	engine, queries := newTestEngine(t, r)
+	ping := tasks.TypeWorkerPing
+
+	id, err := engine.Start(context.Background(), Definition{
+		Name:      "compensated",
+		OnFailure: FailureCompensate,
+		Steps: []Step{
+			{Name: "a", TaskType: ping, Compensate: &Compensation{TaskType: ping, Payload: json.RawMessage(`{"message":"a"}`)}},
+			{Name: "b", TaskType: ping, DependsOn: []string{"a"}},
+			{Name: "c", TaskType: ping, DependsOn: []string{"b"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Start() = %v", err)
+	}
+
+	if want := []string{"a", "b", "compensate:a"}; !slices.Equal(r.ran, want) {
+		t.Errorf("ran %v, want %v", r.ran, want)
+	}
+	wf, steps := workflowStatus(t, queries, id)
+	if wf.Status != StatusFailed {
+		t.Errorf("workflow status = %q, want %q", wf.Status, StatusFailed)
+	}
+	if steps["c"] != "cancelled" {
+		t.Errorf("step c status = %q, want cancelled", steps["c"])
+	}
+}
+
+func TestEngineHaltsOnFailure(t *testing.T) {
+	r := &stepRunner{fail: map[string]bool{"a": true}}
+	engine, queries := newTestEngine(t, r)
+	ping := tasks.TypeWorkerPing
+
+	id, err := engine.Start(context.Background(), Definition{
+		Name: "halted",
+		Steps: []Step{
+			{Name: "a", TaskType: ping, Compensate: &Compensation{TaskType: ping}},
+			{Name: "b", TaskType: ping, DependsOn: []string{"a"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Start() = %v", err)
+	}
+
+	// Halting never compensates, and b never runs
+	if want := []string{"a"}; !slices.Equal(r.ran, want) {
+		t.Errorf("ran %v, want %v", r.ran, want)
+	}
+	wf, _ := workflowStatus(t, queries, id)
+	if wf.Status != StatusFailed || wf.LastError.String == "" {
+		t.Errorf("workflow = %q (last error %q), want failed with an error", wf.Status, wf.LastError.String)
+	}
+}
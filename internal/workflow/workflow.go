@@ -0,0 +1,145 @@
+// Package workflow runs DAGs of tasks on top of scheduler.Client.
+// A workflow is persisted with all of its steps; each step is enqueued once every
+// step it depends on has completed, and a step that fails for good halts the workflow
+// or compensates the steps that already completed.
+package workflow
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"boiler-go/internal/tasks"
+)
+
+// FailurePolicy decides what happens to a workflow when one of its steps fails for good.
+type FailurePolicy string
+
+const (
+	// FailureHalt stops the workflow: waiting steps are cancelled, running steps finish.
+	FailureHalt FailurePolicy = "halt"
+	// FailureCompensate halts the workflow and enqueues the compensation task
+	// of every step that completed.
+	FailureCompensate FailurePolicy = "compensate"
+)
+
+// Definition describes a workflow to start.
+type Definition struct {
+	Name string `json:"name"`
+	// OnFailure defaults to FailureHalt.
+	OnFailure FailurePolicy `json:"on_failure,omitempty"`
+	Steps     []Step        `json:"steps"`
+}
+
+// Step is a single task in a workflow.
+type Step struct {
+	// Name identifies the step within its workflow.
+	Name     string          `json:"name"`
+	TaskType string          `json:"task_type"`
+	Payload  json.RawMessage `json:"payload,omitempty"`
+	// DependsOn lists the steps that must complete before this one is enqueued.
+	DependsOn []string `json:"depends_on,omitempty"`
+	// Compensate optionally undoes the step if the workflow fails after it completed.
+	Compensate *Compensation `json:"compensate,omitempty"`
+}
+
+// Compensation is the task that undoes a completed step.
+type Compensation struct {
+	TaskType string          `json:"task_type"`
+	Payload  json.RawMessage `json:"payload,omitempty"`
+}
+
+func (d Definition) policy() FailurePolicy {
+	if d.OnFailure == "" {
+		return FailureHalt
+	}
+	return d.OnFailure
+}
+
+// Validate checks that every task type is registered, every dependency exists
+// and the steps form a DAG.
+func (d Definition) Validate() error {
+	if d.Name == "" {
+		return errors.New("name is required")
+	}
+	if p := d.policy(); p != FailureHalt && p != FailureCompensate {
+		return fmt.Errorf("on_failure must be %q or %q", FailureHalt, FailureCompensate)
+	}
+	if len(d.Steps) == 0 {
+		return errors.New("at least one step is required")
+	}
+
+	names := make(map[string]bool, len(d.Steps))
+	for _, step := range d.Steps {
+		if step.Name == "" {
+			return errors.New("every step needs a name")
+		}
+		if names[step.Name] {
+			return fmt.Errorf("duplicate step %q", step.Name)
+		}
+		names[step.Name] = true
+
+		if _, ok := tasks.Lookup(step.TaskType); !ok {
+			return fmt.Errorf("step %q: unknown task_type %q", step.Name, step.TaskType)
+		}
+		if len(step.Payload) > 0 && !json.Valid(step.Payload) {
+			return fmt.Errorf("step %q: payload must be valid JSON", step.Name)
+		}
+		if c := step.Compensate; c != nil {
+			if _, ok := tasks.Lookup(c.TaskType); !ok {
+				return fmt.Errorf("step %q: unknown compensation task_type %q", step.Name, c.TaskType)
+			}
+			if len(c.Payload) > 0 && !json.Valid(c.Payload) {
+				return fmt.Errorf("step %q: compensation payload must be valid JSON", step.Name)
+			}
+		}
+	}
+
+	for _, step := range d.Steps {
+		for _, dep := range step.DependsOn {
+			if dep == step.Name {
+				return fmt.Errorf("step %q depends on itself", step.Name)
+			}
+			if !names[dep] {
+				return fmt.Errorf("step %q depends on unknown step %q", step.Name, dep)
+			}
+		}
+	}
+
+	return checkAcyclic(d.Steps)
+}
+
+// checkAcyclic runs Kahn's algorithm: if some steps never reach zero unmet
+// dependencies, they are part of a cycle and could never be enqueued.
+func checkAcyclic(steps []Step) error {
+	unmet := make(map[string]int, len(steps))
+	children := make(map[string][]string, len(steps))
+	var ready []string
+	for _, step := range steps {
+		unmet[step.Name] = len(step.DependsOn)
+		for _, dep := range step.DependsOn {
+			children[dep] = append(children[dep], step.Name)
+		}
+		if len(step.DependsOn) == 0 {
+			ready = append(ready, step.Name)
+		}
+	}
+
+	visited := 0
+	for len(ready) > 0 {
+		name := ready[0]
+		ready = ready[1:]
+		visited++
+		for _, child := range children[name] {
+			unmet[child]--
+			if unmet[child] == 0 {
+				ready = append(ready, child)
+			}
+		}
+	}
+
+	if visited != len(steps) {
+		return errors.New("steps must not form a dependency cycle")
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package workflow
+
+import (
+	"encoding/json"
+	"testing"
+
+	"boiler-go/internal/tasks"
+)
+
+func TestCheckAcyclic(t *testing.T) {
+	tests := []struct {
+		name    string
+		steps   []Step
+		wantErr bool
+	}{
+		{
+			name:  "single step",
+			steps: []Step{{Name: "a"}},
+		},
+		{
+			name: "chain",
+			steps: []Step{
+				{Name: "a"},
+				{Name: "b", DependsOn: []string{"a"}},
+				{Name: "c", DependsOn: []string{"b"}},
+			},
+		},
+		{
+			name: "diamond",
+			steps: []Step{
+				{Name: "a"},
+				{Name: "b", DependsOn: []string{"a"}},
+				{Name: "c", DependsOn: []string{"a"}},
+				{Name: "d", DependsOn: []string{"b", "c"}},
+			},
+		},
+		{
+			name: "steps listed before their dependencies",
+			steps: []Step{
+				{Name: "c", DependsOn: []string{"b"}},
+				{Name: "b", DependsOn: []string{"a"}},
+				{Name: "a"},
+			},
+		},
+		{
+			name: "two step cycle",
+			steps: []Step{
+				{Name: "a", DependsOn: []string{"b"}},
+				{Name: "b", DependsOn: []string{"a"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "cycle below a root",
+			steps: []Step{
+				{Name: "a"},
+				{Name: "b", DependsOn: []string{"a", "d"}},
+				{Name: "c", DependsOn: []string{"b"}},
+				{Name: "d", DependsOn: []string{"c"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "no root",
+			steps: []Step{
+				{Name: "a", DependsOn: []string{"c"}},
+				{Name: "b", DependsOn: []string{"a"}},
+				{Name: "c", DependsOn: []string{"b"}},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkAcyclic(tt.steps)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkAcyclic() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDefinitionValidate(t *testing.T) {
+	ping := tasks.TypeWorkerPing
+	tests := []struct {
+		name    string
+		def     Definition
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			def: Definition{
+				Name:      "onboarding",
+				OnFailure: FailureCompensate,
+				Steps: []Step{
+					{Name: "a", TaskType: ping, Payload: json.RawMessage(`{"message":"a"}`)},
+					{Name: "b", TaskType: ping, DependsOn: []string{"a"}, Compensate: &Compensation{TaskType: ping}},
+				},
+			},
+		},
+		{
+			name:    "missing name",
+			def:     Definition{Steps: []Step{{Name: "a", TaskType: ping}}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown failure policy",
+			def:     Definition{Name: "w", OnFailure: "retry", Steps: []Step{{Name: "a", TaskType: ping}}},
+			wantErr: true,
+		},
+		{
+			name:    "no steps",
+			def:     Definition{Name: "w"},
+			wantErr: true,
+		},
+		{
+			name:    "unnamed step",
+			def:     Definition{Name: "w", Steps: []Step{{TaskType: ping}}},
+			wantErr: true,
+		},
+		{
+			name:    "duplicate step",
+			def:     Definition{Name: "w", Steps: []Step{{Name: "a", TaskType: ping}, {Name: "a", TaskType: ping}}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown task type",
+			def:     Definition{Name: "w", Steps: []Step{{Name: "a", TaskType: "unknown:type"}}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid payload",
+			def:     Definition{Name: "w", Steps: []Step{{Name: "a", TaskType: ping, Payload: json.RawMessage(`{`)}}},
+			wantErr: true,
+		},
+		{
+			name: "unknown compensation task type",
+			def: Definition{Name: "w", Steps: []Step{
+				{Name: "a", TaskType: ping, Compensate: &Compensation{TaskType: "unknown:type"}},
+			}},
+			wantErr: true,
+		},
+		{
+			name:    "depends on itself",
+			def:     Definition{Name: "w", Steps: []Step{{Name: "a", TaskType: ping, DependsOn: []string{"a"}}}},
+			wantErr: true,
+		},
+		{
+			name:    "depends on unknown step",
+			def:     Definition{Name: "w", Steps: []Step{{Name: "a", TaskType: ping, DependsOn: []string{"b"}}}},
+			wantErr: true,
+		},
+		{
+			name: "cycle",
+			def: Definition{Name: "w", Steps: []Step{
+				{Name: "a", TaskType: ping, DependsOn: []string{"b"}},
+				{Name: "b", TaskType: ping, DependsOn: []string{"a"}},
+			}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.def.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,8 @@
+-- Track which queue a job was enqueued to and when its status last changed.
+-- The queue is required to look a task up through the asynq inspector.
+
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS queue TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+CREATE INDEX IF NOT EXISTS jobs_status_idx ON jobs (status);
+CREATE INDEX IF NOT EXISTS jobs_created_at_idx ON jobs (created_at);
@@ -0,0 +1,15 @@
+-- Runtime-defined recurring tasks, synced into the scheduler process
+-- by the periodic task config provider.
+
+CREATE TABLE IF NOT EXISTS periodic_tasks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT NOT NULL UNIQUE,
+    cronspec TEXT NOT NULL,
+    task_type TEXT NOT NULL,
+    payload JSONB,
+    queue TEXT NOT NULL DEFAULT 'default',
+    timezone TEXT NOT NULL DEFAULT 'UTC',
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
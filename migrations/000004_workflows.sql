@@ -0,0 +1,32 @@
+-- Workflows: DAGs of tasks where each step is enqueued once all of its
+-- dependencies have completed. Step tasks are tracked in jobs via job_id.
+
+CREATE TABLE IF NOT EXISTS workflows (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'running',
+    on_failure TEXT NOT NULL DEFAULT 'halt',
+    last_error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    completed_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS workflows_status_idx ON workflows (status);
+
+CREATE TABLE IF NOT EXISTS workflow_steps (
+    workflow_id UUID NOT NULL REFERENCES workflows (id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    position INT NOT NULL,
+    task_type TEXT NOT NULL,
+    payload JSONB,
+    depends_on TEXT[] NOT NULL DEFAULT '{}',
+    compensate_task_type TEXT,
+    compensate_payload JSONB,
+    status TEXT NOT NULL DEFAULT 'waiting',
+    job_id UUID,
+    compensation_job_id UUID,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (workflow_id, name)
+);
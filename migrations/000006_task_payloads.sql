@@ -0,0 +1,8 @@
+-- Claim-check storage: payloads above PAYLOAD_OFFLOAD_THRESHOLD are kept here instead
+-- of in Redis, and the enqueued task only carries a reference to its row.
+
+CREATE TABLE IF NOT EXISTS task_payloads (
+    task_id UUID PRIMARY KEY,
+    data BYTEA NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
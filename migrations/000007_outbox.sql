@@ -0,0 +1,33 @@
+-- Transactional outbox: tasks written in the same transaction as business data, and
+-- handed to asynq by the outbox relay once the transaction commits.
+
+CREATE TABLE IF NOT EXISTS outbox (
+    id BIGSERIAL PRIMARY KEY,
+    task_id UUID NOT NULL UNIQUE,
+    task_type TEXT NOT NULL,
+    payload BYTEA,
+    headers JSONB NOT NULL DEFAULT '{}',
+    options JSONB NOT NULL DEFAULT '{}',
+    status TEXT NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    available_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    dispatched_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS outbox_pending_idx ON outbox (available_at, id) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS outbox_dispatched_at_idx ON outbox (dispatched_at) WHERE status = 'dispatched';
+
+-- Wake the relays when new messages commit; notifications are only delivered on commit
+CREATE OR REPLACE FUNCTION outbox_notify() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('outbox', '');
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS outbox_notify ON outbox;
+CREATE TRIGGER outbox_notify
+AFTER INSERT ON outbox
+FOR EACH STATEMENT EXECUTE FUNCTION outbox_notify();
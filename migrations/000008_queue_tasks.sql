@@ -0,0 +1,31 @@
+-- Postgres queue backend (QUEUE_BACKEND=postgres): tasks are claimed with
+-- FOR UPDATE SKIP LOCKED and leased to a worker until lease_expires_at.
+-- Active tasks whose lease expires, e.g. after a worker crash, are claimed again.
+
+CREATE TABLE IF NOT EXISTS queue_tasks (
+    id UUID PRIMARY KEY,
+    queue TEXT NOT NULL,
+    task_type TEXT NOT NULL,
+    payload BYTEA,
+    headers JSONB NOT NULL DEFAULT '{}',
+    state TEXT NOT NULL DEFAULT 'pending',
+    retried INT NOT NULL DEFAULT 0,
+    max_retry INT NOT NULL,
+    timeout_ms BIGINT NOT NULL DEFAULT 0,
+    deadline TIMESTAMPTZ,
+    retention_ms BIGINT NOT NULL DEFAULT 0,
+    run_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    lease_expires_at TIMESTAMPTZ,
+    cancel_requested BOOLEAN NOT NULL DEFAULT false,
+    last_error TEXT,
+    last_failed_at TIMESTAMPTZ,
+    result BYTEA,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    completed_at TIMESTAMPTZ,
+    expires_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS queue_tasks_due_idx ON queue_tasks (queue, run_at) WHERE state IN ('pending', 'scheduled', 'retry');
+CREATE INDEX IF NOT EXISTS queue_tasks_lease_idx ON queue_tasks (lease_expires_at) WHERE state = 'active';
+CREATE INDEX IF NOT EXISTS queue_tasks_expires_at_idx ON queue_tasks (expires_at) WHERE state = 'completed';
@@ -0,0 +1,5 @@
+-- Each claim of a queue task gets a new lease token. A worker records the outcome of a
+-- task only while the row still carries its token, so a worker whose lease expired and
+-- was claimed by another cannot overwrite the outcome of the newer attempt.
+
+ALTER TABLE queue_tasks ADD COLUMN IF NOT EXISTS lease_token UUID;
@@ -0,0 +1,18 @@
+-- Workers of the postgres queue backend register here and heartbeat, like asynq servers
+-- do in Redis, so /health can tell which queues have a live worker. Rows of workers that
+-- stopped heartbeating expire and are pruned.
+
+CREATE TABLE IF NOT EXISTS queue_servers (
+    id UUID PRIMARY KEY,
+    host TEXT NOT NULL,
+    pid INT NOT NULL,
+    concurrency INT NOT NULL,
+    queues JSONB NOT NULL DEFAULT '{}',
+    status TEXT NOT NULL DEFAULT 'active',
+    active_workers INT NOT NULL DEFAULT 0,
+    started_at TIMESTAMPTZ NOT NULL,
+    heartbeat_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS queue_servers_expires_at_idx ON queue_servers (expires_at);
@@ -0,0 +1,23 @@
+package logger
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+)
+
+type ctxKey struct{}
+
+// WithContext returns a context carrying the logger, e.g. a task-scoped logger in the worker.
+func WithContext(ctx context.Context, log zerolog.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, log)
+}
+
+// FromContext retrieves the logger stored by WithContext.
+// Like FromEchoContext, it falls back to the global logger so logs are never silently dropped.
+func FromContext(ctx context.Context) zerolog.Logger {
+	if log, ok := ctx.Value(ctxKey{}).(zerolog.Logger); ok {
+		return log
+	}
+	return Global()
+}
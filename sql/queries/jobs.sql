@@ -0,0 +1,80 @@
+-- name: CreateJob :execrows
+-- A row count of zero means the job was already recorded, by an earlier enqueue with the same task ID.
+INSERT INTO jobs (id, task_type, queue, payload)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (id) DO NOTHING;
+
+-- name: CreateJobs :batchone
+-- No row is returned for a job that was already recorded, by an earlier enqueue with the same task ID.
+INSERT INTO jobs (id, task_type, queue, payload)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (id) DO NOTHING
+RETURNING id;
+
+-- name: GetJob :one
+SELECT * FROM jobs
+WHERE id = $1;
+
+-- name: DeleteJob :exec
+DELETE FROM jobs
+WHERE id = $1;
+
+-- name: DeleteJobs :exec
+DELETE FROM jobs
+WHERE id = ANY($1::uuid[]);
+
+-- name: MarkJobActive :execrows
+-- Cancelled jobs are left untouched, so a row count of zero means the job was cancelled.
+INSERT INTO jobs (id, task_type, queue, payload, status, attempts)
+VALUES ($1, $2, $3, $4, 'active', $5)
+ON CONFLICT (id) DO UPDATE
+SET status = 'active',
+    attempts = EXCLUDED.attempts,
+    updated_at = now()
+WHERE jobs.status <> 'cancelled';
+
+-- name: MarkJobCompleted :exec
+UPDATE jobs
+SET status = 'completed',
+    completed_at = now(),
+    updated_at = now()
+WHERE id = $1 AND status <> 'cancelled';
+
+-- name: MarkJobRetry :exec
+UPDATE jobs
+SET status = 'retry',
+    last_error = $2,
+    updated_at = now()
+WHERE id = $1 AND status <> 'cancelled';
+
+-- name: MarkJobArchived :exec
+UPDATE jobs
+SET status = 'archived',
+    last_error = $2,
+    completed_at = now(),
+    updated_at = now()
+WHERE id = $1 AND status <> 'cancelled';
+
+-- name: MarkJobCancelled :execrows
+UPDATE jobs
+SET status = 'cancelled',
+    completed_at = now(),
+    updated_at = now()
+WHERE id = $1 AND status NOT IN ('completed', 'archived', 'cancelled');
+
+-- name: MarkJobPending :exec
+UPDATE jobs
+SET status = 'pending',
+    completed_at = NULL,
+    updated_at = now()
+WHERE id = $1;
+
+-- name: MarkJobsFinished :exec
+-- Sets the final status of the tasks aggregated into a task once that task has finished.
+UPDATE jobs
+SET status = sqlc.arg(status),
+    last_error = sqlc.narg(last_error),
+    completed_at = now(),
+    updated_at = now()
+WHERE id = ANY(sqlc.arg(ids)::uuid[])
+  AND status NOT IN ('completed', 'archived', 'cancelled');
@@ -0,0 +1,32 @@
+-- name: CreateOutboxMessage :exec
+INSERT INTO outbox (task_id, task_type, payload, headers, options)
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: ClaimOutboxMessages :many
+-- Locks due messages for the rest of the transaction; other relays skip them.
+SELECT * FROM outbox
+WHERE status = 'pending'
+  AND available_at <= now()
+ORDER BY id
+LIMIT $1
+FOR UPDATE SKIP LOCKED;
+
+-- name: MarkOutboxDispatched :exec
+UPDATE outbox
+SET status = 'dispatched',
+    attempts = attempts + 1,
+    last_error = NULL,
+    dispatched_at = now()
+WHERE id = $1;
+
+-- name: RetryOutboxMessage :exec
+UPDATE outbox
+SET attempts = attempts + 1,
+    last_error = sqlc.arg(last_error),
+    available_at = sqlc.arg(available_at)
+WHERE id = sqlc.arg(id);
+
+-- name: DeleteDispatchedOutboxMessages :execrows
+DELETE FROM outbox
+WHERE status = 'dispatched'
+  AND dispatched_at < $1;
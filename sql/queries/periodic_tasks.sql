@@ -0,0 +1,34 @@
+-- name: CreatePeriodicTask :one
+INSERT INTO periodic_tasks (name, cronspec, task_type, payload, queue, timezone, enabled)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING *;
+
+-- name: GetPeriodicTask :one
+SELECT * FROM periodic_tasks
+WHERE id = $1;
+
+-- name: ListPeriodicTasks :many
+SELECT * FROM periodic_tasks
+ORDER BY name;
+
+-- name: ListEnabledPeriodicTasks :many
+SELECT * FROM periodic_tasks
+WHERE enabled
+ORDER BY name;
+
+-- name: UpdatePeriodicTask :one
+UPDATE periodic_tasks
+SET name = $2,
+    cronspec = $3,
+    task_type = $4,
+    payload = $5,
+    queue = $6,
+    timezone = $7,
+    enabled = $8,
+    updated_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeletePeriodicTask :execrows
+DELETE FROM periodic_tasks
+WHERE id = $1;
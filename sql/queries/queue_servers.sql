@@ -0,0 +1,27 @@
+-- name: HeartbeatQueueServer :exec
+-- Registers a worker of the postgres backend on its first heartbeat, and renews it after.
+INSERT INTO queue_servers (id, host, pid, concurrency, queues, status, active_workers, started_at, expires_at)
+VALUES (
+    sqlc.arg(id), sqlc.arg(host), sqlc.arg(pid), sqlc.arg(concurrency), sqlc.arg(queues),
+    sqlc.arg(status), sqlc.arg(active_workers), sqlc.arg(started_at),
+    now() + make_interval(secs => sqlc.arg(ttl_seconds)::float8)
+)
+ON CONFLICT (id) DO UPDATE
+SET status = EXCLUDED.status,
+    active_workers = EXCLUDED.active_workers,
+    heartbeat_at = now(),
+    expires_at = EXCLUDED.expires_at;
+
+-- name: ListQueueServers :many
+-- Lists the workers whose heartbeat has not expired.
+SELECT * FROM queue_servers
+WHERE expires_at > now()
+ORDER BY started_at;
+
+-- name: DeleteQueueServer :exec
+DELETE FROM queue_servers
+WHERE id = $1;
+
+-- name: DeleteExpiredQueueServers :execrows
+DELETE FROM queue_servers
+WHERE expires_at < now();
@@ -0,0 +1,98 @@
+-- name: CreateQueueTask :execrows
+-- A row count of zero means a task with the same ID already exists.
+INSERT INTO queue_tasks (id, queue, task_type, payload, headers, state, max_retry, timeout_ms, deadline, retention_ms, run_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+ON CONFLICT (id) DO NOTHING;
+
+-- name: GetQueueTask :one
+SELECT * FROM queue_tasks
+WHERE id = $1;
+
+-- name: ClaimQueueTask :one
+-- Leases the next due task of the given queues, in the order they are listed.
+-- An active task whose lease expired is claimed again, and its lost attempt counts as a retry.
+-- The lease token identifies this claim in the writes of the worker holding it.
+UPDATE queue_tasks
+SET state = 'active',
+    retried = CASE WHEN state = 'active' THEN retried + 1 ELSE retried END,
+    lease_expires_at = now() + make_interval(secs => sqlc.arg(lease_seconds)::float8),
+    lease_token = sqlc.arg(lease_token),
+    cancel_requested = false,
+    updated_at = now()
+WHERE id = (
+    SELECT id FROM queue_tasks
+    WHERE queue = ANY(sqlc.arg(queues)::text[])
+      AND ((state IN ('pending', 'scheduled', 'retry') AND run_at <= now())
+        OR (state = 'active' AND lease_expires_at < now()))
+    ORDER BY array_position(sqlc.arg(queues)::text[], queue), run_at
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: ExtendQueueTaskLease :one
+-- Returns whether cancellation of the task was requested.
+-- No row is returned once the lease is lost to another claim.
+UPDATE queue_tasks
+SET lease_expires_at = now() + make_interval(secs => sqlc.arg(lease_seconds)::float8)
+WHERE id = sqlc.arg(id) AND state = 'active' AND lease_token = sqlc.arg(lease_token)
+RETURNING cancel_requested;
+
+-- name: WriteQueueTaskResult :execrows
+-- Stores the result of an active task while its handler runs, like asynq's ResultWriter.
+UPDATE queue_tasks
+SET result = $2,
+    updated_at = now()
+WHERE id = $1 AND state = 'active' AND lease_token = $3;
+
+-- name: CompleteQueueTask :execrows
+-- Like the other outcome writes, a row count of zero means the lease was lost.
+UPDATE queue_tasks
+SET state = 'completed',
+    lease_expires_at = NULL,
+    lease_token = NULL,
+    completed_at = now(),
+    expires_at = now() + retention_ms * interval '1 millisecond',
+    updated_at = now()
+WHERE id = $1 AND state = 'active' AND lease_token = $2;
+
+-- name: RetryQueueTask :execrows
+UPDATE queue_tasks
+SET state = 'retry',
+    retried = sqlc.arg(retried),
+    run_at = sqlc.arg(run_at),
+    last_error = sqlc.arg(last_error),
+    last_failed_at = now(),
+    lease_expires_at = NULL,
+    lease_token = NULL,
+    updated_at = now()
+WHERE id = sqlc.arg(id) AND state = 'active' AND lease_token = sqlc.arg(lease_token);
+
+-- name: ArchiveQueueTask :execrows
+UPDATE queue_tasks
+SET state = 'archived',
+    last_error = $2,
+    last_failed_at = now(),
+    lease_expires_at = NULL,
+    lease_token = NULL,
+    updated_at = now()
+WHERE id = $1 AND state = 'active' AND lease_token = $3;
+
+-- name: RequestQueueTaskCancel :execrows
+-- The worker holding the lease cancels the task on its next lease extension.
+UPDATE queue_tasks
+SET cancel_requested = true
+WHERE id = $1 AND state = 'active';
+
+-- name: DeleteLeasedQueueTask :execrows
+DELETE FROM queue_tasks
+WHERE id = $1 AND state = 'active' AND lease_token = $2;
+
+-- name: DeleteInactiveQueueTask :execrows
+DELETE FROM queue_tasks
+WHERE id = $1 AND state <> 'active';
+
+-- name: DeleteExpiredQueueTasks :execrows
+DELETE FROM queue_tasks
+WHERE state = 'completed'
+  AND expires_at < now();
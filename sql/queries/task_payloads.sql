@@ -0,0 +1,18 @@
+-- name: CreateTaskPayload :exec
+-- A retried idempotent enqueue keeps the payload of the task already queued.
+INSERT INTO task_payloads (task_id, data)
+VALUES ($1, $2)
+ON CONFLICT (task_id) DO NOTHING;
+
+-- name: GetTaskPayload :one
+SELECT data FROM task_payloads
+WHERE task_id = $1;
+
+-- name: DeleteTaskPayload :exec
+DELETE FROM task_payloads
+WHERE task_id = $1;
+
+-- name: ListTaskPayloadKeys :many
+SELECT task_id FROM task_payloads
+WHERE created_at < $1
+ORDER BY created_at;
@@ -0,0 +1,26 @@
+-- name: CreateWebhookDelivery :one
+INSERT INTO webhook_deliveries (task_id, task_type, event, callback_url, payload)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetWebhookDelivery :one
+SELECT * FROM webhook_deliveries
+WHERE id = $1;
+
+-- name: ListWebhookDeliveriesByTask :many
+SELECT * FROM webhook_deliveries
+WHERE task_id = $1
+ORDER BY created_at;
+
+-- name: RecordWebhookAttempt :exec
+-- Appends an attempt to the history and sets the delivery status:
+-- pending while retries remain, then delivered or failed.
+UPDATE webhook_deliveries
+SET status = sqlc.arg(status),
+    attempts = attempts + 1,
+    last_status_code = sqlc.narg(last_status_code),
+    last_error = sqlc.narg(last_error),
+    history = history || sqlc.arg(attempt)::jsonb,
+    delivered_at = CASE WHEN sqlc.arg(status) = 'delivered' THEN now() ELSE delivered_at END,
+    updated_at = now()
+WHERE id = sqlc.arg(id);
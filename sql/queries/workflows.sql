@@ -0,0 +1,77 @@
+-- name: CreateWorkflow :one
+INSERT INTO workflows (name, on_failure)
+VALUES ($1, $2)
+RETURNING *;
+
+-- name: CreateWorkflowStep :exec
+INSERT INTO workflow_steps (workflow_id, name, position, task_type, payload, depends_on, compensate_task_type, compensate_payload)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
+
+-- name: GetWorkflow :one
+SELECT * FROM workflows
+WHERE id = $1;
+
+-- name: LockWorkflow :one
+SELECT * FROM workflows
+WHERE id = $1
+FOR UPDATE;
+
+-- name: ListWorkflowSteps :many
+SELECT * FROM workflow_steps
+WHERE workflow_id = $1
+ORDER BY position;
+
+-- name: ListWorkflowStepStates :many
+SELECT s.name, s.task_type, s.depends_on, s.status, s.job_id, s.compensation_job_id, s.updated_at,
+       j.status AS job_status, j.attempts AS job_attempts, j.last_error AS job_last_error
+FROM workflow_steps s
+LEFT JOIN jobs j ON j.id = s.job_id
+WHERE s.workflow_id = $1
+ORDER BY s.position;
+
+-- name: MarkWorkflowCompleted :exec
+UPDATE workflows
+SET status = 'completed',
+    completed_at = now(),
+    updated_at = now()
+WHERE id = $1;
+
+-- name: MarkWorkflowFailed :exec
+UPDATE workflows
+SET status = 'failed',
+    last_error = $2,
+    completed_at = now(),
+    updated_at = now()
+WHERE id = $1;
+
+-- name: MarkWorkflowStepEnqueued :exec
+UPDATE workflow_steps
+SET status = 'enqueued',
+    job_id = $3,
+    updated_at = now()
+WHERE workflow_id = $1 AND name = $2;
+
+-- name: MarkWorkflowStepCompleted :exec
+UPDATE workflow_steps
+SET status = 'completed',
+    updated_at = now()
+WHERE workflow_id = $1 AND name = $2;
+
+-- name: MarkWorkflowStepFailed :exec
+UPDATE workflow_steps
+SET status = 'failed',
+    updated_at = now()
+WHERE workflow_id = $1 AND name = $2;
+
+-- name: MarkWorkflowStepCompensating :exec
+UPDATE workflow_steps
+SET status = 'compensating',
+    compensation_job_id = $3,
+    updated_at = now()
+WHERE workflow_id = $1 AND name = $2;
+
+-- name: CancelWaitingWorkflowSteps :exec
+UPDATE workflow_steps
+SET status = 'cancelled',
+    updated_at = now()
+WHERE workflow_id = $1 AND status = 'waiting';
@@ -13,10 +13,169 @@ CREATE TABLE
     IF NOT EXISTS jobs (
         id UUID PRIMARY KEY DEFAULT gen_random_uuid (),
         task_type TEXT NOT NULL,
+        queue TEXT NOT NULL DEFAULT 'default',
         payload JSONB,
         status TEXT NOT NULL DEFAULT 'pending',
         attempts INT NOT NULL DEFAULT 0,
         last_error TEXT,
         created_at TIMESTAMPTZ NOT NULL DEFAULT now (),
+        updated_at TIMESTAMPTZ NOT NULL DEFAULT now (),
         completed_at TIMESTAMPTZ
-    );
\ No newline at end of file
+    );
+
+CREATE INDEX IF NOT EXISTS jobs_status_idx ON jobs (status);
+
+CREATE INDEX IF NOT EXISTS jobs_created_at_idx ON jobs (created_at);
+
+CREATE TABLE
+    IF NOT EXISTS periodic_tasks (
+        id UUID PRIMARY KEY DEFAULT gen_random_uuid (),
+        name TEXT NOT NULL UNIQUE,
+        cronspec TEXT NOT NULL,
+        task_type TEXT NOT NULL,
+        payload JSONB,
+        queue TEXT NOT NULL DEFAULT 'default',
+        timezone TEXT NOT NULL DEFAULT 'UTC',
+        enabled BOOLEAN NOT NULL DEFAULT true,
+        created_at TIMESTAMPTZ NOT NULL DEFAULT now (),
+        updated_at TIMESTAMPTZ NOT NULL DEFAULT now ()
+    );
+
+CREATE TABLE
+    IF NOT EXISTS workflows (
+        id UUID PRIMARY KEY DEFAULT gen_random_uuid (),
+        name TEXT NOT NULL,
+        status TEXT NOT NULL DEFAULT 'running',
+        on_failure TEXT NOT NULL DEFAULT 'halt',
+        last_error TEXT,
+        created_at TIMESTAMPTZ NOT NULL DEFAULT now (),
+        updated_at TIMESTAMPTZ NOT NULL DEFAULT now (),
+        completed_at TIMESTAMPTZ
+    );
+
+CREATE INDEX IF NOT EXISTS workflows_status_idx ON workflows (status);
+
+CREATE TABLE
+    IF NOT EXISTS workflow_steps (
+        workflow_id UUID NOT NULL REFERENCES workflows (id) ON DELETE CASCADE,
+        name TEXT NOT NULL,
+        position INT NOT NULL,
+        task_type TEXT NOT NULL,
+        payload JSONB,
+        depends_on TEXT[] NOT NULL DEFAULT '{}',
+        compensate_task_type TEXT,
+        compensate_payload JSONB,
+        status TEXT NOT NULL DEFAULT 'waiting',
+        job_id UUID,
+        compensation_job_id UUID,
+        created_at TIMESTAMPTZ NOT NULL DEFAULT now (),
+        updated_at TIMESTAMPTZ NOT NULL DEFAULT now (),
+        PRIMARY KEY (workflow_id, name)
+    );
+
+CREATE TABLE
+    IF NOT EXISTS webhook_deliveries (
+        id UUID PRIMARY KEY DEFAULT gen_random_uuid (),
+        task_id UUID NOT NULL,
+        task_type TEXT NOT NULL,
+        event TEXT NOT NULL,
+        callback_url TEXT NOT NULL,
+        payload JSONB NOT NULL,
+        status TEXT NOT NULL DEFAULT 'pending',
+        attempts INT NOT NULL DEFAULT 0,
+        last_status_code INT,
+        last_error TEXT,
+        history JSONB NOT NULL DEFAULT '[]',
+        created_at TIMESTAMPTZ NOT NULL DEFAULT now (),
+        updated_at TIMESTAMPTZ NOT NULL DEFAULT now (),
+        delivered_at TIMESTAMPTZ
+    );
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_task_id_idx ON webhook_deliveries (task_id);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_status_idx ON webhook_deliveries (status);
+
+CREATE TABLE
+    IF NOT EXISTS task_payloads (
+        task_id UUID PRIMARY KEY,
+        data BYTEA NOT NULL,
+        created_at TIMESTAMPTZ NOT NULL DEFAULT now ()
+    );
+
+CREATE TABLE
+    IF NOT EXISTS outbox (
+        id BIGSERIAL PRIMARY KEY,
+        task_id UUID NOT NULL UNIQUE,
+        task_type TEXT NOT NULL,
+        payload BYTEA,
+        headers JSONB NOT NULL DEFAULT '{}',
+        options JSONB NOT NULL DEFAULT '{}',
+        status TEXT NOT NULL DEFAULT 'pending',
+        attempts INT NOT NULL DEFAULT 0,
+        last_error TEXT,
+        available_at TIMESTAMPTZ NOT NULL DEFAULT now (),
+        created_at TIMESTAMPTZ NOT NULL DEFAULT now (),
+        dispatched_at TIMESTAMPTZ
+    );
+
+CREATE INDEX IF NOT EXISTS outbox_pending_idx ON outbox (available_at, id)
+WHERE
+    status = 'pending';
+
+CREATE INDEX IF NOT EXISTS outbox_dispatched_at_idx ON outbox (dispatched_at)
+WHERE
+    status = 'dispatched';
+
+CREATE TABLE
+    IF NOT EXISTS queue_tasks (
+        id UUID PRIMARY KEY,
+        queue TEXT NOT NULL,
+        task_type TEXT NOT NULL,
+        payload BYTEA,
+        headers JSONB NOT NULL DEFAULT '{}',
+        state TEXT NOT NULL DEFAULT 'pending',
+        retried INT NOT NULL DEFAULT 0,
+        max_retry INT NOT NULL,
+        timeout_ms BIGINT NOT NULL DEFAULT 0,
+        deadline TIMESTAMPTZ,
+        retention_ms BIGINT NOT NULL DEFAULT 0,
+        run_at TIMESTAMPTZ NOT NULL DEFAULT now (),
+        lease_expires_at TIMESTAMPTZ,
+        cancel_requested BOOLEAN NOT NULL DEFAULT false,
+        last_error TEXT,
+        last_failed_at TIMESTAMPTZ,
+        result BYTEA,
+        created_at TIMESTAMPTZ NOT NULL DEFAULT now (),
+        updated_at TIMESTAMPTZ NOT NULL DEFAULT now (),
+        completed_at TIMESTAMPTZ,
+        expires_at TIMESTAMPTZ,
+        lease_token UUID
+    );
+
+CREATE INDEX IF NOT EXISTS queue_tasks_due_idx ON queue_tasks (queue, run_at)
+WHERE
+    state IN ('pending', 'scheduled', 'retry');
+
+CREATE INDEX IF NOT EXISTS queue_tasks_lease_idx ON queue_tasks (lease_expires_at)
+WHERE
+    state = 'active';
+
+CREATE INDEX IF NOT EXISTS queue_tasks_expires_at_idx ON queue_tasks (expires_at)
+WHERE
+    state = 'completed';
+
+CREATE TABLE
+    IF NOT EXISTS queue_servers (
+        id UUID PRIMARY KEY,
+        host TEXT NOT NULL,
+        pid INT NOT NULL,
+        concurrency INT NOT NULL,
+        queues JSONB NOT NULL DEFAULT '{}',
+        status TEXT NOT NULL DEFAULT 'active',
+        active_workers INT NOT NULL DEFAULT 0,
+        started_at TIMESTAMPTZ NOT NULL,
+        heartbeat_at TIMESTAMPTZ NOT NULL DEFAULT now (),
+        expires_at TIMESTAMPTZ NOT NULL
+    );
+
+CREATE INDEX IF NOT EXISTS queue_servers_expires_at_idx ON queue_servers (expires_at);